@@ -2,25 +2,48 @@ package main
 
 import (
 	"context"
+	"expvar"
+	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/Ravwvil/order-service/backend/internal/app"
+	"github.com/Ravwvil/order-service/backend/internal/broker"
 	"github.com/Ravwvil/order-service/backend/internal/broker/kafka"
+	"github.com/Ravwvil/order-service/backend/internal/broker/nats"
+	"github.com/Ravwvil/order-service/backend/internal/cache/breaker"
+	"github.com/Ravwvil/order-service/backend/internal/cache/codec"
+	"github.com/Ravwvil/order-service/backend/internal/cache/invalidation"
+	"github.com/Ravwvil/order-service/backend/internal/cache/memory"
 	"github.com/Ravwvil/order-service/backend/internal/cache/redis"
+	"github.com/Ravwvil/order-service/backend/internal/cache/tiered"
 	"github.com/Ravwvil/order-service/backend/internal/config"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
 	customhttp "github.com/Ravwvil/order-service/backend/internal/handler/http"
 	"github.com/Ravwvil/order-service/backend/internal/repository/postgres"
+	"github.com/Ravwvil/order-service/backend/internal/repository/sharded"
 	"github.com/Ravwvil/order-service/backend/internal/service"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	redisClient "github.com/redis/go-redis/v9"
 )
 
+// orderCache кэш заказов: Redis или локальный кэш перед Redis
+type orderCache interface {
+	service.OrderCache
+	service.CacheEvicter
+}
+
+// orderStore хранилище заказов: одна база или набор шардов
+type orderStore interface {
+	service.OrderRepository
+	service.RetentionRepository
+}
+
 func main() {
 	// Инициализация конфига
 	cfg, err := config.New()
@@ -46,37 +69,117 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: lvl}))
 
 	ctx := context.Background()
+	// appCtx отменяется при остановке и прерывает операции, запущенные через API в фоне
+	appCtx, cancelApp := context.WithCancel(ctx)
+	defer cancelApp()
+
+	// Инициализация базы данных и репозиториев
+	var (
+		db        app.DBer
+		orderRepo orderStore
+		dlqDB     *sqlx.DB // база для сообщений DLQ: единственная или база справочника шардов
+	)
+	if cfg.Sharding.Enabled {
+		shardedRepo, group, err := newShardedRepository(cfg, logger)
+		if err != nil {
+			logger.Error("failed to init sharded repository", slog.Any("error", err))
+			os.Exit(1)
+		}
+		db, orderRepo = group, shardedRepo
+		dlqDB, _ = group[cfg.Sharding.Directory].(*sqlx.DB)
+	} else {
+		pgDB, err := sqlx.Connect("postgres", cfg.Postgres.DSN())
+		if err != nil {
+			logger.Error("failed to connect to postgres", slog.Any("error", err))
+			os.Exit(1)
+		}
+		pgRepo := postgres.NewOrderRepository(pgDB, logger)
+		pgRepo.SetRowLevelSecurity(cfg.Tenant.Enabled && cfg.Tenant.PostgresRLS)
+		db, orderRepo, dlqDB = pgDB, pgRepo, pgDB
+	}
 
-	// Инициализация базы данных
-	db, err := sqlx.Connect("postgres", cfg.Postgres.DSN())
+	// Инициализация Redis: один клиент для кэша, инвалидаций и проверки здоровья
+	rdb, err := redis.NewClient(cfg.Redis)
 	if err != nil {
-		logger.Error("failed to connect to postgres", slog.Any("error", err))
+		logger.Error("invalid redis config", slog.Any("error", err))
 		os.Exit(1)
 	}
 
-	// Инициализация Redis
-	rdb := redisClient.NewClient(&redisClient.Options{
-		Addr:     cfg.Redis.Addr,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	})
+	// Проверка подключения к Redis: без него приложение работает в режиме деградации,
+	// обслуживая запросы из базы, пока выключатель не обнаружит восстановление
+	cacheBreaker := breaker.New(
+		func(ctx context.Context) error { return rdb.Ping(ctx).Err() },
+		cfg.Cache.BreakerThreshold,
+		time.Duration(cfg.Cache.BreakerProbe)*time.Second,
+		logger,
+	)
+	cacheBreaker.Check(ctx)
+	if !cacheBreaker.Available() {
+		logger.Warn("redis is unavailable, starting in degraded mode")
+	}
+	expvar.Publish("cache_breaker", expvar.Func(func() any { return cacheBreaker.Stats() }))
 
-	// Проверка подключения к Redis
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		logger.Error("failed to connect to redis", slog.Any("error", err))
+	// Инициализация кэша
+	redisCache := redis.New(rdb, time.Duration(cfg.Redis.TTL)*time.Second, logger)
+	redisCache.SetBreaker(cacheBreaker)
+	cacheBreaker.BeforeClose(redisCache.ReplayPending)
+	cacheCodec, err := codec.Parse(cfg.Cache.Codec)
+	if err != nil {
+		logger.Error("invalid cache codec config", slog.Any("error", err))
 		os.Exit(1)
 	}
+	redisCache.SetCodec(cacheCodec)
+	redisCache.SetSchemaFallback(cfg.Cache.SchemaFallback)
+	var cache orderCache = redisCache
 
-	// Инициализация репозиториев
-	orderRepo := postgres.NewOrderRepository(db, logger)
-
-	// Инициализация кэша
-	cache := redis.New(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB, time.Duration(cfg.Redis.TTL)*time.Second, logger)
+	// Локальный кэш процесса перед Redis, инвалидируется между репликами через pub/sub
+	var (
+		tieredCache *tiered.Cache
+		bus         *invalidation.Bus
+	)
+	if cfg.Cache.LocalSize > 0 {
+		bus = invalidation.New(redis.NewInvalidations(rdb, cfg.Cache.InvalidationChannel, logger), logger)
+		tieredCache = tiered.New(
+			memory.New(cfg.Cache.LocalSize, time.Duration(cfg.Cache.LocalTTL)*time.Second),
+			redisCache,
+			bus,
+		)
+		cache = tieredCache
+		expvar.Publish("order_cache", expvar.Func(func() any { return tieredCache.Stats() }))
+		expvar.Publish("cache_invalidation", expvar.Func(func() any { return bus.Stats() }))
+	}
 
 	// Инициализация сервисов
 	orderService := service.NewOrderService(orderRepo, cache, logger)
+	orderService.SetTenantIsolation(cfg.Tenant.Enabled)
+	orderService.SetCacheAvailability(cacheBreaker.Available)
+	orderService.SetOrderIndex(redisCache)
+	cacheBreaker.OnRecover(func(ctx context.Context) {
+		if err := orderService.RestoreCache(ctx); err != nil {
+			logger.Error("failed to re-warm cache after redis recovery", slog.Any("error", err))
+		}
+	})
+	warmupStrategy, err := service.ParseWarmupStrategy(cfg.Cache.WarmupStrategy)
+	if err != nil {
+		logger.Error("invalid cache warm-up config", slog.Any("error", err))
+		os.Exit(1)
+	}
+	orderService.SetRestoreConfig(service.RestoreConfig{
+		Strategy:    warmupStrategy,
+		BatchSize:   cfg.Cache.RestoreBatchSize,
+		Concurrency: cfg.Cache.RestoreConcurrency,
+		RecentDays:  cfg.Cache.WarmupRecentDays,
+		TopK:        cfg.Cache.WarmupTopK,
+		UIDsFile:    cfg.Cache.WarmupUIDsFile,
+	})
+	if warmupStrategy == service.WarmupTop {
+		orderService.SetAccessTracker(redisCache)
+	}
+	if cfg.Cache.NegativeTTL > 0 {
+		orderService.SetNegativeCache(redisCache, time.Duration(cfg.Cache.NegativeTTL)*time.Second)
+	}
 
-	// Инициализация Kafka consumer
+	// Инициализация consumer'а выбранного брокера сообщений
 	consumerCfg := kafka.Config{
 		Brokers:           cfg.Kafka.Brokers,
 		Topic:             cfg.Kafka.Topic,
@@ -87,16 +190,119 @@ func main() {
 		BackoffFactor:     cfg.Kafka.BackoffFactor,
 		DLQTopic:          cfg.Kafka.DLQTopic,
 		Concurrency:       cfg.Kafka.Concurrency,
+		MaxInFlight:       cfg.Kafka.MaxInFlight,
+	}
+	if cfg.Kafka.RetryTopics {
+		for _, seconds := range cfg.Kafka.RetryTiers {
+			consumerCfg.RetryTiers = append(consumerCfg.RetryTiers, time.Duration(seconds)*time.Second)
+		}
+	}
+
+	var consumer broker.Consumer
+	switch cfg.Broker.Transport {
+	case config.BrokerNATS:
+		natsCfg := nats.Config{
+			URL:         cfg.NATS.URL,
+			Stream:      cfg.NATS.Stream,
+			Subject:     cfg.NATS.Subject,
+			Durable:     cfg.NATS.Durable,
+			DLQStream:   cfg.NATS.DLQStream,
+			DLQSubject:  cfg.NATS.DLQSubject,
+			AckWait:     time.Duration(cfg.NATS.AckWait) * time.Second,
+			Concurrency: cfg.NATS.Concurrency,
+		}
+		for _, seconds := range cfg.NATS.RetryDelays {
+			natsCfg.RetryDelays = append(natsCfg.RetryDelays, time.Duration(seconds)*time.Second)
+		}
+		consumer, err = nats.NewConsumer(ctx, natsCfg, orderService, logger)
+		if err != nil {
+			logger.Error("failed to create nats consumer", slog.Any("error", err))
+			os.Exit(1)
+		}
+	default:
+		consumer = kafka.NewConsumer(consumerCfg, orderService, logger)
 	}
-	consumer := kafka.NewConsumer(consumerCfg, orderService, logger)
 
 	// Инициализация HTTP обработчиков и сервера
 	orderHandler := customhttp.NewOrderHandler(orderService)
 
 	a := app.NewApp(logger, nil, orderService, db, rdb, consumer, cfg)
+	a.AddJob(cacheBreaker)
+	if bus != nil {
+		a.AddJob(bus)
+	}
+
+	// Удаление записей кэша прежних версий схемы заказов
+	if cfg.Cache.NamespaceCleanup > 0 {
+		a.AddJob(redis.NewNamespaceCleaner(redisCache, time.Duration(cfg.Cache.NamespaceCleanup)*time.Second, logger))
+	}
+
+	// Периодическая сверка кэша с базой
+	if cfg.Cache.VerifyEnabled {
+		a.AddJob(service.NewCacheVerifier(orderRepo, redisCache, cache, service.VerifyConfig{
+			Sample:   cfg.Cache.VerifySample,
+			Limit:    cfg.Cache.VerifyLimit,
+			Interval: time.Duration(cfg.Cache.VerifyInterval) * time.Second,
+			Repair:   cfg.Cache.VerifyRepair,
+
+			TenantIsolation: cfg.Tenant.Enabled,
+		}, logger))
+	}
+
+	// Инициализация задачи очистки данных по сроку хранения
+	if cfg.Retention.Enabled {
+		retentionMode, err := domain.ParseRetentionMode(cfg.Retention.Mode)
+		if err != nil {
+			logger.Error("invalid retention config", slog.Any("error", err))
+			os.Exit(1)
+		}
+		retentionRules, err := domain.ParseRetentionRules(cfg.Retention.Rules)
+		if err != nil {
+			logger.Error("invalid retention config", slog.Any("error", err))
+			os.Exit(1)
+		}
+		retentionService := service.NewRetentionService(orderRepo, cache, service.RetentionConfig{
+			Policy:    domain.RetentionPolicy{Mode: retentionMode, Rules: retentionRules},
+			BatchSize: cfg.Retention.BatchSize,
+			Interval:  time.Duration(cfg.Retention.Interval) * time.Second,
+			DryRun:    cfg.Retention.DryRun,
+
+			TenantIsolation: cfg.Tenant.Enabled,
+		}, logger)
+		a.AddJob(retentionService)
+	}
+
+	// Сохранение сообщений DLQ в базе для разбора через административное API (только Kafka)
+	var (
+		dlqService *service.DLQService
+		requeuer   *kafka.Requeuer
+	)
+	if cfg.Broker.Transport == config.BrokerKafka && cfg.Kafka.DLQSink && cfg.Kafka.DLQTopic != "" {
+		requeuer = kafka.NewRequeuer(cfg.Kafka.Brokers, cfg.Kafka.DLQTopic)
+		dlqService = service.NewDLQService(postgres.NewDLQRepository(dlqDB, logger), requeuer, logger)
+		a.AddJob(kafka.NewDLQSink(consumerCfg, dlqService, logger))
+	}
 
 	// Теперь, когда у нас есть `a` с методом Health, мы можем создать роутер
-	router := customhttp.NewRouter(orderHandler, a.Health)
+	var middlewares []func(http.Handler) http.Handler
+	if cfg.Tenant.Enabled {
+		middlewares = append(middlewares, customhttp.TenantMiddleware(cfg.Tenant.APIKeys))
+	}
+	// Административное API доступно только при заданном ADMIN_API_KEY
+	var adminRouter http.Handler
+	if cfg.Admin.APIKey != "" {
+		cacheAdmin := service.NewCacheAdminService(appCtx, redisCache, cache, orderService, logger)
+		cacheAdmin.SetTenantIsolation(cfg.Tenant.Enabled)
+		if bus != nil {
+			cacheAdmin.SetInvalidationBus(bus)
+		}
+		var dlqHandler *customhttp.DLQHandler
+		if dlqService != nil {
+			dlqHandler = customhttp.NewDLQHandler(dlqService)
+		}
+		adminRouter = customhttp.NewAdminRouter(cfg.Admin.APIKey, customhttp.NewCacheAdminHandler(cacheAdmin), dlqHandler)
+	}
+	router := customhttp.NewRouter(orderHandler, adminRouter, a.Health, middlewares...)
 	server := customhttp.NewServer(cfg.HTTP, router)
 	a.SetServer(server)
 
@@ -122,6 +328,7 @@ func main() {
 	}
 
 	logger.Info("shutting down server...")
+	cancelApp()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -130,6 +337,56 @@ func main() {
 		logger.Error("error stopping app", slog.Any("error", err))
 		os.Exit(1)
 	}
+	if requeuer != nil {
+		if err := requeuer.Close(); err != nil {
+			logger.Error("error closing dlq requeuer", slog.Any("error", err))
+		}
+	}
 
 	logger.Info("server gracefully stopped")
-}
\ No newline at end of file
+}
+
+// newShardedRepository подключается ко всем шардам и собирает маршрутизирующий репозиторий
+func newShardedRepository(cfg *config.Config, logger *slog.Logger) (*sharded.Repository, sharded.DBGroup, error) {
+	shardMap, err := sharded.ParseShardMap(cfg.Sharding.Map)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	group := make(sharded.DBGroup, len(cfg.Sharding.DSNs))
+	shards := make(map[string]sharded.Shard, len(cfg.Sharding.DSNs))
+	var (
+		directory   sharded.Directory
+		directoryDB *sqlx.DB
+		shardRepos  []*postgres.OrderRepository
+	)
+	for name, dsn := range cfg.Sharding.DSNs {
+		shardDB, err := sqlx.Connect("postgres", dsn)
+		if err != nil {
+			_ = group.Close()
+			return nil, nil, fmt.Errorf("failed to connect to shard %s: %w", name, err)
+		}
+		group[name] = shardDB
+
+		shardRepo := postgres.NewOrderRepository(shardDB, logger.With(slog.String("shard", name)))
+		shardRepo.SetRowLevelSecurity(cfg.Tenant.Enabled && cfg.Tenant.PostgresRLS)
+		shards[name] = shardRepo
+		shardRepos = append(shardRepos, shardRepo)
+
+		if name == cfg.Sharding.Directory {
+			directory = postgres.NewShardDirectory(shardDB, logger)
+			directoryDB = shardDB
+		}
+	}
+	// id товаров выделяются из последовательности базы справочника, чтобы быть уникальными во всех шардах
+	for _, shardRepo := range shardRepos {
+		shardRepo.SetItemIDSource(directoryDB)
+	}
+
+	repo, err := sharded.New(shards, shardMap, directory, logger)
+	if err != nil {
+		_ = group.Close()
+		return nil, nil, err
+	}
+	return repo, group, nil
+}
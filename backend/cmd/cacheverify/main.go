@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/cache/codec"
+	"github.com/Ravwvil/order-service/backend/internal/cache/invalidation"
+	"github.com/Ravwvil/order-service/backend/internal/cache/memory"
+	"github.com/Ravwvil/order-service/backend/internal/cache/redis"
+	"github.com/Ravwvil/order-service/backend/internal/cache/tiered"
+	"github.com/Ravwvil/order-service/backend/internal/config"
+	"github.com/Ravwvil/order-service/backend/internal/repository/postgres"
+	"github.com/Ravwvil/order-service/backend/internal/repository/sharded"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// exitMismatches код завершения, если найдены расхождения, которые не были исправлены
+const exitMismatches = 2
+
+// Cacheverify сверяет заказы в Redis с базой и при -repair исправляет расхождения.
+// Отчет выводится в stdout в JSON.
+func main() {
+	cfg, err := config.New()
+	if err != nil {
+		log.Printf("ERROR: Failed to load config: %v", err)
+		os.Exit(1)
+	}
+
+	sample := flag.Float64("sample", 1, "fraction of cached orders to verify, from 0 to 1")
+	limit := flag.Int("limit", 0, "maximum number of orders to verify, 0 - no limit")
+	repair := flag.Bool("repair", false, "overwrite stale entries and delete orphaned or corrupt ones")
+	flag.Parse()
+
+	report, err := run(cfg, service.VerifyConfig{
+		Sample:          *sample,
+		Limit:           *limit,
+		Repair:          *repair,
+		TenantIsolation: cfg.Tenant.Enabled,
+	})
+	if report != nil {
+		_ = json.NewEncoder(os.Stdout).Encode(report)
+	}
+	if err != nil {
+		log.Printf("ERROR: Cache verification failed: %v", err)
+		os.Exit(1)
+	}
+	if report.Mismatches() > 0 && !*repair {
+		os.Exit(exitMismatches)
+	}
+}
+
+func run(cfg *config.Config, verifyCfg service.VerifyConfig) (*service.VerifyReport, error) {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	repo, closeRepo, err := newRepository(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := closeRepo(); err != nil {
+			log.Printf("WARN: failed to close database connections: %v", err)
+		}
+	}()
+
+	rdb, err := redis.NewClient(cfg.Redis)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis config: %w", err)
+	}
+	redisCache := redis.New(rdb, time.Duration(cfg.Redis.TTL)*time.Second, logger)
+	defer redisCache.Close()
+
+	cacheCodec, err := codec.Parse(cfg.Cache.Codec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache codec config: %w", err)
+	}
+	redisCache.SetCodec(cacheCodec)
+
+	// Исправления публикуются в канал инвалидации, чтобы реплики сервиса удалили локальные копии
+	repairer := tiered.New(
+		memory.New(1, time.Second),
+		redisCache,
+		invalidation.New(redis.NewInvalidations(rdb, cfg.Cache.InvalidationChannel, logger), logger),
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	verifier := service.NewCacheVerifier(repo, redisCache, repairer, verifyCfg, logger)
+	return verifier.Verify(ctx)
+}
+
+// newRepository подключается к базе или ко всем шардам
+func newRepository(cfg *config.Config, logger *slog.Logger) (service.OrderRepository, func() error, error) {
+	if !cfg.Sharding.Enabled {
+		db, err := sqlx.Connect("postgres", cfg.Postgres.DSN())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+		repo := postgres.NewOrderRepository(db, logger)
+		repo.SetRowLevelSecurity(cfg.Tenant.Enabled && cfg.Tenant.PostgresRLS)
+		return repo, db.Close, nil
+	}
+
+	shardMap, err := sharded.ParseShardMap(cfg.Sharding.Map)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid SHARD_MAP: %w", err)
+	}
+
+	group := make(sharded.DBGroup, len(cfg.Sharding.DSNs))
+	shards := make(map[string]sharded.Shard, len(cfg.Sharding.DSNs))
+	var directory sharded.Directory
+	for name, dsn := range cfg.Sharding.DSNs {
+		db, err := sqlx.Connect("postgres", dsn)
+		if err != nil {
+			_ = group.Close()
+			return nil, nil, fmt.Errorf("failed to connect to shard %s: %w", name, err)
+		}
+		group[name] = db
+
+		shardRepo := postgres.NewOrderRepository(db, logger.With(slog.String("shard", name)))
+		shardRepo.SetRowLevelSecurity(cfg.Tenant.Enabled && cfg.Tenant.PostgresRLS)
+		shards[name] = shardRepo
+
+		if name == cfg.Sharding.Directory {
+			directory = postgres.NewShardDirectory(db, logger)
+		}
+	}
+
+	repo, err := sharded.New(shards, shardMap, directory, logger)
+	if err != nil {
+		_ = group.Close()
+		return nil, nil, err
+	}
+	return repo, group.Close, nil
+}
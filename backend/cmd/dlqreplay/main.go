@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/broker/kafka"
+	"github.com/Ravwvil/order-service/backend/internal/config"
+	"github.com/Ravwvil/order-service/backend/internal/repository/postgres"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Dlqreplay выводит сообщения DLQ, проверяет их текущим кодом и при -replay
+// публикует обратно в исходный топик. Отчет выводится в stdout в JSON.
+// Переигранные сообщения отмечаются в dlq_messages, поэтому повторный запуск
+// не публикует их снова.
+func main() {
+	cfg, err := config.New()
+	if err != nil {
+		log.Printf("ERROR: Failed to load config: %v", err)
+		os.Exit(1)
+	}
+
+	dlqTopic := flag.String("dlq-topic", cfg.Kafka.DLQTopic, "DLQ topic to read")
+	reason := flag.String("reason", "", "select messages whose failure reason contains this text")
+	key := flag.String("key", "", "select messages with this key (order_uid)")
+	from := flag.String("from", "", "select messages failed at or after this time, RFC3339")
+	to := flag.String("to", "", "select messages failed before this time, RFC3339")
+	limit := flag.Int("limit", 0, "maximum number of selected messages, 0 - no limit")
+	replay := flag.Bool("replay", false, "republish valid selected messages to their original topic; without it only a report is printed")
+	maxReplays := flag.Int("max-replays", 3, "skip messages already replayed this many times, 0 - no limit")
+	flag.Parse()
+
+	filter := kafka.ReplayFilter{Reason: *reason, Key: *key}
+	if filter.From, err = parseTime(*from); err != nil {
+		log.Printf("ERROR: Invalid -from: %v", err)
+		os.Exit(1)
+	}
+	if filter.To, err = parseTime(*to); err != nil {
+		log.Printf("ERROR: Invalid -to: %v", err)
+		os.Exit(1)
+	}
+	if *dlqTopic == "" {
+		log.Printf("ERROR: DLQ topic is not set")
+		os.Exit(1)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	// dlq_messages хранится в единственной базе или в базе справочника шардов.
+	// Dry-run работает и без базы, но тогда не отличает уже разобранные попадания.
+	dsn := cfg.Postgres.DSN()
+	if cfg.Sharding.Enabled {
+		dsn = cfg.Sharding.DSNs[cfg.Sharding.Directory]
+	}
+	var ledger kafka.ReplayLedger
+	db, err := sqlx.Connect("postgres", dsn)
+	switch {
+	case err == nil:
+		defer db.Close()
+		ledger = postgres.NewDLQRepository(db, logger)
+	case *replay:
+		log.Printf("ERROR: Failed to connect to postgres: %v", err)
+		os.Exit(1)
+	default:
+		log.Printf("WARN: Failed to connect to postgres, replayed messages are reported as valid: %v", err)
+	}
+
+	replayer := kafka.NewReplayer(cfg.Kafka.Brokers, *dlqTopic, kafka.ReplayConfig{
+		Filter:     filter,
+		Limit:      *limit,
+		Replay:     *replay,
+		MaxReplays: *maxReplays,
+		Topic:      cfg.Kafka.Topic,
+	}, ledger, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	report, err := replayer.Run(ctx)
+	stop()
+	if closeErr := replayer.Close(); closeErr != nil {
+		log.Printf("WARN: failed to close kafka producer: %v", closeErr)
+	}
+
+	if report != nil {
+		_ = json.NewEncoder(os.Stdout).Encode(report)
+	}
+	if err != nil {
+		log.Printf("ERROR: DLQ replay failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+// parseTime разбирает время RFC3339, пустая строка - без ограничения
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 time: %w", err)
+	}
+	return t, nil
+}
@@ -10,6 +10,8 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
+
+	"github.com/Ravwvil/order-service/backend/internal/config"
 )
 
 const (
@@ -35,6 +37,23 @@ func main() {
 
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
 
+	// При шардировании миграции применяются к каждому шарду
+	targets := map[string]string{"default": dsn}
+	if shardDSNs := os.Getenv("SHARD_DSNS"); shardDSNs != "" {
+		parsed, err := config.ParseShardDSNs(shardDSNs)
+		if err != nil {
+			log.Fatalf("failed to parse SHARD_DSNS: %v", err)
+		}
+		targets = parsed
+	}
+
+	for name, target := range targets {
+		log.Printf("Migrating database %q...", name)
+		migrateDB(target)
+	}
+}
+
+func migrateDB(dsn string) {
 	var m *migrate.Migrate
 	var err error
 
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Ravwvil/order-service/backend/internal/config"
+	"github.com/Ravwvil/order-service/backend/internal/repository/postgres"
+	"github.com/Ravwvil/order-service/backend/internal/repository/sharded"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Resharder переносит заказы между шардами с текущей карты SHARD_MAP на SHARD_MAP_TARGET.
+// После успешного запуска SHARD_MAP_TARGET становится новым SHARD_MAP сервиса.
+func main() {
+	dryRun := flag.Bool("dry-run", true, "only report orders that would be moved")
+	batchSize := flag.Int("batch-size", 500, "number of orders scanned per batch")
+	flag.Parse()
+
+	if err := run(*dryRun, *batchSize); err != nil {
+		log.Printf("ERROR: Resharder failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+func run(dryRun bool, batchSize int) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if len(cfg.Sharding.DSNs) == 0 || cfg.Sharding.Map == "" || cfg.Sharding.TargetMap == "" {
+		return fmt.Errorf("SHARD_DSNS, SHARD_MAP and SHARD_MAP_TARGET must be set")
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	current, err := sharded.ParseShardMap(cfg.Sharding.Map)
+	if err != nil {
+		return fmt.Errorf("invalid SHARD_MAP: %w", err)
+	}
+	target, err := sharded.ParseShardMap(cfg.Sharding.TargetMap)
+	if err != nil {
+		return fmt.Errorf("invalid SHARD_MAP_TARGET: %w", err)
+	}
+
+	group := make(sharded.DBGroup, len(cfg.Sharding.DSNs))
+	defer func() {
+		if err := group.Close(); err != nil {
+			log.Printf("WARN: failed to close shard connections: %v", err)
+		}
+	}()
+
+	shards := make(map[string]sharded.Shard, len(cfg.Sharding.DSNs))
+	var (
+		directory   sharded.Directory
+		directoryDB *sqlx.DB
+		shardRepos  []*postgres.OrderRepository
+	)
+	for name, dsn := range cfg.Sharding.DSNs {
+		db, err := sqlx.Connect("postgres", dsn)
+		if err != nil {
+			return fmt.Errorf("failed to connect to shard %s: %w", name, err)
+		}
+		group[name] = db
+		shardRepo := postgres.NewOrderRepository(db, logger.With(slog.String("shard", name)))
+		shards[name] = shardRepo
+		shardRepos = append(shardRepos, shardRepo)
+
+		if name == cfg.Sharding.Directory {
+			directory = postgres.NewShardDirectory(db, logger)
+			directoryDB = db
+		}
+	}
+	// Как и в приложении, id товаров выделяются из последовательности базы справочника
+	for _, shardRepo := range shardRepos {
+		shardRepo.SetItemIDSource(directoryDB)
+	}
+
+	repo, err := sharded.New(shards, current, directory, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := repo.Reshard(ctx, target, batchSize, dryRun)
+	if report != nil {
+		log.Printf("Resharding finished: scanned=%d moved=%d failed=%d dry_run=%t",
+			report.Scanned, report.Moved, report.Failed, report.DryRun)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d orders failed to move, rerun to retry", report.Failed)
+	}
+	return nil
+}
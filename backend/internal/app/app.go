@@ -4,8 +4,9 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"sync"
 
-	"github.com/Ravwvil/order-service/backend/internal/broker/kafka"
+	"github.com/Ravwvil/order-service/backend/internal/broker"
 	"github.com/Ravwvil/order-service/backend/internal/config"
 	"github.com/Ravwvil/order-service/backend/internal/service"
 	redisClient "github.com/redis/go-redis/v9"
@@ -23,14 +24,23 @@ type Rediser interface {
 	Close() error
 }
 
+// BackgroundJob фоновая задача, работающая до отмены контекста
+type BackgroundJob interface {
+	Run(ctx context.Context)
+}
+
 type App struct {
-	logger        *slog.Logger
-	server        *http.Server
-	consumer      kafka.ConsumerInterface
-	db            DBer
-	redis         Rediser
-	orderService  service.OrderServicer
-	cfg           *config.Config
+	logger       *slog.Logger
+	server       *http.Server
+	consumer     broker.Consumer
+	db           DBer
+	redis        Rediser
+	orderService service.OrderServicer
+	cfg          *config.Config
+
+	jobs       []BackgroundJob
+	jobsCancel context.CancelFunc
+	jobsWg     sync.WaitGroup
 }
 
 func NewApp(
@@ -39,17 +49,17 @@ func NewApp(
 	orderService service.OrderServicer,
 	db DBer,
 	redis Rediser,
-	consumer kafka.ConsumerInterface,
+	consumer broker.Consumer,
 	cfg *config.Config,
 ) *App {
 	return &App{
-		logger:        logger,
-		server:        server,
-		orderService:  orderService,
-		db:            db,
-		redis:         redis,
-		consumer:      consumer,
-		cfg:           cfg,
+		logger:       logger,
+		server:       server,
+		orderService: orderService,
+		db:           db,
+		redis:        redis,
+		consumer:     consumer,
+		cfg:          cfg,
 	}
 }
 
@@ -57,6 +67,11 @@ func (a *App) SetServer(server *http.Server) {
 	a.server = server
 }
 
+// AddJob регистрирует фоновую задачу, запускаемую вместе с приложением
+func (a *App) AddJob(job BackgroundJob) {
+	a.jobs = append(a.jobs, job)
+}
+
 func (a *App) Run(ctx context.Context) error {
 	// Восстанавливаем кэш из базы данных при запуске
 	if err := a.orderService.RestoreCache(ctx); err != nil {
@@ -64,11 +79,14 @@ func (a *App) Run(ctx context.Context) error {
 		// Не возвращаем ошибку, чтобы приложение могло запуститься
 	}
 
-	// Запускаем Kafka consumer
+	// Запускаем consumer брокера сообщений (Kafka или NATS JetStream)
 	if err := a.consumer.Start(ctx); err != nil {
 		return err
 	}
 
+	// Запускаем фоновые задачи
+	a.startJobs(ctx)
+
 	a.logger.Info("starting http server", slog.String("addr", a.server.Addr))
 	return a.server.ListenAndServe()
 }
@@ -78,9 +96,12 @@ func (a *App) Stop(ctx context.Context) error {
 
 	// Останавливаем consumer
 	if err := a.consumer.Stop(ctx); err != nil {
-		a.logger.Error("error stopping kafka consumer", "error", err)
+		a.logger.Error("error stopping consumer", "error", err)
 	}
 
+	// Останавливаем фоновые задачи
+	a.stopJobs(ctx)
+
 	// Закрываем подключения
 	if err := a.db.Close(); err != nil {
 		a.logger.Error("error closing database connection", "error", err)
@@ -94,6 +115,38 @@ func (a *App) Stop(ctx context.Context) error {
 	return a.server.Shutdown(ctx)
 }
 
+func (a *App) startJobs(ctx context.Context) {
+	var jobsCtx context.Context
+	jobsCtx, a.jobsCancel = context.WithCancel(ctx)
+
+	for _, job := range a.jobs {
+		a.jobsWg.Add(1)
+		go func(job BackgroundJob) {
+			defer a.jobsWg.Done()
+			job.Run(jobsCtx)
+		}(job)
+	}
+}
+
+func (a *App) stopJobs(ctx context.Context) {
+	if a.jobsCancel == nil {
+		return
+	}
+	a.jobsCancel()
+
+	done := make(chan struct{})
+	go func() {
+		a.jobsWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		a.logger.Warn("background jobs stop timeout")
+	}
+}
+
 // Health проверяет состояние приложения
 func (a *App) Health(ctx context.Context) error {
 	// Проверяем подключение к базе данных
@@ -101,15 +154,35 @@ func (a *App) Health(ctx context.Context) error {
 		return err
 	}
 
-	// Проверяем подключение к Redis
-	if err := a.redis.Ping(ctx).Err(); err != nil {
-		return err
-	}
-
 	// Проверяем состояние consumer
 	if err := a.consumer.Health(ctx); err != nil {
 		return err
 	}
 
+	// Без Redis заказы отдаются из базы, поэтому его недоступность - деградация, а не отказ
+	if err := a.redis.Ping(ctx).Err(); err != nil {
+		return &DegradedError{Component: "redis", Err: err}
+	}
+
 	return nil
 }
+
+// DegradedError сообщает, что недоступен необязательный компонент,
+// но приложение продолжает обслуживать запросы
+type DegradedError struct {
+	Component string
+	Err       error
+}
+
+func (e *DegradedError) Error() string {
+	return e.Component + " is unavailable: " + e.Err.Error()
+}
+
+func (e *DegradedError) Unwrap() error {
+	return e.Err
+}
+
+// Degraded отличает деградацию от отказа (см. обработчик /healthz)
+func (e *DegradedError) Degraded() bool {
+	return true
+}
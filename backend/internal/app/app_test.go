@@ -83,20 +83,25 @@ func TestApp_Health(t *testing.T) {
 		dbMock.AssertExpectations(t)
 	})
 
-	t.Run("redis unhealthy", func(t *testing.T) {
+	t.Run("redis unhealthy degrades", func(t *testing.T) {
 		dbMock := new(mockDB)
 		redisMock := new(mockRedis)
+		consumerMock := new(mockConsumer)
 		redisErr := errors.New("redis down")
 
 		dbMock.On("PingContext", ctx).Return(nil).Once()
+		consumerMock.On("Health", ctx).Return(nil).Once()
 		redisMock.On("Ping", ctx).Return(redisClient.NewStatusResult("", redisErr)).Once()
 
-		app := NewApp(logger, nil, nil, dbMock, redisMock, nil, nil)
+		app := NewApp(logger, nil, nil, dbMock, redisMock, consumerMock, nil)
 		err := app.Health(ctx)
-		assert.Error(t, err)
-		assert.Equal(t, redisErr, err)
+		assert.ErrorIs(t, err, redisErr)
+		var degraded *DegradedError
+		assert.ErrorAs(t, err, &degraded)
+		assert.Equal(t, "redis", degraded.Component)
 		dbMock.AssertExpectations(t)
 		redisMock.AssertExpectations(t)
+		consumerMock.AssertExpectations(t)
 	})
 
 	t.Run("consumer unhealthy", func(t *testing.T) {
@@ -106,7 +111,6 @@ func TestApp_Health(t *testing.T) {
 		consumerErr := errors.New("kafka down")
 
 		dbMock.On("PingContext", ctx).Return(nil).Once()
-		redisMock.On("Ping", ctx).Return(redisClient.NewStatusResult("", nil)).Once()
 		consumerMock.On("Health", ctx).Return(consumerErr).Once()
 
 		app := NewApp(logger, nil, nil, dbMock, redisMock, consumerMock, nil)
@@ -114,7 +118,36 @@ func TestApp_Health(t *testing.T) {
 		assert.Error(t, err)
 		assert.Equal(t, consumerErr, err)
 		dbMock.AssertExpectations(t)
-		redisMock.AssertExpectations(t)
+		redisMock.AssertNotCalled(t, "Ping", ctx)
 		consumerMock.AssertExpectations(t)
 	})
-} 
\ No newline at end of file
+}
+
+type mockJob struct {
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (j *mockJob) Run(ctx context.Context) {
+	close(j.started)
+	<-ctx.Done()
+	close(j.stopped)
+}
+
+func TestApp_Jobs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	job := &mockJob{started: make(chan struct{}), stopped: make(chan struct{})}
+
+	app := NewApp(logger, nil, nil, nil, nil, nil, nil)
+	app.AddJob(job)
+	app.startJobs(context.Background())
+
+	<-job.started
+	app.stopJobs(context.Background())
+
+	select {
+	case <-job.stopped:
+	default:
+		t.Fatal("job was not stopped")
+	}
+}
@@ -0,0 +1,97 @@
+// Package broker содержит не зависящую от транспорта часть обработки сообщений с заказами:
+// разбор и обработку с повторами, выбор итога и заголовки сообщений повторов и DLQ.
+// Транспорты (kafka, nats) переводят итог в свои операции.
+package broker
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// Consumer потребитель сообщений с заказами (kafka.Consumer, nats.Consumer)
+type Consumer interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Health(ctx context.Context) error
+}
+
+// Outcome итог обработки сообщения
+type Outcome int
+
+const (
+	// OutcomeSuccess заказ обработан: Kafka коммитит offset, JetStream подтверждает (ack)
+	OutcomeSuccess Outcome = iota
+	// OutcomeRetry временная ошибка, повторы не исчерпаны: Kafka публикует сообщение
+	// в топик следующего уровня, JetStream возвращает его с задержкой (nak)
+	OutcomeRetry
+	// OutcomeDLQ постоянная ошибка или повторы исчерпаны: сообщение публикуется в DLQ,
+	// после чего Kafka коммитит offset, а JetStream прекращает доставку (term)
+	OutcomeDLQ
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeRetry:
+		return "retry"
+	default:
+		return "dlq"
+	}
+}
+
+// Decide выбирает итог по ошибке обработки. canRetry - у транспорта остались повторы
+// (уровни топиков повторов, попытки доставки).
+func Decide(processingErr error, canRetry bool) Outcome {
+	if processingErr == nil {
+		return OutcomeSuccess
+	}
+	if canRetry && domain.ClassifyError(processingErr) == domain.ErrorClassRetryable {
+		return OutcomeRetry
+	}
+	return OutcomeDLQ
+}
+
+// Заголовки сообщений повторов и DLQ, общие для всех транспортов
+const (
+	HeaderOriginalTopic = "x-original-topic" // топик Kafka или subject JetStream исходного сообщения
+	HeaderFailureReason = "x-failure-reason"
+	HeaderErrorClass    = "x-error-class"
+	HeaderFailedAt      = "x-failed-at"
+	HeaderReplayCount   = "x-replay-count"
+	HeaderReplayedAt    = "x-replayed-at"
+	HeaderReplayedFrom  = "x-replayed-from"
+)
+
+// Header заголовок сообщения
+type Header struct {
+	Key   string
+	Value string
+}
+
+// FailureHeaders возвращает заголовки с причиной и классом ошибки обработки
+func FailureHeaders(processingErr error) []Header {
+	return []Header{
+		{Key: HeaderFailureReason, Value: processingErr.Error()},
+		{Key: HeaderErrorClass, Value: string(domain.ClassifyError(processingErr))},
+		{Key: HeaderFailedAt, Value: time.Now().UTC().Format(time.RFC3339)},
+	}
+}
+
+// WorkerIndex выбирает воркера по хешу ключа сообщения (order_uid), чтобы изменения
+// одного заказа обрабатывались последовательно, а разных - параллельно.
+// Сообщения без ключа распределяются по fallback (например, номеру партиции).
+func WorkerIndex(key []byte, fallback, workers int) int {
+	if workers <= 1 {
+		return 0
+	}
+	if len(key) == 0 {
+		return fallback % workers
+	}
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	return int(h.Sum32() % uint32(workers))
+}
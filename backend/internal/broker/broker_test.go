@@ -0,0 +1,105 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOrderService мок для метода ProcessOrderMessage интерфейса OrderServicer.
+type MockOrderService struct {
+	service.OrderServicer
+	mock.Mock
+}
+
+func (m *MockOrderService) ProcessOrderMessage(ctx context.Context, order *domain.Order) error {
+	return m.Called(ctx, order).Error(0)
+}
+
+func TestDecide(t *testing.T) {
+	retryable := errors.New("connection refused")
+	permanent := domain.Permanent(errors.New("order validation failed"))
+
+	assert.Equal(t, OutcomeSuccess, Decide(nil, true))
+	assert.Equal(t, OutcomeRetry, Decide(retryable, true))
+	assert.Equal(t, OutcomeDLQ, Decide(retryable, false))
+	assert.Equal(t, OutcomeDLQ, Decide(permanent, true))
+}
+
+func TestFailureHeaders(t *testing.T) {
+	headers := make(map[string]string)
+	for _, h := range FailureHeaders(domain.Permanent(errors.New("order validation failed"))) {
+		headers[h.Key] = h.Value
+	}
+	assert.Equal(t, "order validation failed", headers[HeaderFailureReason])
+	assert.Equal(t, string(domain.ErrorClassPermanent), headers[HeaderErrorClass])
+	_, err := time.Parse(time.RFC3339, headers[HeaderFailedAt])
+	assert.NoError(t, err)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, BackoffFactor: 2}
+
+	assert.GreaterOrEqual(t, policy.Backoff(1), 100*time.Millisecond)
+	assert.Less(t, policy.Backoff(1), 110*time.Millisecond)
+	assert.GreaterOrEqual(t, policy.Backoff(3), 400*time.Millisecond)
+	assert.Equal(t, time.Second, policy.Backoff(10))
+	assert.Equal(t, 50*time.Millisecond, RetryPolicy{InitialDelay: 50 * time.Millisecond}.Backoff(3))
+}
+
+func TestProcessor_Process(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	policy := RetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond}
+	order := []byte(`{"order_uid":"uid-1"}`)
+
+	t.Run("retries retryable errors", func(t *testing.T) {
+		orderService := &MockOrderService{}
+		orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Return(errors.New("timeout")).Twice()
+		orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Return(nil).Once()
+
+		assert.NoError(t, NewProcessor(orderService, policy, logger).Process(context.Background(), order))
+		orderService.AssertExpectations(t)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		orderService := &MockOrderService{}
+		orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Return(errors.New("timeout"))
+
+		err := NewProcessor(orderService, policy, logger).Process(context.Background(), order)
+		assert.Error(t, err)
+		assert.Equal(t, domain.ErrorClassRetryable, domain.ClassifyError(err))
+		orderService.AssertNumberOfCalls(t, "ProcessOrderMessage", 3)
+	})
+
+	t.Run("permanent error is not retried", func(t *testing.T) {
+		orderService := &MockOrderService{}
+		orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).
+			Return(domain.Permanent(errors.New("order validation failed"))).Once()
+
+		err := NewProcessor(orderService, policy, logger).Process(context.Background(), order)
+		assert.Equal(t, domain.ErrorClassPermanent, domain.ClassifyError(err))
+		orderService.AssertExpectations(t)
+	})
+
+	t.Run("unreadable JSON is permanent", func(t *testing.T) {
+		orderService := &MockOrderService{}
+
+		err := NewProcessor(orderService, policy, logger).Process(context.Background(), []byte("{not json"))
+		assert.Equal(t, domain.ErrorClassPermanent, domain.ClassifyError(err))
+		orderService.AssertNotCalled(t, "ProcessOrderMessage", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWorkerIndex(t *testing.T) {
+	assert.Equal(t, WorkerIndex([]byte("order-1"), 0, 8), WorkerIndex([]byte("order-1"), 5, 8))
+	assert.Equal(t, 3, WorkerIndex(nil, 11, 8))
+	assert.Equal(t, 0, WorkerIndex([]byte("order-1"), 5, 1))
+}
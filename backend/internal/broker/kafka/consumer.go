@@ -2,47 +2,58 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"math"
-	"math/rand"
 	"runtime"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/Ravwvil/order-service/backend/internal/broker"
 	"github.com/Ravwvil/order-service/backend/internal/domain"
 	"github.com/Ravwvil/order-service/backend/internal/service"
 	"github.com/segmentio/kafka-go"
 )
 
-// ConsumerInterface интерфейс для Kafka Consumer
-type ConsumerInterface interface {
-	Start(ctx context.Context) error
-	Stop(ctx context.Context) error
-	Health(ctx context.Context) error
+// messageReader чтение и коммит сообщений группы (kafka.Reader)
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Stats() kafka.ReaderStats
+	Close() error
+}
+
+// messageWriter отправка сообщений в DLQ и топики повторов (kafka.Writer)
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
 }
 
 // Consumer (Kafka) для обработки заказов
 type Consumer struct {
-	reader       *kafka.Reader
-	producer     *kafka.Writer // Для отправки в DLQ
-	orderService service.OrderServicer
-	logger       *slog.Logger
-	wg           *sync.WaitGroup
-	cancel       context.CancelFunc
-	msgChan      chan kafka.Message
+	reader      messageReader
+	offsets     *offsetTracker
+	producer    messageWriter      // Для отправки в DLQ и топики повторов
+	handOff     broker.RetryPolicy // паузы между попытками передачи в DLQ или топик повторов
+	processor   *broker.Processor
+	logger      *slog.Logger
+	wg          *sync.WaitGroup
+	cancel      context.CancelFunc
+	workerChans []chan kafka.Message // очередь каждого воркера, см. workerIndex
+
+	// Повторы через топики уровней: основной consumer имеет tier 0 и запускает
+	// consumer'ы уровней, сообщение уровня i обрабатывается consumer'ом с tier i
+	tier           int
+	retryTiers     []retryTier
+	retryConsumers []*Consumer
 
 	// Конфигурация
-	brokers           []string
-	topic             string
-	groupID           string
-	maxRetries        int
-	initialRetryDelay time.Duration
-	maxRetryDelay     time.Duration
-	backoffFactor     float64
-	dlqTopic          string
-	concurrency       int
+	brokers     []string
+	topic       string
+	groupID     string
+	dlqTopic    string
+	concurrency int
 }
 
 // Config для Kafka consumer
@@ -56,22 +67,70 @@ type Config struct {
 	BackoffFactor     float64
 	DLQTopic          string
 	Concurrency       int
+	RetryTiers        []time.Duration // задержки уровней топиков повторов, пусто - повторы в воркере
+	MaxInFlight       int             // предел незакоммиченных сообщений партиции, 0 - defaultMaxInFlight
 }
 
-func NewConsumer(cfg Config, orderService service.OrderServicer, logger *slog.Logger) *Consumer {
-	logger.Debug("creating new kafka consumer",
-		slog.String("topic", cfg.Topic),
-		slog.String("group_id", cfg.GroupID),
-		slog.Any("brokers", cfg.Brokers))
+// defaultMaxInFlight предел незакоммиченных сообщений партиции по умолчанию
+const defaultMaxInFlight = 1000
+
+// handOffRetry паузы между попытками передать сообщение в топик повторов или DLQ
+var handOffRetry = broker.RetryPolicy{
+	InitialDelay:  time.Second,
+	MaxDelay:      30 * time.Second,
+	BackoffFactor: 2,
+}
 
+func NewConsumer(cfg Config, orderService service.OrderServicer, logger *slog.Logger) *Consumer {
 	if cfg.Concurrency <= 0 {
 		cfg.Concurrency = runtime.NumCPU()
 	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = defaultMaxInFlight
+	}
+
+	var producer messageWriter
+	if cfg.DLQTopic != "" || len(cfg.RetryTiers) > 0 {
+		// Топик задается в каждом сообщении: DLQ или топик уровня повторов
+		producer = &kafka.Writer{
+			Addr:                   kafka.TCP(cfg.Brokers...),
+			Balancer:               &kafka.LeastBytes{},
+			AllowAutoTopicCreation: true,
+		}
+	}
+
+	tiers := newRetryTiers(cfg.Topic, cfg.RetryTiers)
+	if len(tiers) > 0 {
+		// Между попытками сообщение ждет в топике уровня, а не в воркере
+		cfg.MaxRetries = 0
+	}
+
+	consumer := newConsumer(cfg, cfg.Topic, cfg.GroupID, orderService, logger)
+	consumer.producer = producer
+	consumer.retryTiers = tiers
+	for i, tier := range tiers {
+		retry := newConsumer(cfg, tier.topic, cfg.GroupID+"-"+tier.topic, orderService,
+			logger.With(slog.String("retry_topic", tier.topic)))
+		retry.producer = producer
+		retry.tier = i + 1
+		retry.retryTiers = tiers
+		consumer.retryConsumers = append(consumer.retryConsumers, retry)
+	}
+
+	return consumer
+}
+
+// newConsumer создает consumer одного топика
+func newConsumer(cfg Config, topic, groupID string, orderService service.OrderServicer, logger *slog.Logger) *Consumer {
+	logger.Debug("creating new kafka consumer",
+		slog.String("topic", topic),
+		slog.String("group_id", groupID),
+		slog.Any("brokers", cfg.Brokers))
 
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers: cfg.Brokers,
-		Topic:   cfg.Topic,
-		GroupID: cfg.GroupID,
+		Topic:   topic,
+		GroupID: groupID,
 
 		// Настройки производительности
 		MinBytes: 1e3,  // 1KB
@@ -90,51 +149,50 @@ func NewConsumer(cfg Config, orderService service.OrderServicer, logger *slog.Lo
 		CommitInterval: 1 * time.Second,
 	})
 
-	var producer *kafka.Writer
-	if cfg.DLQTopic != "" {
-		producer = &kafka.Writer{
-			Addr:     kafka.TCP(cfg.Brokers...),
-			Topic:    cfg.DLQTopic,
-			Balancer: &kafka.LeastBytes{},
-		}
+	consumer := &Consumer{
+		reader:  reader,
+		offsets: newOffsetTracker(cfg.MaxInFlight),
+		handOff: handOffRetry,
+		processor: broker.NewProcessor(orderService, broker.RetryPolicy{
+			MaxRetries:    cfg.MaxRetries,
+			InitialDelay:  cfg.InitialRetryDelay,
+			MaxDelay:      cfg.MaxRetryDelay,
+			BackoffFactor: cfg.BackoffFactor,
+		}, logger),
+		logger:      logger,
+		wg:          &sync.WaitGroup{},
+		workerChans: make([]chan kafka.Message, cfg.Concurrency),
+		brokers:     cfg.Brokers,
+		topic:       topic,
+		groupID:     groupID,
+		dlqTopic:    cfg.DLQTopic,
+		concurrency: cfg.Concurrency,
 	}
 
-	consumer := &Consumer{
-		reader:            reader,
-		producer:          producer,
-		orderService:      orderService,
-		logger:            logger,
-		wg:                &sync.WaitGroup{},
-		msgChan:           make(chan kafka.Message, cfg.Concurrency),
-		brokers:           cfg.Brokers,
-		topic:             cfg.Topic,
-		groupID:           cfg.GroupID,
-		maxRetries:        cfg.MaxRetries,
-		initialRetryDelay: cfg.InitialRetryDelay,
-		maxRetryDelay:     cfg.MaxRetryDelay,
-		backoffFactor:     cfg.BackoffFactor,
-		dlqTopic:          cfg.DLQTopic,
-		concurrency:       cfg.Concurrency,
+	for i := range consumer.workerChans {
+		consumer.workerChans[i] = make(chan kafka.Message, workerQueueSize)
 	}
 
 	logger.Info("kafka consumer created successfully",
-		slog.String("topic", cfg.Topic),
-		slog.String("group_id", cfg.GroupID))
+		slog.String("topic", topic),
+		slog.String("group_id", groupID))
 
 	return consumer
 }
 
 // Start запускает consumer для чтения сообщений из Kafka
 func (c *Consumer) Start(ctx context.Context) error {
+	retry := c.processor.RetryPolicy()
 	c.logger.Info("starting kafka consumer",
 		slog.String("topic", c.topic),
 		slog.String("group_id", c.groupID),
 		slog.Any("brokers", c.brokers),
-		slog.Int("max_retries", c.maxRetries),
-		slog.Duration("initial_retry_delay", c.initialRetryDelay),
-		slog.Duration("max_retry_delay", c.maxRetryDelay),
-		slog.Float64("backoff_factor", c.backoffFactor),
+		slog.Int("max_retries", retry.MaxRetries),
+		slog.Duration("initial_retry_delay", retry.InitialDelay),
+		slog.Duration("max_retry_delay", retry.MaxDelay),
+		slog.Float64("backoff_factor", retry.BackoffFactor),
 		slog.String("dlq_topic", c.dlqTopic),
+		slog.Int("retry_tiers", len(c.retryTiers)),
 		slog.Int("concurrency", c.concurrency))
 
 	var consumerCtx context.Context
@@ -149,13 +207,44 @@ func (c *Consumer) Start(ctx context.Context) error {
 	c.wg.Add(1)
 	go c.consumeMessages(consumerCtx)
 
+	// Запускаем consumer'ы уровней повторов
+	for _, retry := range c.retryConsumers {
+		if err := retry.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start retry consumer %s: %w", retry.topic, err)
+		}
+	}
+
 	c.logger.Info("kafka consumer started successfully")
 	return nil
 }
 
-// Stop останавливает consumer и ждет завершения обработки
+// Stop останавливает consumer и consumer'ы уровней повторов и ждет завершения обработки
 func (c *Consumer) Stop(ctx context.Context) error {
-	c.logger.Info("stopping kafka consumer")
+	var errs []error
+	for _, consumer := range append([]*Consumer{c}, c.retryConsumers...) {
+		if err := consumer.stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Producer общий для всех уровней, закрываем после остановки воркеров
+	if c.producer != nil {
+		if err := c.producer.Close(); err != nil {
+			c.logger.Error("error closing kafka dlq producer", slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("failed to close kafka dlq producer: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	c.logger.Info("kafka consumer stopped successfully")
+	return nil
+}
+
+// stop останавливает воркеры и reader одного топика
+func (c *Consumer) stop(ctx context.Context) error {
+	c.logger.Info("stopping kafka consumer", slog.String("topic", c.topic))
 
 	// Сигнализируем о завершении
 	c.cancel()
@@ -173,23 +262,21 @@ func (c *Consumer) Stop(ctx context.Context) error {
 		c.logger.Warn("kafka consumer stop timeout")
 	}
 
-	// Закрываем канал сообщений
-	close(c.msgChan)
+	// Сообщения, не вошедшие в закоммиченный префикс партиции, будут доставлены повторно
+	if pending := c.offsets.Pending(); pending > 0 {
+		c.logger.Warn("uncommitted messages will be redelivered", slog.Int("count", pending))
+	}
+
+	// Закрываем очереди воркеров
+	for _, ch := range c.workerChans {
+		close(ch)
+	}
 
 	// Закрываем reader
 	if err := c.reader.Close(); err != nil {
 		c.logger.Error("error closing kafka reader", slog.String("error", err.Error()))
 		return fmt.Errorf("failed to close kafka reader: %w", err)
 	}
-
-	if c.producer != nil {
-		if err := c.producer.Close(); err != nil {
-			c.logger.Error("error closing kafka dlq producer", slog.String("error", err.Error()))
-			return fmt.Errorf("failed to close kafka dlq producer: %w", err)
-		}
-	}
-
-	c.logger.Info("kafka consumer stopped successfully")
 	return nil
 }
 
@@ -202,7 +289,7 @@ func (c *Consumer) worker(ctx context.Context, id int) {
 		case <-ctx.Done():
 			c.logger.Info("worker context cancelled, stopping", slog.Int("worker_id", id))
 			return
-		case msg, ok := <-c.msgChan:
+		case msg, ok := <-c.workerChans[id]:
 			if !ok {
 				c.logger.Info("message channel closed, stopping worker", slog.Int("worker_id", id))
 				return
@@ -211,49 +298,103 @@ func (c *Consumer) worker(ctx context.Context, id int) {
 				slog.Int("worker_id", id),
 				slog.Int64("offset", msg.Offset))
 
+			// Сообщение уровня повторов обрабатывается не раньше назначенного времени
+			if !c.waitUntilDue(ctx, msg) {
+				c.logger.Info("consumer stopped while waiting for retry, leaving message uncommitted",
+					slog.Int64("offset", msg.Offset),
+					slog.Int("partition", msg.Partition))
+				return
+			}
+
 			// Обрабатываем сообщение
 			processingErr := c.processMessage(ctx, msg)
 			if processingErr == nil {
 				// Успешная обработка, коммитим
-				if err := c.reader.CommitMessages(ctx, msg); err != nil {
-					c.logger.Error("error committing message",
-						slog.String("error", err.Error()),
-						slog.Int64("offset", msg.Offset),
-						slog.Int("partition", msg.Partition))
-				} else {
-					c.logger.Debug("message committed successfully",
-						slog.Int64("offset", msg.Offset),
-						slog.Int("partition", msg.Partition))
-				}
+				c.commit(ctx, msg)
 				continue
 			}
 
+			// Остановка во время обработки или ожидания повтора: сообщение не завершено
+			// и не коммитится, после перезапуска оно будет доставлено снова
+			if ctx.Err() != nil {
+				c.logger.Info("consumer stopped while processing message, leaving it uncommitted",
+					slog.Int64("offset", msg.Offset),
+					slog.Int("partition", msg.Partition))
+				return
+			}
+
 			// Ошибка обработки
-			c.logger.Error("error processing message, attempting to send to DLQ",
+			c.logger.Error("error processing message, handing it off to retry topic or DLQ",
 				slog.String("error", processingErr.Error()),
+				slog.String("error_class", string(domain.ClassifyError(processingErr))),
 				slog.Int64("offset", msg.Offset),
 				slog.Int("partition", msg.Partition))
 
-			// Пытаемся отправить в DLQ
-			if dlqErr := c.handleFailedMessage(ctx, msg, processingErr); dlqErr != nil {
-				c.logger.Error("failed to send message to DLQ, message will be re-processed",
-					slog.String("dlq_error", dlqErr.Error()),
-					slog.Int64("offset", msg.Offset))
-				// Не коммитим, позволяем kafka-go повторить доставку
-				continue
+			// Отправляем на следующий уровень повторов или в DLQ. Пока передача не удалась,
+			// сообщение не завершено и коммиты партиции стоят на нем, поэтому воркер
+			// повторяет передачу, а не берет следующее сообщение
+			if !c.handOffFailed(ctx, msg, processingErr) {
+				c.logger.Info("consumer stopped while handing off message, leaving it uncommitted",
+					slog.Int64("offset", msg.Offset),
+					slog.Int("partition", msg.Partition))
+				return
 			}
 
-			// Успешно отправлено в DLQ, коммитим, чтобы не обрабатывать снова
-			c.logger.Info("message sent to DLQ, committing offset", slog.Int64("offset", msg.Offset))
-			if commitErr := c.reader.CommitMessages(ctx, msg); commitErr != nil {
-				c.logger.Error("error committing message after sending to DLQ",
-					slog.String("error", commitErr.Error()),
-					slog.Int64("offset", msg.Offset))
-			}
+			// Успешно передано дальше, коммитим, чтобы не обрабатывать снова
+			c.logger.Info("failed message handed off, committing offset", slog.Int64("offset", msg.Offset))
+			c.commit(ctx, msg)
+		}
+	}
+}
+
+// handOffFailed передает необработанное сообщение дальше, повторяя попытки с
+// экспоненциальной паузой. false, если consumer остановлен раньше.
+func (c *Consumer) handOffFailed(ctx context.Context, msg kafka.Message, processingErr error) bool {
+	for attempt := 1; ; attempt++ {
+		err := c.handleFailedMessage(ctx, msg, processingErr)
+		if err == nil {
+			return true
+		}
+
+		delay := c.handOff.Backoff(attempt)
+		c.logger.Error("failed to hand off message, retrying",
+			slog.String("dlq_error", err.Error()),
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Int64("offset", msg.Offset),
+			slog.Int("partition", msg.Partition))
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
 		}
 	}
 }
 
+// commit отмечает сообщение завершенным и коммитит наибольший offset партиции,
+// до которого все сообщения завершены
+func (c *Consumer) commit(ctx context.Context, msg kafka.Message) {
+	commitMsg, ok := c.offsets.Done(msg)
+	if !ok {
+		c.logger.Debug("message completed, waiting for earlier offsets of partition",
+			slog.Int64("offset", msg.Offset),
+			slog.Int("partition", msg.Partition))
+		return
+	}
+
+	if err := c.reader.CommitMessages(ctx, commitMsg); err != nil {
+		c.logger.Error("error committing message",
+			slog.String("error", err.Error()),
+			slog.Int64("offset", commitMsg.Offset),
+			slog.Int("partition", commitMsg.Partition))
+		return
+	}
+	c.logger.Debug("message committed successfully",
+		slog.Int64("offset", commitMsg.Offset),
+		slog.Int("partition", commitMsg.Partition))
+}
+
 // consumeMessages основной цикл чтения сообщений
 func (c *Consumer) consumeMessages(ctx context.Context) {
 	defer c.wg.Done()
@@ -283,18 +424,38 @@ func (c *Consumer) consumeMessages(ctx context.Context) {
 				slog.Int64("offset", msg.Offset),
 				slog.Int("partition", msg.Partition))
 
-			// Отправляем сообщение в канал для обработки воркерами
+			// Ждем, пока у партиции освободится место среди незакоммиченных сообщений
+			if err := c.offsets.Start(ctx, msg); err != nil {
+				c.logger.Info("context cancelled while waiting for in-flight messages of partition",
+					slog.Int64("offset", msg.Offset),
+					slog.Int("partition", msg.Partition))
+				return
+			}
+
+			// Сообщения с одним ключом всегда попадают к одному воркеру и обрабатываются по порядку
+			workerID := workerIndex(msg, len(c.workerChans))
 			select {
 			case <-ctx.Done():
 				c.logger.Info("context cancelled, not sending message to worker", slog.Int64("offset", msg.Offset))
 				return
-			case c.msgChan <- msg:
-				c.logger.Debug("message sent to worker channel", slog.Int64("offset", msg.Offset))
+			case c.workerChans[workerID] <- msg:
+				c.logger.Debug("message sent to worker channel",
+					slog.Int64("offset", msg.Offset),
+					slog.Int("worker_id", workerID))
 			}
 		}
 	}
 }
 
+// workerQueueSize емкость очереди одного воркера
+const workerQueueSize = 16
+
+// workerIndex выбирает воркера по ключу сообщения (order_uid), сообщения без ключа -
+// по партиции, сохраняя порядок внутри партиции
+func workerIndex(msg kafka.Message, workers int) int {
+	return broker.WorkerIndex(msg.Key, msg.Partition, workers)
+}
+
 // processMessage обрабатывает отдельное сообщение
 func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) error {
 	c.logger.Debug("processing message",
@@ -302,100 +463,21 @@ func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) error
 		slog.Int("partition", msg.Partition),
 		slog.String("key", string(msg.Key)))
 
-	// Парсим JSON сообщение
-	var order domain.Order
-	if err := json.Unmarshal(msg.Value, &order); err != nil {
-		c.logger.Error("error unmarshaling order",
-			slog.String("error", err.Error()),
-			slog.String("value", string(msg.Value)))
-		return fmt.Errorf("unmarshal order: %w", err)
-	}
-
-	c.logger.Debug("order unmarshaled successfully",
-		slog.String("order_uid", order.OrderUID))
-
-	// Обрабатываем заказ с повторными попытками
-	return c.processOrderWithRetry(ctx, &order)
+	return c.processor.Process(ctx, msg.Value)
 }
 
-// processOrderWithRetry обрабатывает заказ с механизмом повторных попыток и экспоненциальной задержкой
-func (c *Consumer) processOrderWithRetry(ctx context.Context, order *domain.Order) error {
-	c.logger.Debug("starting order processing with retry",
-		slog.String("order_uid", order.OrderUID),
-		slog.Int("max_retries", c.maxRetries))
-
-	var lastErr error
-
-	for attempt := 1; attempt <= c.maxRetries+1; attempt++ {
-		c.logger.Debug("attempting to process order",
-			slog.String("order_uid", order.OrderUID),
-			slog.Int("attempt", attempt))
-
-		err := c.orderService.ProcessOrderMessage(ctx, order)
-		if err == nil {
-			c.logger.Info("order processed successfully",
-				slog.String("order_uid", order.OrderUID),
-				slog.Int("attempt", attempt))
-			return nil
-		}
-
-		lastErr = err
-		c.logger.Warn("order processing failed",
-			slog.String("order_uid", order.OrderUID),
-			slog.Int("attempt", attempt),
-			slog.Int("max_retries", c.maxRetries),
-			slog.String("error", err.Error()))
-
-		if attempt <= c.maxRetries {
-			delay := c.calculateBackoff(attempt)
-			c.logger.Debug("waiting before retry",
-				slog.String("order_uid", order.OrderUID),
-				slog.Duration("delay", delay))
-
-			select {
-			case <-ctx.Done():
-				c.logger.Debug("context cancelled during retry wait",
-					slog.String("order_uid", order.OrderUID))
-				return ctx.Err()
-			case <-time.After(delay): // Продолжение после задержки
-			}
-		}
-	}
-
-	c.logger.Error("failed to process order after all retry attempts",
-		slog.String("order_uid", order.OrderUID),
-		slog.Int("max_retries", c.maxRetries),
-		slog.String("error", lastErr.Error()))
-
-	return fmt.Errorf("failed to process order after %d attempts: %w", c.maxRetries+1, lastErr)
-}
-
-func (c *Consumer) calculateBackoff(attempt int) time.Duration {
-	if c.initialRetryDelay <= 0 || c.backoffFactor <= 1 || c.maxRetryDelay <= 0 {
-		return c.initialRetryDelay // Fallback to simple retry delay
-	}
-	backoff := float64(c.initialRetryDelay) * math.Pow(c.backoffFactor, float64(attempt-1))
-	delay := time.Duration(backoff)
-
-	// Добавляем джиттер
-	if delay > 0 {
-		jitterMax := int64(delay) / 10
-		if jitterMax > 0 {
-			jitter := time.Duration(rand.Int63n(jitterMax))
-			delay += jitter
-		}
-	}
-
-	if delay > c.maxRetryDelay {
-		delay = c.maxRetryDelay
+// handleFailedMessage передает необработанное сообщение на следующий уровень повторов,
+// а при постоянной ошибке или после последнего уровня - в DLQ
+func (c *Consumer) handleFailedMessage(ctx context.Context, msg kafka.Message, processingErr error) error {
+	if broker.Decide(processingErr, c.tier < len(c.retryTiers)) == broker.OutcomeRetry {
+		return c.sendToRetry(msg, processingErr)
 	}
-
-	return delay
+	return c.sendToDLQ(msg, processingErr)
 }
 
-// handleFailedMessage обрабатывает сообщение, которое не удалось обработать
-func (c *Consumer) handleFailedMessage(ctx context.Context, msg kafka.Message, processingErr error) error {
-	if c.producer == nil {
+// sendToDLQ отправляет сообщение в DLQ
+func (c *Consumer) sendToDLQ(msg kafka.Message, processingErr error) error {
+	if c.producer == nil || c.dlqTopic == "" {
 		c.logger.Warn("DLQ producer is not configured, message will be re-processed or lost",
 			slog.Int64("offset", msg.Offset))
 		return nil // Не возвращаем ошибку, чтобы не зацикливаться, если DLQ не настроен
@@ -405,16 +487,15 @@ func (c *Consumer) handleFailedMessage(ctx context.Context, msg kafka.Message, p
 		slog.String("dlq_topic", c.dlqTopic),
 		slog.Int64("offset", msg.Offset))
 
+	headers := c.failureHeaders(msg, processingErr)
+	if c.tier > 0 {
+		headers = append(headers, kafka.Header{Key: headerRetryTier, Value: []byte(strconv.Itoa(c.tier))})
+	}
 	dlqMsg := kafka.Message{
-		Key:   msg.Key,
-		Value: msg.Value,
-		Headers: []kafka.Header{
-			{Key: "x-original-topic", Value: []byte(c.topic)},
-			{Key: "x-original-offset", Value: []byte(fmt.Sprintf("%d", msg.Offset))},
-			{Key: "x-original-partition", Value: []byte(fmt.Sprintf("%d", msg.Partition))},
-			{Key: "x-failure-reason", Value: []byte(processingErr.Error())},
-			{Key: "x-failed-at", Value: []byte(time.Now().UTC().Format(time.RFC3339))},
-		},
+		Topic:   c.dlqTopic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
 	}
 
 	// Используем новый контекст с таймаутом, чтобы гарантировать отправку в DLQ
@@ -457,8 +538,8 @@ func (c *Consumer) Health(ctx context.Context) error {
 	}
 
 	// Проверяем, что воркеры запущены
-	if c.concurrency > 0 && c.msgChan == nil {
-		return fmt.Errorf("consumer is not running, msgChan is nil")
+	if c.concurrency > 0 && len(c.workerChans) == 0 {
+		return fmt.Errorf("consumer is not running, worker channels are not created")
 	}
 
 	return nil
@@ -42,6 +42,42 @@ func (m *MockOrderService) GetOrderByUID(ctx context.Context, uid string) (*doma
 	return nil, args.Error(1)
 }
 
+// GetOrderJSON мок для метода GetOrderJSON.
+func (m *MockOrderService) GetOrderJSON(ctx context.Context, uid string) ([]byte, error) {
+	args := m.Called(ctx, uid)
+	if data := args.Get(0); data != nil {
+		return data.([]byte), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// GetOrdersByUIDs мок для метода GetOrdersByUIDs.
+func (m *MockOrderService) GetOrdersByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error) {
+	args := m.Called(ctx, uids)
+	if orders := args.Get(0); orders != nil {
+		return orders.([]*domain.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// GetOrdersByCustomer мок для метода GetOrdersByCustomer.
+func (m *MockOrderService) GetOrdersByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	args := m.Called(ctx, customerID)
+	if orders := args.Get(0); orders != nil {
+		return orders.([]*domain.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// GetOrderByTrackNumber мок для метода GetOrderByTrackNumber.
+func (m *MockOrderService) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error) {
+	args := m.Called(ctx, trackNumber)
+	if order := args.Get(0); order != nil {
+		return order.(*domain.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 // RestoreCache мок для метода RestoreCache.
 func (m *MockOrderService) RestoreCache(ctx context.Context) error {
 	args := m.Called(ctx)
@@ -276,4 +312,61 @@ func TestKafkaConsumer_RetryAndDLQ(t *testing.T) {
 	}
 	assert.Equal(t, testTopic, headers["x-original-topic"])
 	assert.Contains(t, headers["x-failure-reason"], "processing failed")
+	assert.Equal(t, string(domain.ErrorClassRetryable), headers["x-error-class"])
+}
+
+// TestConsumer_PermanentErrorSkipsRetries проверяет, что постоянные ошибки не ждут повторов.
+func TestConsumer_PermanentErrorSkipsRetries(t *testing.T) {
+	value, err := json.Marshal(domain.Order{OrderUID: "invalid-order"})
+	require.NoError(t, err)
+	log := &memoryLog{messages: []kafka.Message{
+		{Key: []byte("broken"), Value: []byte("{not json"), Offset: 0},
+		{Key: []byte("invalid-order"), Value: value, Offset: 1},
+	}}
+
+	orderService := &MockOrderService{}
+	orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).
+		Return(domain.Permanent(errors.New("order validation failed"))).Once()
+
+	// Задержка повтора - час: сообщения дойдут до DLQ, только если повторов не было
+	consumer := newMemoryConsumer(log, orderService, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, consumer.Start(ctx))
+
+	assert.Eventually(t, func() bool { return log.Committed() == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, consumer.Stop(context.Background()))
+	orderService.AssertExpectations(t)
+}
+
+// TestWorkerIndex тестирует распределение сообщений по воркерам.
+func TestWorkerIndex(t *testing.T) {
+	const workers = 8
+
+	t.Run("same key goes to same worker", func(t *testing.T) {
+		first := workerIndex(kafka.Message{Key: []byte("order-1"), Partition: 0}, workers)
+		for partition := 0; partition < 4; partition++ {
+			assert.Equal(t, first, workerIndex(kafka.Message{Key: []byte("order-1"), Partition: partition}, workers))
+		}
+	})
+
+	t.Run("keys spread across workers", func(t *testing.T) {
+		used := make(map[int]bool)
+		for i := 0; i < 100; i++ {
+			idx := workerIndex(kafka.Message{Key: []byte(fmt.Sprintf("order-%d", i))}, workers)
+			assert.GreaterOrEqual(t, idx, 0)
+			assert.Less(t, idx, workers)
+			used[idx] = true
+		}
+		assert.Greater(t, len(used), workers/2)
+	})
+
+	t.Run("keyless messages follow partition", func(t *testing.T) {
+		assert.Equal(t, 3, workerIndex(kafka.Message{Partition: 3}, workers))
+		assert.Equal(t, 1, workerIndex(kafka.Message{Partition: 9}, workers))
+	})
+
+	t.Run("single worker", func(t *testing.T) {
+		assert.Equal(t, 0, workerIndex(kafka.Message{Key: []byte("order-1"), Partition: 5}, 1))
+	})
 }
@@ -0,0 +1,183 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/segmentio/kafka-go"
+)
+
+// dlqSinkRetryDelay пауза перед повторным сохранением сообщения при недоступной базе
+const dlqSinkRetryDelay = 5 * time.Second
+
+// DLQSink читает DLQ своей группой и сохраняет сообщения в базу,
+// чтобы их можно было разобрать через административный API без доступа к Kafka
+type DLQSink struct {
+	reader     messageReader
+	recorder   service.DLQRecorder
+	topic      string // исходный топик для сообщений без x-original-topic
+	retryDelay time.Duration
+	logger     *slog.Logger
+}
+
+// NewDLQSink создает sink топика cfg.DLQTopic с группой "<GroupID>-dlq-sink"
+func NewDLQSink(cfg Config, recorder service.DLQRecorder, logger *slog.Logger) *DLQSink {
+	groupID := cfg.GroupID + "-dlq-sink"
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		Topic:       cfg.DLQTopic,
+		GroupID:     groupID,
+		MinBytes:    1,
+		MaxBytes:    10e6, // 10MB
+		MaxWait:     1 * time.Second,
+		StartOffset: kafka.FirstOffset,
+		ErrorLogger: kafka.LoggerFunc(func(msg string, args ...interface{}) {
+			logger.Error("kafka dlq sink reader error", slog.String("message", fmt.Sprintf(msg, args...)))
+		}),
+	})
+
+	return &DLQSink{
+		reader:     reader,
+		recorder:   recorder,
+		topic:      cfg.Topic,
+		retryDelay: dlqSinkRetryDelay,
+		logger:     logger.With(slog.String("dlq_topic", cfg.DLQTopic), slog.String("group_id", groupID)),
+	}
+}
+
+// Run читает DLQ до отмены контекста. Offset коммитится только после сохранения сообщения,
+// при ошибке базы сохранение повторяется, и чтение дальше не идет.
+func (s *DLQSink) Run(ctx context.Context) {
+	s.logger.Info("dlq sink started")
+	defer func() {
+		if err := s.reader.Close(); err != nil {
+			s.logger.Error("error closing kafka dlq sink reader", slog.String("error", err.Error()))
+		}
+		s.logger.Info("dlq sink stopped")
+	}()
+
+	for {
+		msg, err := s.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Error("error fetching dlq message", slog.String("error", err.Error()))
+			continue
+		}
+
+		if !s.record(ctx, msg) {
+			return
+		}
+
+		if err := s.reader.CommitMessages(ctx, msg); err != nil && !errors.Is(err, context.Canceled) {
+			// Сообщение будет сохранено повторно, запись в базе от этого не изменится
+			s.logger.Error("error committing dlq message",
+				slog.String("error", err.Error()),
+				slog.Int64("offset", msg.Offset),
+				slog.Int("partition", msg.Partition))
+		}
+	}
+}
+
+// record сохраняет сообщение, повторяя попытки до успеха. false, если sink остановлен раньше.
+func (s *DLQSink) record(ctx context.Context, msg kafka.Message) bool {
+	entry := dlqEntry(msg, s.topic)
+	for {
+		err := s.recorder.Record(ctx, entry)
+		if err == nil {
+			s.logger.Debug("dlq message recorded",
+				slog.Int64("offset", msg.Offset),
+				slog.Int("partition", msg.Partition),
+				slog.String("key", entry.Key))
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		s.logger.Error("failed to record dlq message, retrying",
+			slog.String("error", err.Error()),
+			slog.Int64("offset", msg.Offset),
+			slog.Int("partition", msg.Partition),
+			slog.Duration("retry_delay", s.retryDelay))
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(s.retryDelay):
+		}
+	}
+}
+
+// dlqEntry описывает сообщение DLQ для сохранения в базе.
+// topic - исходный топик для сообщений без x-original-topic.
+func dlqEntry(msg kafka.Message, topic string) *domain.DLQEntry {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+
+	entry := &domain.DLQEntry{
+		Key:           string(msg.Key),
+		Payload:       msg.Value,
+		Headers:       headers,
+		OriginalTopic: headers[headerOriginalTopic],
+		ErrorClass:    domain.ErrorClass(headers[headerErrorClass]),
+		FailureReason: headers[headerFailureReason],
+		LastSeen:      failedAt(msg),
+		DLQPartition:  msg.Partition,
+		DLQOffset:     msg.Offset,
+	}
+	if entry.OriginalTopic == "" {
+		entry.OriginalTopic = topic
+	}
+	// Сообщения, записанные до появления x-error-class, считаются временными ошибками
+	if entry.ErrorClass == "" {
+		entry.ErrorClass = domain.ErrorClassRetryable
+	}
+	return entry
+}
+
+// Requeuer публикует сообщения DLQ, сохраненные в базе, обратно в исходный топик
+type Requeuer struct {
+	producer messageWriter
+	dlqTopic string
+}
+
+func NewRequeuer(brokers []string, dlqTopic string) *Requeuer {
+	return &Requeuer{
+		producer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{}, // ключ order_uid попадает в ту же партицию, что и при исходной публикации
+			RequiredAcks: kafka.RequireAll,
+		},
+		dlqTopic: dlqTopic,
+	}
+}
+
+// Requeue публикует сообщение с увеличенным счетчиком переигрываний, как и Replayer,
+// чтобы петля "DLQ -> топик -> DLQ" была видна по заголовку x-replay-count
+func (r *Requeuer) Requeue(ctx context.Context, entry *domain.DLQEntry) error {
+	if entry.OriginalTopic == "" {
+		return fmt.Errorf("dlq entry %d has no original topic", entry.ID)
+	}
+
+	replays := parseReplayCount(entry.Headers[headerReplayCount])
+	msg := replayMessage(entry.OriginalTopic, []byte(entry.Key), entry.Payload, replays,
+		fmt.Sprintf("%s/%d/%d", r.dlqTopic, entry.DLQPartition, entry.DLQOffset))
+	if err := r.producer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to requeue dlq entry %d: %w", entry.ID, err)
+	}
+	return nil
+}
+
+// Close закрывает producer
+func (r *Requeuer) Close() error {
+	return r.producer.Close()
+}
@@ -0,0 +1,100 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDLQRecorder мок для интерфейса service.DLQRecorder
+type MockDLQRecorder struct {
+	mock.Mock
+}
+
+func (m *MockDLQRecorder) Record(ctx context.Context, entry *domain.DLQEntry) error {
+	return m.Called(ctx, entry).Error(0)
+}
+
+func TestDLQSink(t *testing.T) {
+	failedAt := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	first := dlqMessage(0, "uid-1", []byte(`{"order_uid":"uid-1"}`), "order validation failed", failedAt, "2")
+	second := dlqMessage(1, "uid-2", []byte(`not json`), "unmarshal order", failedAt, "")
+	second.Headers = second.Headers[1:] // без x-original-topic
+	log := &memoryLog{messages: []kafka.Message{first, second}}
+
+	recorder := new(MockDLQRecorder)
+	// База недоступна при первой попытке: сообщение сохраняется повторно и только потом коммитится
+	recorder.On("Record", mock.Anything, mock.Anything).Return(errors.New("connection refused")).Once()
+	recorder.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+	sink := &DLQSink{
+		reader:     newMemoryReader(log),
+		recorder:   recorder,
+		topic:      testTopic,
+		retryDelay: time.Millisecond,
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sink.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return log.Committed() == 2 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	recorder.AssertNumberOfCalls(t, "Record", 3)
+	entry := recorder.Calls[0].Arguments.Get(1).(*domain.DLQEntry)
+	assert.Equal(t, "uid-1", entry.Key)
+	assert.Equal(t, testTopic, entry.OriginalTopic)
+	assert.Equal(t, domain.ErrorClassRetryable, entry.ErrorClass)
+	assert.Equal(t, "order validation failed", entry.FailureReason)
+	assert.Equal(t, "2", entry.Headers[headerReplayCount])
+	assert.True(t, entry.LastSeen.Equal(failedAt))
+
+	entry = recorder.Calls[2].Arguments.Get(1).(*domain.DLQEntry)
+	assert.Equal(t, "uid-2", entry.Key)
+	assert.Equal(t, []byte("not json"), entry.Payload)
+	assert.Equal(t, testTopic, entry.OriginalTopic)
+	assert.Equal(t, int64(1), entry.DLQOffset)
+}
+
+func TestRequeuer(t *testing.T) {
+	writer := newMemoryWriter(testTopic)
+	requeuer := &Requeuer{producer: writer, dlqTopic: dlqTopic}
+
+	entry := &domain.DLQEntry{
+		ID:            7,
+		Key:           "uid-1",
+		Payload:       []byte(`{"order_uid":"uid-1"}`),
+		Headers:       map[string]string{headerReplayCount: "2"},
+		OriginalTopic: testTopic,
+		DLQPartition:  0,
+		DLQOffset:     42,
+	}
+	require.NoError(t, requeuer.Requeue(context.Background(), entry))
+
+	messages := writer.messages(testTopic)
+	require.Len(t, messages, 1)
+	msg := messages[0]
+	assert.Equal(t, "uid-1", string(msg.Key))
+	assert.Equal(t, entry.Payload, msg.Value)
+	assert.Equal(t, 3, replayCount(msg))
+	from, _ := headerValue(msg, headerReplayedOf)
+	assert.Equal(t, dlqTopic+"/0/42", from)
+
+	entry.OriginalTopic = ""
+	assert.Error(t, requeuer.Requeue(context.Background(), entry))
+}
@@ -0,0 +1,113 @@
+package kafka
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// offsetTracker отслеживает сообщения, переданные воркерам, по партициям.
+// Воркеры завершают сообщения в произвольном порядке, а коммитить можно только
+// непрерывный префикс партиции: иначе при падении процесса сообщение, которое
+// еще повторяется, оказалось бы за закоммиченным offset'ом и было бы потеряно.
+// Число незакоммиченных сообщений партиции ограничено limit: пока сообщение в
+// начале префикса не завершено, чтение останавливается, а не копит память.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+	limit      int
+	released   chan struct{} // закрывается при сдвиге префикса любой партиции
+}
+
+// partitionOffsets сообщения партиции в порядке получения, еще не закоммиченные
+type partitionOffsets struct {
+	inFlight []trackedMessage
+}
+
+type trackedMessage struct {
+	msg  kafka.Message
+	done bool
+}
+
+// newOffsetTracker создает трекер; limit <= 0 - без ограничения
+func newOffsetTracker(limit int) *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[int]*partitionOffsets),
+		limit:      limit,
+		released:   make(chan struct{}),
+	}
+}
+
+// Start регистрирует сообщение, полученное из Kafka, до передачи воркеру.
+// Сообщение с offset'ом не больше уже полученного означает повторную доставку
+// (например, после ребалансировки), и состояние партиции начинается заново.
+// Если у партиции уже limit незакоммиченных сообщений, Start ждет, пока префикс
+// сдвинется, или отмены контекста.
+func (t *offsetTracker) Start(ctx context.Context, msg kafka.Message) error {
+	for {
+		t.mu.Lock()
+		p, ok := t.partitions[msg.Partition]
+		if !ok || (len(p.inFlight) > 0 && msg.Offset <= p.inFlight[len(p.inFlight)-1].msg.Offset) {
+			p = &partitionOffsets{}
+			t.partitions[msg.Partition] = p
+		}
+		if t.limit <= 0 || len(p.inFlight) < t.limit {
+			p.inFlight = append(p.inFlight, trackedMessage{msg: msg})
+			t.mu.Unlock()
+			return nil
+		}
+		released := t.released
+		t.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// Done отмечает сообщение завершенным (обработанным или отправленным в DLQ) и возвращает
+// последнее сообщение непрерывного префикса завершенных, если его можно закоммитить
+func (t *offsetTracker) Done(msg kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[msg.Partition]
+	if !ok {
+		return kafka.Message{}, false
+	}
+	i := sort.Search(len(p.inFlight), func(i int) bool { return p.inFlight[i].msg.Offset >= msg.Offset })
+	if i == len(p.inFlight) || p.inFlight[i].msg.Offset != msg.Offset {
+		// Сообщение из доставки до повторного чтения партиции уже не отслеживается
+		return kafka.Message{}, false
+	}
+	p.inFlight[i].done = true
+
+	n := 0
+	for n < len(p.inFlight) && p.inFlight[n].done {
+		n++
+	}
+	if n == 0 {
+		return kafka.Message{}, false
+	}
+	commit := p.inFlight[n-1].msg
+	p.inFlight = p.inFlight[n:]
+	close(t.released)
+	t.released = make(chan struct{})
+	return commit, true
+}
+
+// Pending возвращает число незакоммиченных сообщений по всем партициям
+func (t *offsetTracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var n int
+	for _, p := range t.partitions {
+		n += len(p.inFlight)
+	}
+	return n
+}
@@ -0,0 +1,311 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/broker"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOffsetTracker тестирует вычисление offset'а для коммита.
+func TestOffsetTracker(t *testing.T) {
+	ctx := context.Background()
+	msg := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Partition: partition, Offset: offset}
+	}
+
+	t.Run("out of order completion", func(t *testing.T) {
+		tracker := newOffsetTracker(0)
+		for offset := int64(0); offset < 4; offset++ {
+			require.NoError(t, tracker.Start(ctx, msg(0, offset)))
+		}
+
+		_, ok := tracker.Done(msg(0, 2))
+		assert.False(t, ok, "offset 2 cannot be committed before 0 and 1")
+		_, ok = tracker.Done(msg(0, 1))
+		assert.False(t, ok)
+
+		commit, ok := tracker.Done(msg(0, 0))
+		require.True(t, ok)
+		assert.Equal(t, int64(2), commit.Offset)
+		assert.Equal(t, 1, tracker.Pending())
+
+		commit, ok = tracker.Done(msg(0, 3))
+		require.True(t, ok)
+		assert.Equal(t, int64(3), commit.Offset)
+		assert.Zero(t, tracker.Pending())
+	})
+
+	t.Run("gap in offsets", func(t *testing.T) {
+		// Offset'ы компактированного топика или транзакций идут с пропусками
+		tracker := newOffsetTracker(0)
+		require.NoError(t, tracker.Start(ctx, msg(0, 10)))
+		require.NoError(t, tracker.Start(ctx, msg(0, 15)))
+
+		_, ok := tracker.Done(msg(0, 15))
+		assert.False(t, ok)
+		commit, ok := tracker.Done(msg(0, 10))
+		require.True(t, ok)
+		assert.Equal(t, int64(15), commit.Offset)
+	})
+
+	t.Run("partitions are independent", func(t *testing.T) {
+		tracker := newOffsetTracker(0)
+		require.NoError(t, tracker.Start(ctx, msg(0, 0)))
+		require.NoError(t, tracker.Start(ctx, msg(1, 0)))
+		require.NoError(t, tracker.Start(ctx, msg(1, 1)))
+
+		commit, ok := tracker.Done(msg(1, 0))
+		require.True(t, ok)
+		assert.Equal(t, 1, commit.Partition)
+		assert.Equal(t, int64(0), commit.Offset)
+		assert.Equal(t, 2, tracker.Pending())
+	})
+
+	t.Run("redelivery resets partition", func(t *testing.T) {
+		tracker := newOffsetTracker(0)
+		require.NoError(t, tracker.Start(ctx, msg(0, 5)))
+		require.NoError(t, tracker.Start(ctx, msg(0, 6)))
+
+		// После ребалансировки партиция читается заново с закоммиченного offset'а
+		require.NoError(t, tracker.Start(ctx, msg(0, 5)))
+		assert.Equal(t, 1, tracker.Pending())
+
+		// Завершение сообщения прежней доставки, которое еще не прочитано заново, не учитывается
+		_, ok := tracker.Done(msg(0, 6))
+		assert.False(t, ok)
+		commit, ok := tracker.Done(msg(0, 5))
+		require.True(t, ok)
+		assert.Equal(t, int64(5), commit.Offset)
+
+		require.NoError(t, tracker.Start(ctx, msg(0, 6)))
+		_, ok = tracker.Done(msg(0, 7))
+		assert.False(t, ok)
+		commit, ok = tracker.Done(msg(0, 6))
+		require.True(t, ok)
+		assert.Equal(t, int64(6), commit.Offset)
+	})
+
+	t.Run("unknown message", func(t *testing.T) {
+		tracker := newOffsetTracker(0)
+		_, ok := tracker.Done(msg(3, 1))
+		assert.False(t, ok)
+	})
+
+	t.Run("limit blocks until prefix is committed", func(t *testing.T) {
+		tracker := newOffsetTracker(2)
+		require.NoError(t, tracker.Start(ctx, msg(0, 0)))
+		require.NoError(t, tracker.Start(ctx, msg(0, 1)))
+		// Другие партиции не ограничены сообщениями партиции 0
+		require.NoError(t, tracker.Start(ctx, msg(1, 0)))
+
+		started := make(chan error, 1)
+		go func() { started <- tracker.Start(ctx, msg(0, 2)) }()
+
+		// Завершение сообщения за незавершенным началом префикса место не освобождает
+		_, ok := tracker.Done(msg(0, 1))
+		assert.False(t, ok)
+		select {
+		case <-started:
+			t.Fatal("partition limit exceeded")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		_, ok = tracker.Done(msg(0, 0))
+		require.True(t, ok)
+		select {
+		case err := <-started:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("start was not released after commit")
+		}
+		assert.Equal(t, 2, tracker.Pending())
+	})
+
+	t.Run("limit wait is cancelled", func(t *testing.T) {
+		tracker := newOffsetTracker(1)
+		require.NoError(t, tracker.Start(ctx, msg(0, 0)))
+
+		cancelled, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, tracker.Start(cancelled, msg(0, 1)), context.DeadlineExceeded)
+	})
+}
+
+// memoryLog партиция Kafka в памяти с закоммиченным offset'ом группы
+type memoryLog struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed int64
+}
+
+func (l *memoryLog) Committed() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.committed
+}
+
+// memoryReader читает memoryLog с закоммиченного offset'а, как новый участник группы
+type memoryReader struct {
+	log  *memoryLog
+	next int64
+}
+
+func newMemoryReader(log *memoryLog) *memoryReader {
+	return &memoryReader{log: log, next: log.Committed()}
+}
+
+func (r *memoryReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		r.log.mu.Lock()
+		if r.next < int64(len(r.log.messages)) {
+			msg := r.log.messages[r.next]
+			r.next++
+			r.log.mu.Unlock()
+			return msg, nil
+		}
+		r.log.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *memoryReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.log.mu.Lock()
+	defer r.log.mu.Unlock()
+	for _, msg := range msgs {
+		if msg.Offset+1 <= r.log.committed {
+			return fmt.Errorf("commit of offset %d moves group offset %d backwards", msg.Offset, r.log.committed)
+		}
+		r.log.committed = msg.Offset + 1
+	}
+	return nil
+}
+
+func (r *memoryReader) Stats() kafka.ReaderStats { return kafka.ReaderStats{} }
+
+func (r *memoryReader) Close() error { return nil }
+
+// newMemoryConsumer создает consumer поверх memoryLog
+func newMemoryConsumer(log *memoryLog, orderService *MockOrderService, concurrency int) *Consumer {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	consumer := &Consumer{
+		reader:      newMemoryReader(log),
+		offsets:     newOffsetTracker(0),
+		handOff:     broker.RetryPolicy{InitialDelay: 10 * time.Millisecond},
+		processor:   broker.NewProcessor(orderService, broker.RetryPolicy{MaxRetries: 5, InitialDelay: time.Hour}, logger),
+		logger:      logger,
+		wg:          &sync.WaitGroup{},
+		workerChans: make([]chan kafka.Message, concurrency),
+		topic:       testTopic,
+		concurrency: concurrency,
+	}
+	for i := range consumer.workerChans {
+		consumer.workerChans[i] = make(chan kafka.Message, workerQueueSize)
+	}
+	return consumer
+}
+
+// TestConsumer_CrashDuringRetry проверяет, что сообщение, которое повторялось в момент
+// остановки процесса, не теряется, хотя последующие сообщения партиции уже обработаны.
+func TestConsumer_CrashDuringRetry(t *testing.T) {
+	const (
+		total       = 6
+		concurrency = 6
+		failing     = "order-1"
+	)
+
+	log := &memoryLog{}
+	for i := 0; i < total; i++ {
+		value, err := json.Marshal(domain.Order{OrderUID: fmt.Sprintf("order-%d", i)})
+		require.NoError(t, err)
+		log.messages = append(log.messages, kafka.Message{
+			Key:    []byte(fmt.Sprintf("order-%d", i)),
+			Value:  value,
+			Offset: int64(i),
+		})
+	}
+
+	var (
+		mu        sync.Mutex
+		processed = make(map[string]bool)
+	)
+	record := func(args mock.Arguments) {
+		mu.Lock()
+		defer mu.Unlock()
+		processed[args.Get(1).(*domain.Order).OrderUID] = true
+	}
+	isProcessed := func(uid string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return processed[uid]
+	}
+
+	// Первый запуск: order-1 падает и ждет повтора, остальные обрабатываются
+	firstService := &MockOrderService{}
+	firstService.On("ProcessOrderMessage", mock.Anything, mock.MatchedBy(func(o *domain.Order) bool {
+		return o.OrderUID == failing
+	})).Return(errors.New("database unavailable"))
+	firstService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Run(record).Return(nil)
+
+	first := newMemoryConsumer(log, firstService, concurrency)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, first.Start(ctx))
+
+	// Ждем сообщения, которые не стоят в очереди воркера за order-1
+	failingWorker := workerIndex(log.messages[1], concurrency)
+	assert.Eventually(t, func() bool {
+		for _, msg := range log.messages {
+			if string(msg.Key) != failing && workerIndex(msg, concurrency) != failingWorker && !isProcessed(string(msg.Key)) {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Закоммичен только offset 0: order-1 еще не завершен
+	assert.Eventually(t, func() bool { return log.Committed() == 1 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int64(1), log.Committed())
+
+	// Падение процесса во время ожидания повтора
+	cancel()
+	require.NoError(t, first.Stop(context.Background()))
+	assert.Equal(t, int64(1), log.Committed(), "message in retry must not be committed on shutdown")
+
+	// Перезапуск: чтение продолжается с order-1
+	secondService := &MockOrderService{}
+	secondService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Run(record).Return(nil)
+
+	second := newMemoryConsumer(log, secondService, concurrency)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, second.Start(ctx))
+
+	assert.Eventually(t, func() bool { return log.Committed() == total }, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, isProcessed(failing))
+	for _, msg := range log.messages {
+		assert.True(t, isProcessed(string(msg.Key)), "message %s lost", msg.Key)
+	}
+
+	require.NoError(t, second.Stop(context.Background()))
+}
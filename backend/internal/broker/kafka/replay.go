@@ -0,0 +1,424 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/broker"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовки повторно опубликованных сообщений DLQ
+const (
+	headerReplayCount = broker.HeaderReplayCount
+	headerReplayedAt  = broker.HeaderReplayedAt
+	headerReplayedOf  = broker.HeaderReplayedFrom // "<dlq topic>/<partition>/<offset>"
+)
+
+// Статусы сообщений в отчете ReplayReport
+const (
+	ReplayStatusValid    = "valid"    // проходит проверку текущим кодом, будет опубликовано при Replay
+	ReplayStatusInvalid  = "invalid"  // снова упадет с постоянной ошибкой, не публикуется
+	ReplayStatusLooping  = "looping"  // уже переигрывалось MaxReplays раз, не публикуется
+	ReplayStatusHandled  = "handled"  // уже переотправлено или отброшено, либо снова попало в DLQ позже
+	ReplayStatusReplayed = "replayed" // опубликовано в исходный топик
+)
+
+// ReplayFilter отбор сообщений DLQ. Пустые поля не ограничивают выборку.
+type ReplayFilter struct {
+	Reason string    // подстрока x-failure-reason
+	Key    string    // ключ сообщения (order_uid)
+	From   time.Time // время попадания в DLQ (x-failed-at), включительно
+	To     time.Time // время попадания в DLQ, не включительно
+}
+
+// Match проверяет, подходит ли сообщение под фильтр
+func (f ReplayFilter) Match(msg kafka.Message) bool {
+	if f.Key != "" && string(msg.Key) != f.Key {
+		return false
+	}
+	if f.Reason != "" {
+		reason, _ := headerValue(msg, headerFailureReason)
+		if !strings.Contains(reason, f.Reason) {
+			return false
+		}
+	}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		failedAt := failedAt(msg)
+		if !f.From.IsZero() && failedAt.Before(f.From) {
+			return false
+		}
+		if !f.To.IsZero() && !failedAt.Before(f.To) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplayConfig настройки переигрывания DLQ
+type ReplayConfig struct {
+	Filter ReplayFilter
+	Limit  int // максимум отобранных сообщений, 0 - без ограничения
+	// Replay публикует отобранные сообщения в исходный топик, иначе только отчет (dry-run)
+	Replay bool
+	// MaxReplays останавливает петли: сообщение, уже переигранное столько раз, не публикуется
+	MaxReplays int
+	// Topic топик для сообщений без x-original-topic
+	Topic string
+}
+
+// ReplayedMessage сообщение DLQ в отчете
+type ReplayedMessage struct {
+	Partition     int       `json:"partition"`
+	Offset        int64     `json:"offset"`
+	Key           string    `json:"key"`
+	OriginalTopic string    `json:"original_topic"`
+	FailureReason string    `json:"failure_reason"`
+	ErrorClass    string    `json:"error_class,omitempty"`
+	FailedAt      time.Time `json:"failed_at"`
+	ReplayCount   int       `json:"replay_count"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"` // ошибка проверки для статуса invalid
+}
+
+// ReplayReport результат прогона по DLQ
+type ReplayReport struct {
+	Scanned  int               `json:"scanned"`
+	Matched  int               `json:"matched"`
+	Valid    int               `json:"valid"` // прошли проверку, включая опубликованные
+	Invalid  int               `json:"invalid"`
+	Looping  int               `json:"looping"`
+	Handled  int               `json:"handled"`
+	Replayed int               `json:"replayed"`
+	Messages []ReplayedMessage `json:"messages"`
+}
+
+// dlqSource перебирает сообщения DLQ, записанные к началу прогона
+type dlqSource interface {
+	Messages(ctx context.Context, fn func(msg kafka.Message) error) error
+}
+
+// ReplayLedger состояние разбора сообщений DLQ в базе (postgres.DLQRepository).
+// Попадание сообщения в DLQ (партиция и offset) переигрывается не больше одного раза
+// за все прогоны, в том числе с переотправкой через административный API.
+// Более позднее попадание того же сообщения - новая попытка, даже если sink его не сохранил.
+type ReplayLedger interface {
+	// Replayed проверяет, разобрано ли попадание
+	Replayed(ctx context.Context, entry *domain.DLQEntry) (bool, error)
+	// ClaimReplay отмечает попадание переотправленным; false, если оно уже разобрано
+	ClaimReplay(ctx context.Context, entry *domain.DLQEntry) (bool, error)
+	// ReleaseReplay возвращает попадание в разбор после неудачной публикации
+	ReleaseReplay(ctx context.Context, entry *domain.DLQEntry) error
+}
+
+// errNoLedger возвращается при ReplayConfig.Replay без ReplayLedger
+var errNoLedger = errors.New("replay requires the dlq ledger")
+
+// errReplayLimit прерывает перебор DLQ по достижении ReplayConfig.Limit
+var errReplayLimit = errors.New("replay limit reached")
+
+// Replayer читает DLQ, проверяет сообщения текущим кодом и публикует их обратно в исходный топик
+type Replayer struct {
+	source   dlqSource
+	producer messageWriter
+	ledger   ReplayLedger
+	dlqTopic string
+	cfg      ReplayConfig
+	logger   *slog.Logger
+}
+
+// NewReplayer создает прогон по DLQ. ledger может быть nil только для dry-run:
+// тогда уже разобранные попадания не отличаются от неразобранных.
+func NewReplayer(brokers []string, dlqTopic string, cfg ReplayConfig, ledger ReplayLedger, logger *slog.Logger) *Replayer {
+	return &Replayer{
+		source: &partitionSource{brokers: brokers, topic: dlqTopic},
+		producer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{}, // ключ order_uid попадает в ту же партицию, что и при исходной публикации
+			RequiredAcks: kafka.RequireAll,
+		},
+		ledger:   ledger,
+		dlqTopic: dlqTopic,
+		cfg:      cfg,
+		logger:   logger,
+	}
+}
+
+// Run выполняет прогон по DLQ
+func (r *Replayer) Run(ctx context.Context) (*ReplayReport, error) {
+	if r.cfg.Replay && r.ledger == nil {
+		return nil, errNoLedger
+	}
+	report := &ReplayReport{Messages: []ReplayedMessage{}}
+
+	err := r.source.Messages(ctx, func(msg kafka.Message) error {
+		report.Scanned++
+		if !r.cfg.Filter.Match(msg) {
+			return nil
+		}
+		if r.cfg.Limit > 0 && report.Matched >= r.cfg.Limit {
+			return errReplayLimit
+		}
+		report.Matched++
+
+		entry := r.inspect(msg)
+		if entry.Status == ReplayStatusValid {
+			status, err := r.replay(ctx, msg, entry)
+			if err != nil {
+				return err
+			}
+			entry.Status = status
+		}
+
+		switch entry.Status {
+		case ReplayStatusValid:
+			report.Valid++
+		case ReplayStatusInvalid:
+			report.Invalid++
+		case ReplayStatusLooping:
+			report.Looping++
+		case ReplayStatusHandled:
+			report.Handled++
+		case ReplayStatusReplayed:
+			report.Valid++
+			report.Replayed++
+		}
+		report.Messages = append(report.Messages, entry)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errReplayLimit) {
+		return report, fmt.Errorf("failed to replay %s: %w", r.dlqTopic, err)
+	}
+
+	r.logger.Info("dlq replay finished",
+		slog.String("dlq_topic", r.dlqTopic),
+		slog.Bool("replay", r.cfg.Replay),
+		slog.Int("scanned", report.Scanned),
+		slog.Int("matched", report.Matched),
+		slog.Int("invalid", report.Invalid),
+		slog.Int("looping", report.Looping),
+		slog.Int("handled", report.Handled),
+		slog.Int("replayed", report.Replayed))
+	return report, nil
+}
+
+// Close закрывает producer
+func (r *Replayer) Close() error {
+	return r.producer.Close()
+}
+
+// inspect описывает сообщение DLQ и проверяет, пройдет ли оно обработку текущим кодом
+func (r *Replayer) inspect(msg kafka.Message) ReplayedMessage {
+	entry := ReplayedMessage{
+		Partition:   msg.Partition,
+		Offset:      msg.Offset,
+		Key:         string(msg.Key),
+		FailedAt:    failedAt(msg),
+		ReplayCount: replayCount(msg),
+	}
+	entry.OriginalTopic, _ = headerValue(msg, headerOriginalTopic)
+	if entry.OriginalTopic == "" {
+		entry.OriginalTopic = r.cfg.Topic
+	}
+	entry.FailureReason, _ = headerValue(msg, headerFailureReason)
+	entry.ErrorClass, _ = headerValue(msg, headerErrorClass)
+
+	if r.cfg.MaxReplays > 0 && entry.ReplayCount >= r.cfg.MaxReplays {
+		entry.Status = ReplayStatusLooping
+		return entry
+	}
+	if err := validateMessage(msg); err != nil {
+		entry.Status = ReplayStatusInvalid
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Status = ReplayStatusValid
+	return entry
+}
+
+// validateMessage выполняет проверки, которые consumer делает до записи в базу:
+// разбор JSON и валидацию заказа. Такие ошибки постоянны, и сообщение вернется в DLQ.
+func validateMessage(msg kafka.Message) error {
+	var order domain.Order
+	if err := json.Unmarshal(msg.Value, &order); err != nil {
+		return fmt.Errorf("unmarshal order: %w", err)
+	}
+	if result := order.Validate(); result.HasErrors() {
+		return fmt.Errorf("order validation failed: %w", result.GetFirstError())
+	}
+	return nil
+}
+
+// replay публикует прошедшее проверку сообщение при ReplayConfig.Replay. Попадание,
+// уже разобранное прошлым прогоном или через административный API, не публикуется.
+// Отметка ставится до публикации и снимается при ошибке, поэтому параллельные
+// прогоны не опубликуют сообщение дважды.
+func (r *Replayer) replay(ctx context.Context, msg kafka.Message, entry ReplayedMessage) (string, error) {
+	dlq := dlqEntry(msg, entry.OriginalTopic)
+	if !r.cfg.Replay {
+		if r.ledger == nil {
+			return ReplayStatusValid, nil
+		}
+		replayed, err := r.ledger.Replayed(ctx, dlq)
+		if err != nil {
+			return "", err
+		}
+		if replayed {
+			return ReplayStatusHandled, nil
+		}
+		return ReplayStatusValid, nil
+	}
+
+	claimed, err := r.ledger.ClaimReplay(ctx, dlq)
+	if err != nil {
+		return "", err
+	}
+	if !claimed {
+		return ReplayStatusHandled, nil
+	}
+	if err := r.republish(ctx, msg, entry); err != nil {
+		if releaseErr := r.ledger.ReleaseReplay(context.WithoutCancel(ctx), dlq); releaseErr != nil {
+			r.logger.Error("failed to release dlq message after failed replay",
+				slog.Int("partition", msg.Partition),
+				slog.Int64("offset", msg.Offset),
+				slog.Any("error", releaseErr))
+		}
+		return "", err
+	}
+	return ReplayStatusReplayed, nil
+}
+
+// republish публикует сообщение в исходный топик с увеличенным счетчиком переигрываний
+func (r *Replayer) republish(ctx context.Context, msg kafka.Message, entry ReplayedMessage) error {
+	if entry.OriginalTopic == "" {
+		return fmt.Errorf("message %d/%d has no original topic", msg.Partition, msg.Offset)
+	}
+
+	replayMsg := replayMessage(entry.OriginalTopic, msg.Key, msg.Value, entry.ReplayCount,
+		fmt.Sprintf("%s/%d/%d", r.dlqTopic, msg.Partition, msg.Offset))
+	if err := r.producer.WriteMessages(ctx, replayMsg); err != nil {
+		return fmt.Errorf("failed to republish message %d/%d: %w", msg.Partition, msg.Offset, err)
+	}
+
+	r.logger.Info("dlq message replayed",
+		slog.Int("partition", msg.Partition),
+		slog.Int64("offset", msg.Offset),
+		slog.String("key", entry.Key),
+		slog.String("topic", entry.OriginalTopic),
+		slog.Int("replay_count", entry.ReplayCount+1))
+	return nil
+}
+
+// replayMessage сообщение для публикации в исходный топик с увеличенным счетчиком переигрываний.
+// from - сообщение DLQ в виде "<dlq topic>/<partition>/<offset>".
+func replayMessage(topic string, key, value []byte, replays int, from string) kafka.Message {
+	return kafka.Message{
+		Topic: topic,
+		Key:   key,
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: headerReplayCount, Value: []byte(strconv.Itoa(replays + 1))},
+			{Key: headerReplayedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+			{Key: headerReplayedOf, Value: []byte(from)},
+		},
+	}
+}
+
+// replayCount возвращает число переигрываний сообщения
+func replayCount(msg kafka.Message) int {
+	value, _ := headerValue(msg, headerReplayCount)
+	return parseReplayCount(value)
+}
+
+// parseReplayCount разбирает значение заголовка x-replay-count, 0 при отсутствии
+func parseReplayCount(value string) int {
+	count, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// failedAt возвращает время попадания сообщения в DLQ
+func failedAt(msg kafka.Message) time.Time {
+	if value, ok := headerValue(msg, headerFailedAt); ok {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return msg.Time
+}
+
+// partitionSource читает все партиции топика от начала до последнего offset'а на момент старта,
+// без группы: прогон не сдвигает offset'ы и не видит сообщения, вернувшиеся в DLQ во время прогона
+type partitionSource struct {
+	brokers []string
+	topic   string
+}
+
+func (s *partitionSource) Messages(ctx context.Context, fn func(msg kafka.Message) error) error {
+	conn, err := kafka.DialContext(ctx, "tcp", s.brokers[0])
+	if err != nil {
+		return fmt.Errorf("kafka dial error: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(s.topic)
+	_ = conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read partitions of %s: %w", s.topic, err)
+	}
+
+	for _, partition := range partitions {
+		if err := s.readPartition(ctx, partition.ID, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *partitionSource) readPartition(ctx context.Context, partition int, fn func(msg kafka.Message) error) error {
+	leader, err := kafka.DialLeader(ctx, "tcp", s.brokers[0], s.topic, partition)
+	if err != nil {
+		return fmt.Errorf("failed to dial leader of partition %d: %w", partition, err)
+	}
+	first, last, err := leader.ReadOffsets()
+	_ = leader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read offsets of partition %d: %w", partition, err)
+	}
+	if first >= last {
+		return nil
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   s.brokers,
+		Topic:     s.topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6, // 10MB
+		MaxWait:   1 * time.Second,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(first); err != nil {
+		return fmt.Errorf("failed to seek partition %d: %w", partition, err)
+	}
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read partition %d: %w", partition, err)
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+		if msg.Offset+1 >= last {
+			return nil
+		}
+	}
+}
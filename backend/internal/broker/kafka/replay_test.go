@@ -0,0 +1,338 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceSource источник DLQ из готового списка сообщений
+type sliceSource []kafka.Message
+
+func (s sliceSource) Messages(_ context.Context, fn func(msg kafka.Message) error) error {
+	for _, msg := range s {
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dlqMessage сообщение DLQ с заголовками consumer'а
+func dlqMessage(offset int64, key string, value []byte, reason string, failedAt time.Time, replays string) kafka.Message {
+	headers := []kafka.Header{
+		{Key: headerOriginalTopic, Value: []byte(testTopic)},
+		{Key: headerFailureReason, Value: []byte(reason)},
+		{Key: headerFailedAt, Value: []byte(failedAt.UTC().Format(time.RFC3339))},
+	}
+	if replays != "" {
+		headers = append(headers, kafka.Header{Key: headerReplayCount, Value: []byte(replays)})
+	}
+	return kafka.Message{Topic: dlqTopic, Offset: offset, Key: []byte(key), Value: value, Headers: headers}
+}
+
+// memoryLedger состояние разбора DLQ в памяти, как dlq_messages:
+// одна запись на ключ и payload с последним попаданием в DLQ
+type memoryLedger struct {
+	mu      sync.Mutex
+	entries map[string]*domain.DLQEntry
+}
+
+func newMemoryLedger() *memoryLedger {
+	return &memoryLedger{entries: make(map[string]*domain.DLQEntry)}
+}
+
+func (l *memoryLedger) Replayed(_ context.Context, entry *domain.DLQEntry) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stored, ok := l.entries[entry.Key+string(entry.Payload)]
+	if !ok || entry.NewerThan(stored) {
+		return false, nil
+	}
+	return stored.Status != domain.DLQStatusOpen || !samePosition(stored, entry), nil
+}
+
+func (l *memoryLedger) ClaimReplay(_ context.Context, entry *domain.DLQEntry) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := entry.Key + string(entry.Payload)
+	stored, ok := l.entries[key]
+	if !ok || entry.NewerThan(stored) {
+		claimed := *entry
+		claimed.Status = domain.DLQStatusRequeued
+		l.entries[key] = &claimed
+		return true, nil
+	}
+	if stored.Status != domain.DLQStatusOpen || !samePosition(stored, entry) {
+		return false, nil
+	}
+	stored.Status = domain.DLQStatusRequeued
+	return true, nil
+}
+
+func (l *memoryLedger) ReleaseReplay(_ context.Context, entry *domain.DLQEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if stored, ok := l.entries[entry.Key+string(entry.Payload)]; ok && samePosition(stored, entry) {
+		stored.Status = domain.DLQStatusOpen
+	}
+	return nil
+}
+
+// record сохраняет новое попадание в DLQ, как DLQSink
+func (l *memoryLedger) record(msg kafka.Message) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := dlqEntry(msg, testTopic)
+	if stored, ok := l.entries[entry.Key+string(entry.Payload)]; ok && !entry.NewerThan(stored) {
+		return
+	}
+	entry.Status = domain.DLQStatusOpen
+	l.entries[entry.Key+string(entry.Payload)] = entry
+}
+
+func samePosition(a, b *domain.DLQEntry) bool {
+	return a.DLQPartition == b.DLQPartition && a.DLQOffset == b.DLQOffset
+}
+
+func newTestReplayer(source dlqSource, writer *memoryWriter, cfg ReplayConfig) *Replayer {
+	return &Replayer{
+		source:   source,
+		producer: writer,
+		ledger:   newMemoryLedger(),
+		dlqTopic: dlqTopic,
+		cfg:      cfg,
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	valid, err := os.ReadFile("../../service/testdata/valid_order.json")
+	require.NoError(t, err)
+	var order map[string]any
+	require.NoError(t, json.Unmarshal(valid, &order))
+	order["order_uid"] = ""
+	invalid, err := json.Marshal(order)
+	require.NoError(t, err)
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	source := sliceSource{
+		dlqMessage(0, "a", valid, "failed to save order: connection refused", day.Add(time.Hour), ""),
+		dlqMessage(1, "b", invalid, "order validation failed: order_uid: required field", day.Add(2*time.Hour), ""),
+		dlqMessage(2, "c", valid, "failed to save order: connection refused", day.Add(25*time.Hour), ""),
+		dlqMessage(3, "d", valid, "failed to save order: connection refused", day.Add(3*time.Hour), "3"),
+		dlqMessage(4, "e", []byte("{not json"), "unmarshal order: invalid character", day.Add(4*time.Hour), ""),
+	}
+
+	t.Run("dry run reports without publishing", func(t *testing.T) {
+		writer := newMemoryWriter(testTopic)
+		report, err := newTestReplayer(source, writer, ReplayConfig{MaxReplays: 3}).Run(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, 5, report.Scanned)
+		assert.Equal(t, 5, report.Matched)
+		assert.Equal(t, 2, report.Valid)
+		assert.Equal(t, 2, report.Invalid)
+		assert.Equal(t, 1, report.Looping)
+		assert.Zero(t, report.Replayed)
+		assert.Empty(t, writer.messages(testTopic))
+
+		statuses := make(map[string]string)
+		for _, msg := range report.Messages {
+			statuses[msg.Key] = msg.Status
+		}
+		assert.Equal(t, map[string]string{
+			"a": ReplayStatusValid,
+			"b": ReplayStatusInvalid,
+			"c": ReplayStatusValid,
+			"d": ReplayStatusLooping,
+			"e": ReplayStatusInvalid,
+		}, statuses)
+		assert.Equal(t, testTopic, report.Messages[0].OriginalTopic)
+		assert.Contains(t, report.Messages[1].Error, "order_uid")
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			filter ReplayFilter
+			keys   []string
+		}{
+			{"reason", ReplayFilter{Reason: "validation"}, []string{"b"}},
+			{"key", ReplayFilter{Key: "c"}, []string{"c"}},
+			{"time window", ReplayFilter{From: day.Add(2 * time.Hour), To: day.Add(4 * time.Hour)}, []string{"b", "d"}},
+			{"combined", ReplayFilter{Reason: "connection refused", To: day.Add(24 * time.Hour)}, []string{"a", "d"}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				report, err := newTestReplayer(source, newMemoryWriter(testTopic), ReplayConfig{Filter: tc.filter}).Run(context.Background())
+				require.NoError(t, err)
+
+				var keys []string
+				for _, msg := range report.Messages {
+					keys = append(keys, msg.Key)
+				}
+				assert.Equal(t, tc.keys, keys)
+			})
+		}
+	})
+
+	t.Run("replay republishes valid messages", func(t *testing.T) {
+		writer := newMemoryWriter(testTopic)
+		report, err := newTestReplayer(source, writer, ReplayConfig{Replay: true, MaxReplays: 3}).Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Replayed)
+
+		published := writer.messages(testTopic)
+		require.Len(t, published, 2)
+		assert.Equal(t, "a", string(published[0].Key))
+		assert.Equal(t, valid, published[0].Value)
+
+		count, _ := headerValue(published[0], headerReplayCount)
+		assert.Equal(t, "1", count)
+		from, _ := headerValue(published[0], headerReplayedOf)
+		assert.Equal(t, dlqTopic+"/0/0", from)
+		_, retried := headerValue(published[0], headerFailureReason)
+		assert.False(t, retried, "failure headers are not republished")
+	})
+
+	t.Run("second run does not republish", func(t *testing.T) {
+		writer := newMemoryWriter(testTopic)
+		replayer := newTestReplayer(source, writer, ReplayConfig{Replay: true, MaxReplays: 3})
+
+		report, err := replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Replayed)
+
+		report, err = replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, report.Replayed)
+		assert.Equal(t, 2, report.Handled)
+		assert.Len(t, writer.messages(testTopic), 2)
+
+		// Dry-run показывает, что сообщения уже переиграны
+		replayer.cfg.Replay = false
+		report, err = replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Handled)
+		assert.Zero(t, report.Valid)
+	})
+
+	t.Run("message failed again is replayed once more", func(t *testing.T) {
+		writer := newMemoryWriter(testTopic)
+		ledger := newMemoryLedger()
+		replayer := newTestReplayer(source[:1], writer, ReplayConfig{Replay: true, MaxReplays: 3})
+		replayer.ledger = ledger
+
+		_, err := replayer.Run(context.Background())
+		require.NoError(t, err)
+
+		// Переигранное сообщение снова упало и вернулось в DLQ с новым offset'ом
+		again := dlqMessage(10, "a", valid, "failed to save order: connection refused", day.Add(48*time.Hour), "1")
+		ledger.record(again)
+		replayer.source = append(sliceSource{}, source[0], again)
+
+		report, err := replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Replayed)
+		assert.Equal(t, 1, report.Handled)
+		assert.Len(t, writer.messages(testTopic), 2)
+	})
+
+	t.Run("message failed again without sink is replayed once more", func(t *testing.T) {
+		writer := newMemoryWriter(testTopic)
+		ledger := newMemoryLedger()
+		replayer := newTestReplayer(source[:1], writer, ReplayConfig{Replay: true, MaxReplays: 3})
+		replayer.ledger = ledger
+
+		_, err := replayer.Run(context.Background())
+		require.NoError(t, err)
+
+		// Новое попадание не сохранено в ledger: sink выключен
+		again := dlqMessage(10, "a", valid, "failed to save order: connection refused", day.Add(48*time.Hour), "1")
+		replayer.source = append(sliceSource{}, source[0], again)
+
+		replayer.cfg.Replay = false
+		report, err := replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Valid)
+		assert.Equal(t, 1, report.Handled)
+
+		replayer.cfg.Replay = true
+		report, err = replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Replayed)
+		assert.Equal(t, 1, report.Handled)
+
+		report, err = replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Handled)
+		assert.Len(t, writer.messages(testTopic), 2)
+	})
+
+	t.Run("dry run without ledger", func(t *testing.T) {
+		writer := newMemoryWriter(testTopic)
+		replayer := newTestReplayer(source[:1], writer, ReplayConfig{MaxReplays: 3})
+		replayer.ledger = nil
+
+		report, err := replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Valid)
+
+		replayer.cfg.Replay = true
+		_, err = replayer.Run(context.Background())
+		assert.ErrorIs(t, err, errNoLedger)
+		assert.Empty(t, writer.messages(testTopic))
+	})
+
+	t.Run("failed publish releases claim", func(t *testing.T) {
+		writer := newMemoryWriter(testTopic)
+		writer.failures = 1
+		replayer := newTestReplayer(source[:1], writer, ReplayConfig{Replay: true})
+
+		_, err := replayer.Run(context.Background())
+		require.Error(t, err)
+
+		report, err := replayer.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Replayed)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		writer := newMemoryWriter(testTopic)
+		report, err := newTestReplayer(source, writer, ReplayConfig{Replay: true, Limit: 1}).Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Matched)
+		assert.Len(t, writer.messages(testTopic), 1)
+	})
+}
+
+// TestConsumer_ReplayCountSurvivesFailure проверяет, что счетчик переигрываний
+// возвращается в DLQ вместе с сообщением, и петля может быть остановлена.
+func TestConsumer_ReplayCountSurvivesFailure(t *testing.T) {
+	writer := newMemoryWriter(dlqTopic)
+	consumer := newMemoryConsumer(&memoryLog{}, &MockOrderService{}, 1)
+	consumer.producer = writer
+	consumer.dlqTopic = dlqTopic
+
+	msg := kafka.Message{
+		Key:     []byte("order-1"),
+		Value:   []byte("{not json"),
+		Headers: []kafka.Header{{Key: headerReplayCount, Value: []byte("2")}},
+	}
+	require.NoError(t, consumer.handleFailedMessage(context.Background(), msg, consumer.processMessage(context.Background(), msg)))
+
+	dlq := writer.messages(dlqTopic)
+	require.Len(t, dlq, 1)
+	assert.Equal(t, 2, replayCount(dlq[0]))
+}
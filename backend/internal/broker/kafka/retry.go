@@ -0,0 +1,170 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/broker"
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовки сообщений топиков повторов и DLQ
+const (
+	headerOriginalTopic     = broker.HeaderOriginalTopic
+	headerOriginalOffset    = "x-original-offset"
+	headerOriginalPartition = "x-original-partition"
+	headerFailureReason     = broker.HeaderFailureReason
+	headerErrorClass        = broker.HeaderErrorClass
+	headerFailedAt          = broker.HeaderFailedAt
+	headerRetryTier         = "x-retry-tier"
+	headerRetryDueAt        = "x-retry-due-at"
+)
+
+// retryTier уровень повторов: топик и задержка, после которой обрабатываются его сообщения
+type retryTier struct {
+	topic string
+	delay time.Duration
+}
+
+// newRetryTiers строит уровни повторов с топиками вида "<topic>-retry-10s", "<topic>-retry-1m"
+func newRetryTiers(topic string, delays []time.Duration) []retryTier {
+	tiers := make([]retryTier, 0, len(delays))
+	for _, delay := range delays {
+		tiers = append(tiers, retryTier{
+			topic: topic + "-retry-" + formatDelay(delay),
+			delay: delay,
+		})
+	}
+	return tiers
+}
+
+// formatDelay форматирует задержку для имени топика: 10s, 1m, 2h
+func formatDelay(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	default:
+		return fmt.Sprintf("%dms", d/time.Millisecond)
+	}
+}
+
+// headerValue возвращает значение заголовка сообщения
+func headerValue(msg kafka.Message, key string) (string, bool) {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+// originHeaders возвращает заголовки, указывающие на исходное сообщение основного топика.
+// Сообщения уровней повторов переносят их дальше, чтобы запись в DLQ ссылалась на исходный offset.
+func (c *Consumer) originHeaders(msg kafka.Message) []kafka.Header {
+	if c.tier > 0 {
+		var headers []kafka.Header
+		for _, h := range msg.Headers {
+			switch h.Key {
+			case headerOriginalTopic, headerOriginalOffset, headerOriginalPartition:
+				headers = append(headers, h)
+			}
+		}
+		if len(headers) > 0 {
+			return headers
+		}
+	}
+	return []kafka.Header{
+		{Key: headerOriginalTopic, Value: []byte(c.topic)},
+		{Key: headerOriginalOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		{Key: headerOriginalPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+	}
+}
+
+// failureHeaders возвращает заголовки сообщения, не прошедшего обработку.
+// Счетчик переигрываний из DLQ переносится, чтобы Replayer мог остановить петлю.
+func (c *Consumer) failureHeaders(msg kafka.Message, processingErr error) []kafka.Header {
+	headers := c.originHeaders(msg)
+	for _, h := range broker.FailureHeaders(processingErr) {
+		headers = append(headers, kafka.Header{Key: h.Key, Value: []byte(h.Value)})
+	}
+	if count, ok := headerValue(msg, headerReplayCount); ok {
+		headers = append(headers, kafka.Header{Key: headerReplayCount, Value: []byte(count)})
+	}
+	return headers
+}
+
+// sendToRetry отправляет сообщение в топик следующего уровня повторов
+func (c *Consumer) sendToRetry(msg kafka.Message, processingErr error) error {
+	next := c.retryTiers[c.tier]
+	dueAt := time.Now().Add(next.delay)
+
+	retryMsg := kafka.Message{
+		Topic: next.topic,
+		Key:   msg.Key,
+		Value: msg.Value,
+		Headers: append(c.failureHeaders(msg, processingErr),
+			kafka.Header{Key: headerRetryTier, Value: []byte(strconv.Itoa(c.tier + 1))},
+			kafka.Header{Key: headerRetryDueAt, Value: []byte(dueAt.UTC().Format(time.RFC3339Nano))},
+		),
+	}
+
+	// Как и для DLQ, отправка не зависит от контекста consumer'а
+	retryCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.producer.WriteMessages(retryCtx, retryMsg); err != nil {
+		c.logger.Error("failed to write message to retry topic",
+			slog.String("retry_topic", next.topic),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("failed to write to retry topic: %w", err)
+	}
+
+	c.logger.Info("message scheduled for retry",
+		slog.Int64("offset", msg.Offset),
+		slog.String("retry_topic", next.topic),
+		slog.Time("due_at", dueAt))
+	return nil
+}
+
+// waitUntilDue ждет времени обработки сообщения уровня повторов.
+// false, если consumer остановлен раньше.
+func (c *Consumer) waitUntilDue(ctx context.Context, msg kafka.Message) bool {
+	if c.tier == 0 {
+		return true
+	}
+	value, ok := headerValue(msg, headerRetryDueAt)
+	if !ok {
+		return true
+	}
+	dueAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		c.logger.Warn("invalid retry due time, processing message now",
+			slog.Int64("offset", msg.Offset),
+			slog.String("due_at", value))
+		return true
+	}
+
+	wait := time.Until(dueAt)
+	if wait <= 0 {
+		return true
+	}
+	c.logger.Debug("waiting until retry is due",
+		slog.Int64("offset", msg.Offset),
+		slog.Duration("wait", wait))
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
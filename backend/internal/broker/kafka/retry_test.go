@@ -0,0 +1,228 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/broker"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryWriter пишет сообщения в memoryLog топика; первые failures записей завершаются ошибкой
+type memoryWriter struct {
+	mu       sync.Mutex
+	logs     map[string]*memoryLog
+	failures int
+}
+
+func newMemoryWriter(topics ...string) *memoryWriter {
+	w := &memoryWriter{logs: make(map[string]*memoryLog)}
+	for _, topic := range topics {
+		w.logs[topic] = &memoryLog{}
+	}
+	return w
+}
+
+func (w *memoryWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("leader not available")
+	}
+	for _, msg := range msgs {
+		log, ok := w.logs[msg.Topic]
+		if !ok {
+			return errors.New("unknown topic " + msg.Topic)
+		}
+		log.mu.Lock()
+		msg.Offset = int64(len(log.messages))
+		log.messages = append(log.messages, msg)
+		log.mu.Unlock()
+	}
+	return nil
+}
+
+func (w *memoryWriter) Close() error { return nil }
+
+func (w *memoryWriter) messages(topic string) []kafka.Message {
+	log := w.logs[topic]
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return append([]kafka.Message(nil), log.messages...)
+}
+
+func TestNewRetryTiers(t *testing.T) {
+	tiers := newRetryTiers("orders", []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute, 2 * time.Hour, 90 * time.Second})
+
+	var topics []string
+	for _, tier := range tiers {
+		topics = append(topics, tier.topic)
+	}
+	assert.Equal(t, []string{"orders-retry-10s", "orders-retry-1m", "orders-retry-10m", "orders-retry-2h", "orders-retry-90s"}, topics)
+	assert.Equal(t, time.Minute, tiers[1].delay)
+}
+
+// newTieredConsumer собирает основной consumer и consumer'ы уровней поверх memoryWriter
+func newTieredConsumer(writer *memoryWriter, main *memoryLog, tiers []retryTier, orderService *MockOrderService) *Consumer {
+	// Между попытками сообщение ждет в топике уровня, а не в воркере
+	processor := broker.NewProcessor(orderService, broker.RetryPolicy{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	consumer := newMemoryConsumer(main, orderService, 2)
+	consumer.processor = processor
+	consumer.producer = writer
+	consumer.dlqTopic = dlqTopic
+	consumer.retryTiers = tiers
+	for i, tier := range tiers {
+		retry := newMemoryConsumer(writer.logs[tier.topic], orderService, 2)
+		retry.topic = tier.topic
+		retry.processor = processor
+		retry.producer = writer
+		retry.dlqTopic = dlqTopic
+		retry.tier = i + 1
+		retry.retryTiers = tiers
+		consumer.retryConsumers = append(consumer.retryConsumers, retry)
+	}
+	return consumer
+}
+
+// TestConsumer_RetryTiers проверяет прохождение сообщения по уровням повторов до DLQ.
+func TestConsumer_RetryTiers(t *testing.T) {
+	tiers := newRetryTiers(testTopic, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond})
+
+	value, err := json.Marshal(domain.Order{OrderUID: "order-retry"})
+	require.NoError(t, err)
+
+	t.Run("retryable error passes every tier", func(t *testing.T) {
+		writer := newMemoryWriter(tiers[0].topic, tiers[1].topic, dlqTopic)
+		main := &memoryLog{messages: []kafka.Message{{Key: []byte("order-retry"), Value: value, Offset: 7, Partition: 2}}}
+
+		var (
+			mu    sync.Mutex
+			calls []time.Time
+		)
+		orderService := &MockOrderService{}
+		orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, time.Now())
+		}).Return(errors.New("database unavailable"))
+
+		consumer := newTieredConsumer(writer, main, tiers, orderService)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		require.NoError(t, consumer.Start(ctx))
+
+		assert.Eventually(t, func() bool { return len(writer.messages(dlqTopic)) == 1 }, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, consumer.Stop(context.Background()))
+
+		// Одна попытка на основном топике и по одной на каждом уровне
+		mu.Lock()
+		require.Len(t, calls, 3)
+		assert.GreaterOrEqual(t, calls[1].Sub(calls[0]), tiers[0].delay)
+		assert.GreaterOrEqual(t, calls[2].Sub(calls[1]), tiers[1].delay)
+		mu.Unlock()
+
+		retried := writer.messages(tiers[0].topic)
+		require.Len(t, retried, 1)
+		tier, _ := headerValue(retried[0], headerRetryTier)
+		assert.Equal(t, "1", tier)
+		_, ok := headerValue(retried[0], headerRetryDueAt)
+		assert.True(t, ok)
+
+		dlq := writer.messages(dlqTopic)[0]
+		assert.Equal(t, value, dlq.Value)
+		for key, want := range map[string]string{
+			headerOriginalTopic:     testTopic,
+			headerOriginalOffset:    "7",
+			headerOriginalPartition: "2",
+			headerErrorClass:        string(domain.ErrorClassRetryable),
+			headerRetryTier:         "2",
+		} {
+			got, _ := headerValue(dlq, key)
+			assert.Equal(t, want, got, key)
+		}
+
+		// Все три топика закоммичены
+		assert.Equal(t, int64(8), main.Committed())
+		assert.Equal(t, int64(1), writer.logs[tiers[0].topic].Committed())
+		assert.Equal(t, int64(1), writer.logs[tiers[1].topic].Committed())
+	})
+
+	t.Run("permanent error skips tiers", func(t *testing.T) {
+		writer := newMemoryWriter(tiers[0].topic, tiers[1].topic, dlqTopic)
+		main := &memoryLog{messages: []kafka.Message{{Key: []byte("order-retry"), Value: value}}}
+
+		orderService := &MockOrderService{}
+		orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).
+			Return(domain.Permanent(errors.New("order validation failed"))).Once()
+
+		consumer := newTieredConsumer(writer, main, tiers, orderService)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		require.NoError(t, consumer.Start(ctx))
+
+		assert.Eventually(t, func() bool { return len(writer.messages(dlqTopic)) == 1 }, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, consumer.Stop(context.Background()))
+
+		assert.Empty(t, writer.messages(tiers[0].topic))
+		class, _ := headerValue(writer.messages(dlqTopic)[0], headerErrorClass)
+		assert.Equal(t, string(domain.ErrorClassPermanent), class)
+		orderService.AssertExpectations(t)
+	})
+
+	t.Run("recovers on retry tier", func(t *testing.T) {
+		writer := newMemoryWriter(tiers[0].topic, tiers[1].topic, dlqTopic)
+		main := &memoryLog{messages: []kafka.Message{{Key: []byte("order-retry"), Value: value}}}
+
+		orderService := &MockOrderService{}
+		orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Return(errors.New("timeout")).Once()
+		orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Return(nil).Once()
+
+		consumer := newTieredConsumer(writer, main, tiers, orderService)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		require.NoError(t, consumer.Start(ctx))
+
+		assert.Eventually(t, func() bool { return writer.logs[tiers[0].topic].Committed() == 1 }, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, consumer.Stop(context.Background()))
+
+		assert.Empty(t, writer.messages(tiers[1].topic))
+		assert.Empty(t, writer.messages(dlqTopic))
+		orderService.AssertExpectations(t)
+	})
+}
+
+// TestConsumer_HandOffRetried проверяет, что сбой передачи в DLQ повторяется в воркере,
+// а не оставляет сообщение незавершенным до перезапуска.
+func TestConsumer_HandOffRetried(t *testing.T) {
+	writer := newMemoryWriter(dlqTopic)
+	writer.failures = 3
+	log := &memoryLog{messages: []kafka.Message{
+		{Key: []byte("broken"), Value: []byte("{not json"), Offset: 0},
+	}}
+
+	consumer := newMemoryConsumer(log, &MockOrderService{}, 1)
+	consumer.producer = writer
+	consumer.dlqTopic = dlqTopic
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, consumer.Start(ctx))
+
+	assert.Eventually(t, func() bool { return log.Committed() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, consumer.Stop(context.Background()))
+
+	assert.Len(t, writer.messages(dlqTopic), 1)
+	writer.mu.Lock()
+	assert.Zero(t, writer.failures)
+	writer.mu.Unlock()
+}
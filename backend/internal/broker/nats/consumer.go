@@ -0,0 +1,375 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/broker"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Заголовки сообщений DLQ, специфичные для JetStream
+const (
+	headerOriginalSequence = "x-original-sequence" // номер сообщения в потоке
+	headerDeliveries       = "x-deliveries"        // число доставок до отправки в DLQ
+)
+
+// messageSource durable consumer JetStream (jetstream.Consumer)
+type messageSource interface {
+	Messages(opts ...jetstream.PullMessagesOpt) (jetstream.MessagesContext, error)
+}
+
+// messagePublisher публикация в DLQ (jetstream.JetStream)
+type messagePublisher interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// Consumer (NATS JetStream) для обработки заказов. Итоги обработки переводятся в ack/nak/term:
+// успех - ack, временная ошибка - nak с задержкой из RetryDelays, постоянная ошибка или
+// исчерпанные доставки - публикация в DLQ и term.
+type Consumer struct {
+	conn        *nats.Conn
+	source      messageSource
+	messages    jetstream.MessagesContext
+	publisher   messagePublisher
+	processor   *broker.Processor
+	logger      *slog.Logger
+	wg          *sync.WaitGroup
+	cancel      context.CancelFunc
+	workerChans []chan jetstream.Msg // очередь каждого воркера, см. broker.WorkerIndex
+
+	// Конфигурация
+	stream      string
+	durable     string
+	dlqSubject  string
+	retryDelays []time.Duration
+	concurrency int
+}
+
+// Config для JetStream consumer
+type Config struct {
+	URL         string
+	Stream      string // поток заказов, создается или обновляется при запуске
+	Subject     string // subject'ы потока, например "orders.>"
+	Durable     string // имя durable consumer'а, общее для всех реплик
+	DLQStream   string
+	DLQSubject  string          // пусто - без DLQ
+	RetryDelays []time.Duration // задержки повторных доставок, всего доставок len(RetryDelays)+1
+	AckWait     time.Duration
+	Concurrency int
+}
+
+// NewConsumer подключается к NATS и создает потоки заказов и DLQ и durable consumer
+func NewConsumer(ctx context.Context, cfg Config, orderService service.OrderServicer, logger *slog.Logger) (*Consumer, error) {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = runtime.NumCPU()
+	}
+
+	conn, err := nats.Connect(cfg.URL,
+		nats.Name(cfg.Durable),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("nats disconnected", slog.String("error", err.Error()))
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info("nats reconnected", slog.String("url", conn.ConnectedUrl()))
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("nats connect error: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to init jetstream: %w", err)
+	}
+
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: []string{cfg.Subject},
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create stream %s: %w", cfg.Stream, err)
+	}
+	if cfg.DLQSubject != "" {
+		if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     cfg.DLQStream,
+			Subjects: []string{cfg.DLQSubject},
+		}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to create dlq stream %s: %w", cfg.DLQStream, err)
+		}
+	}
+
+	source, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       cfg.Durable,
+		FilterSubject: cfg.Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       cfg.AckWait,
+		// Число доставок ограничивает consumer: после последней сообщение уходит в DLQ,
+		// а не пропадает на сервере
+		MaxDeliver: -1,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create consumer %s: %w", cfg.Durable, err)
+	}
+
+	consumer := newConsumer(cfg, source, js, orderService, logger)
+	consumer.conn = conn
+
+	logger.Info("nats consumer created successfully",
+		slog.String("stream", cfg.Stream),
+		slog.String("subject", cfg.Subject),
+		slog.String("durable", cfg.Durable))
+
+	return consumer, nil
+}
+
+func newConsumer(cfg Config, source messageSource, publisher messagePublisher, orderService service.OrderServicer, logger *slog.Logger) *Consumer {
+	consumer := &Consumer{
+		source:    source,
+		publisher: publisher,
+		// Между попытками сообщение ждет повторной доставки на сервере, а не в воркере
+		processor:   broker.NewProcessor(orderService, broker.RetryPolicy{}, logger),
+		logger:      logger,
+		wg:          &sync.WaitGroup{},
+		workerChans: make([]chan jetstream.Msg, cfg.Concurrency),
+		stream:      cfg.Stream,
+		durable:     cfg.Durable,
+		dlqSubject:  cfg.DLQSubject,
+		retryDelays: cfg.RetryDelays,
+		concurrency: cfg.Concurrency,
+	}
+	for i := range consumer.workerChans {
+		consumer.workerChans[i] = make(chan jetstream.Msg, workerQueueSize)
+	}
+	return consumer
+}
+
+// workerQueueSize емкость очереди одного воркера
+const workerQueueSize = 16
+
+// Start запускает чтение сообщений durable consumer'а
+func (c *Consumer) Start(ctx context.Context) error {
+	c.logger.Info("starting nats consumer",
+		slog.String("stream", c.stream),
+		slog.String("durable", c.durable),
+		slog.String("dlq_subject", c.dlqSubject),
+		slog.Any("retry_delays", c.retryDelays),
+		slog.Int("concurrency", c.concurrency))
+
+	messages, err := c.source.Messages()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", c.durable, err)
+	}
+	c.messages = messages
+
+	var consumerCtx context.Context
+	consumerCtx, c.cancel = context.WithCancel(ctx)
+
+	for i := 0; i < c.concurrency; i++ {
+		c.wg.Add(1)
+		go c.worker(consumerCtx, i)
+	}
+
+	c.wg.Add(1)
+	go c.consumeMessages(consumerCtx)
+
+	c.logger.Info("nats consumer started successfully")
+	return nil
+}
+
+// Stop останавливает чтение и ждет завершения обработки. Неподтвержденные сообщения
+// будут доставлены повторно по истечении AckWait.
+func (c *Consumer) Stop(ctx context.Context) error {
+	c.logger.Info("stopping nats consumer", slog.String("durable", c.durable))
+
+	c.messages.Stop()
+	c.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		c.logger.Info("nats consumer stopped gracefully")
+	case <-ctx.Done():
+		c.logger.Warn("nats consumer stop timeout")
+	}
+
+	for _, ch := range c.workerChans {
+		close(ch)
+	}
+
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	return nil
+}
+
+// Health проверяет подключение к NATS
+func (c *Consumer) Health(ctx context.Context) error {
+	if status := c.conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats connection is %s", status)
+	}
+	return nil
+}
+
+// consumeMessages основной цикл чтения сообщений
+func (c *Consumer) consumeMessages(ctx context.Context) {
+	defer c.wg.Done()
+
+	for {
+		msg, err := c.messages.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) || ctx.Err() != nil {
+				c.logger.Debug("message iterator closed")
+				return
+			}
+			c.logger.Error("error fetching message", slog.String("error", err.Error()))
+			continue
+		}
+
+		// Сообщения одного subject'а (orders.<order_uid>) обрабатываются одним воркером по порядку
+		workerID := broker.WorkerIndex([]byte(msg.Subject()), 0, len(c.workerChans))
+		select {
+		case <-ctx.Done():
+			c.logger.Info("context cancelled, not sending message to worker", slog.String("subject", msg.Subject()))
+			return
+		case c.workerChans[workerID] <- msg:
+		}
+	}
+}
+
+func (c *Consumer) worker(ctx context.Context, id int) {
+	defer c.wg.Done()
+	c.logger.Info("starting worker", slog.Int("worker_id", id))
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("worker context cancelled, stopping", slog.Int("worker_id", id))
+			return
+		case msg, ok := <-c.workerChans[id]:
+			if !ok {
+				c.logger.Info("message channel closed, stopping worker", slog.Int("worker_id", id))
+				return
+			}
+			c.handleMessage(ctx, msg)
+		}
+	}
+}
+
+// handleMessage обрабатывает сообщение и сообщает серверу итог
+func (c *Consumer) handleMessage(ctx context.Context, msg jetstream.Msg) {
+	var (
+		deliveries uint64 = 1
+		sequence   uint64
+	)
+	if meta, err := msg.Metadata(); err == nil {
+		deliveries, sequence = meta.NumDelivered, meta.Sequence.Stream
+	}
+	logger := c.logger.With(
+		slog.String("subject", msg.Subject()),
+		slog.Uint64("sequence", sequence),
+		slog.Uint64("deliveries", deliveries))
+
+	// Сообщение могло ждать в очереди воркера: продлеваем AckWait, чтобы сервер
+	// не доставил его повторно во время обработки
+	if err := msg.InProgress(); err != nil {
+		logger.Warn("failed to extend ack wait", slog.String("error", err.Error()))
+	}
+
+	processingErr := c.processor.Process(ctx, msg.Data())
+
+	// Остановка во время обработки: сообщение сразу возвращается на сервер
+	if processingErr != nil && ctx.Err() != nil {
+		logger.Info("consumer stopped while processing message, returning it to the stream")
+		c.respond(logger, "nak", msg.Nak())
+		return
+	}
+
+	outcome := broker.Decide(processingErr, deliveries <= uint64(len(c.retryDelays)))
+	switch outcome {
+	case broker.OutcomeSuccess:
+		c.respond(logger, "ack", msg.Ack())
+	case broker.OutcomeRetry:
+		delay := c.retryDelays[deliveries-1]
+		logger.Warn("error processing message, scheduling redelivery",
+			slog.String("error", processingErr.Error()),
+			slog.Duration("delay", delay))
+		c.respond(logger, "nak", msg.NakWithDelay(delay))
+	case broker.OutcomeDLQ:
+		logger.Error("error processing message, sending it to DLQ",
+			slog.String("error", processingErr.Error()),
+			slog.String("error_class", string(domain.ClassifyError(processingErr))))
+		if err := c.sendToDLQ(msg, processingErr, deliveries, sequence); err != nil {
+			// Не завершаем доставку: сервер повторит сообщение, и оно снова попадет сюда
+			logger.Error("failed to send message to DLQ, message will be redelivered", slog.String("error", err.Error()))
+			c.respond(logger, "nak", msg.NakWithDelay(c.dlqRetryDelay()))
+			return
+		}
+		c.respond(logger, "term", msg.Term())
+	}
+}
+
+// respond логирует ошибку подтверждения: сообщение будет доставлено повторно после AckWait
+func (c *Consumer) respond(logger *slog.Logger, action string, err error) {
+	if err != nil {
+		logger.Error("failed to respond to message", slog.String("action", action), slog.String("error", err.Error()))
+	}
+}
+
+// dlqRetryDelay задержка повторной доставки при недоступном DLQ
+func (c *Consumer) dlqRetryDelay() time.Duration {
+	if len(c.retryDelays) == 0 {
+		return 0
+	}
+	return c.retryDelays[len(c.retryDelays)-1]
+}
+
+// sendToDLQ публикует сообщение в DLQ с причиной ошибки
+func (c *Consumer) sendToDLQ(msg jetstream.Msg, processingErr error, deliveries, sequence uint64) error {
+	if c.dlqSubject == "" {
+		c.logger.Warn("DLQ subject is not configured, message will be dropped", slog.String("subject", msg.Subject()))
+		return nil
+	}
+
+	dlqMsg := nats.NewMsg(c.dlqSubject)
+	dlqMsg.Data = msg.Data()
+	dlqMsg.Header.Set(broker.HeaderOriginalTopic, msg.Subject())
+	dlqMsg.Header.Set(headerOriginalSequence, strconv.FormatUint(sequence, 10))
+	dlqMsg.Header.Set(headerDeliveries, strconv.FormatUint(deliveries, 10))
+	for _, h := range broker.FailureHeaders(processingErr) {
+		dlqMsg.Header.Set(h.Key, h.Value)
+	}
+	// Счетчик переигрываний переносится, чтобы петлю "DLQ -> поток -> DLQ" было видно
+	if count := msg.Headers().Get(broker.HeaderReplayCount); count != "" {
+		dlqMsg.Header.Set(broker.HeaderReplayCount, count)
+	}
+
+	// Отправка не зависит от контекста consumer'а, как и в Kafka consumer'е
+	dlqCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := c.publisher.PublishMsg(dlqCtx, dlqMsg); err != nil {
+		return fmt.Errorf("failed to publish to DLQ: %w", err)
+	}
+	return nil
+}
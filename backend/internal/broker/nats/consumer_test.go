@@ -0,0 +1,226 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/broker"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSubject = "orders.uid-1"
+	dlqSubject  = "orders-dlq"
+)
+
+// MockOrderService мок для метода ProcessOrderMessage интерфейса OrderServicer.
+type MockOrderService struct {
+	service.OrderServicer
+	mock.Mock
+}
+
+func (m *MockOrderService) ProcessOrderMessage(ctx context.Context, order *domain.Order) error {
+	return m.Called(ctx, order).Error(0)
+}
+
+// memoryMsg сообщение JetStream, запоминающее ответ consumer'а
+type memoryMsg struct {
+	jetstream.Msg
+
+	subject    string
+	data       []byte
+	headers    nats.Header
+	deliveries uint64
+
+	mu       sync.Mutex
+	response string
+	delay    time.Duration
+	done     chan struct{}
+}
+
+func newMemoryMsg(data string, deliveries uint64) *memoryMsg {
+	return &memoryMsg{subject: testSubject, data: []byte(data), deliveries: deliveries, done: make(chan struct{})}
+}
+
+func (m *memoryMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{NumDelivered: m.deliveries, Sequence: jetstream.SequencePair{Stream: 42}}, nil
+}
+
+func (m *memoryMsg) Data() []byte         { return m.data }
+func (m *memoryMsg) Headers() nats.Header { return m.headers }
+func (m *memoryMsg) Subject() string      { return m.subject }
+func (m *memoryMsg) InProgress() error    { return nil }
+func (m *memoryMsg) Ack() error           { return m.respond("ack", 0) }
+func (m *memoryMsg) Nak() error           { return m.respond("nak", 0) }
+func (m *memoryMsg) Term() error          { return m.respond("term", 0) }
+
+func (m *memoryMsg) NakWithDelay(delay time.Duration) error { return m.respond("nak", delay) }
+
+func (m *memoryMsg) respond(response string, delay time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.response, m.delay = response, delay
+	close(m.done)
+	return nil
+}
+
+func (m *memoryMsg) result() (string, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.response, m.delay
+}
+
+// memoryPublisher запоминает сообщения, опубликованные в DLQ
+type memoryPublisher struct {
+	mu       sync.Mutex
+	messages []*nats.Msg
+	err      error
+}
+
+func (p *memoryPublisher) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.messages = append(p.messages, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func (p *memoryPublisher) published() []*nats.Msg {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.messages
+}
+
+// memorySource отдает сообщения из канала, как durable consumer JetStream
+type memorySource struct {
+	msgs chan jetstream.Msg
+}
+
+func (s *memorySource) Messages(...jetstream.PullMessagesOpt) (jetstream.MessagesContext, error) {
+	return &memoryIterator{msgs: s.msgs, closed: make(chan struct{})}, nil
+}
+
+type memoryIterator struct {
+	msgs      chan jetstream.Msg
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (it *memoryIterator) Next(...jetstream.NextOpt) (jetstream.Msg, error) {
+	select {
+	case msg := <-it.msgs:
+		return msg, nil
+	case <-it.closed:
+		return nil, jetstream.ErrMsgIteratorClosed
+	}
+}
+
+func (it *memoryIterator) Stop()  { it.closeOnce.Do(func() { close(it.closed) }) }
+func (it *memoryIterator) Drain() { it.Stop() }
+
+func newTestConsumer(source messageSource, publisher *memoryPublisher, orderService *MockOrderService) *Consumer {
+	cfg := Config{
+		Durable:     "test-consumer",
+		DLQSubject:  dlqSubject,
+		RetryDelays: []time.Duration{10 * time.Second, time.Minute},
+		Concurrency: 2,
+	}
+	return newConsumer(cfg, source, publisher, orderService, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// TestConsumer_HandleMessage проверяет перевод итогов обработки в ack/nak/term.
+func TestConsumer_HandleMessage(t *testing.T) {
+	const order = `{"order_uid":"uid-1"}`
+	timeout := errors.New("connection refused")
+
+	tests := []struct {
+		name       string
+		data       string
+		deliveries uint64
+		processErr error
+		publishErr error
+		response   string
+		delay      time.Duration
+		dlq        bool
+	}{
+		{name: "success", data: order, deliveries: 1, response: "ack"},
+		{name: "retryable error is redelivered with delay", data: order, deliveries: 1, processErr: timeout, response: "nak", delay: 10 * time.Second},
+		{name: "delay grows with deliveries", data: order, deliveries: 2, processErr: timeout, response: "nak", delay: time.Minute},
+		{name: "last delivery goes to DLQ", data: order, deliveries: 3, processErr: timeout, response: "term", dlq: true},
+		{name: "permanent error goes to DLQ at once", data: order, deliveries: 1, processErr: domain.Permanent(errors.New("order validation failed")), response: "term", dlq: true},
+		{name: "unreadable JSON goes to DLQ at once", data: "{not json", deliveries: 1, response: "term", dlq: true},
+		{name: "DLQ failure keeps message", data: order, deliveries: 3, processErr: timeout, publishErr: errors.New("no responders"), response: "nak", delay: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderService := &MockOrderService{}
+			orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Return(tt.processErr).Maybe()
+			publisher := &memoryPublisher{err: tt.publishErr}
+			consumer := newTestConsumer(nil, publisher, orderService)
+
+			msg := newMemoryMsg(tt.data, tt.deliveries)
+			msg.headers = nats.Header{}
+			msg.headers.Set(broker.HeaderReplayCount, "1")
+			consumer.handleMessage(context.Background(), msg)
+
+			response, delay := msg.result()
+			assert.Equal(t, tt.response, response)
+			assert.Equal(t, tt.delay, delay)
+
+			if !tt.dlq {
+				assert.Empty(t, publisher.published())
+				return
+			}
+			require.Len(t, publisher.published(), 1)
+			dlqMsg := publisher.published()[0]
+			assert.Equal(t, dlqSubject, dlqMsg.Subject)
+			assert.Equal(t, tt.data, string(dlqMsg.Data))
+			assert.Equal(t, testSubject, dlqMsg.Header.Get(broker.HeaderOriginalTopic))
+			assert.Equal(t, "42", dlqMsg.Header.Get(headerOriginalSequence))
+			assert.NotEmpty(t, dlqMsg.Header.Get(broker.HeaderFailureReason))
+			assert.Equal(t, "1", dlqMsg.Header.Get(broker.HeaderReplayCount))
+		})
+	}
+}
+
+// TestConsumer_StartStop проверяет чтение сообщений воркерами и остановку consumer'а.
+func TestConsumer_StartStop(t *testing.T) {
+	source := &memorySource{msgs: make(chan jetstream.Msg)}
+	orderService := &MockOrderService{}
+	orderService.On("ProcessOrderMessage", mock.Anything, mock.Anything).Return(nil)
+	consumer := newTestConsumer(source, &memoryPublisher{}, orderService)
+
+	require.NoError(t, consumer.Start(context.Background()))
+
+	msgs := []*memoryMsg{newMemoryMsg(`{"order_uid":"uid-1"}`, 1), newMemoryMsg(`{"order_uid":"uid-2"}`, 1)}
+	msgs[1].subject = "orders.uid-2"
+	for _, msg := range msgs {
+		source.msgs <- msg
+	}
+	for _, msg := range msgs {
+		select {
+		case <-msg.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("message was not handled")
+		}
+		response, _ := msg.result()
+		assert.Equal(t, "ack", response)
+	}
+
+	require.NoError(t, consumer.Stop(context.Background()))
+	orderService.AssertNumberOfCalls(t, "ProcessOrderMessage", 2)
+}
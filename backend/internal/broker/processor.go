@@ -0,0 +1,143 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+)
+
+// RetryPolicy повторы временных ошибок внутри обработки одного сообщения
+type RetryPolicy struct {
+	MaxRetries    int
+	InitialDelay  time.Duration
+	MaxDelay      time.Duration
+	BackoffFactor float64
+}
+
+// Backoff задержка перед повтором attempt: экспоненциальная, с джиттером до 10%,
+// не больше MaxDelay
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if p.InitialDelay <= 0 || p.BackoffFactor <= 1 || p.MaxDelay <= 0 {
+		return p.InitialDelay // Fallback to simple retry delay
+	}
+	backoff := float64(p.InitialDelay) * math.Pow(p.BackoffFactor, float64(attempt-1))
+	delay := time.Duration(backoff)
+
+	// Добавляем джиттер
+	if delay > 0 {
+		jitterMax := int64(delay) / 10
+		if jitterMax > 0 {
+			jitter := time.Duration(rand.Int63n(jitterMax))
+			delay += jitter
+		}
+	}
+
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	return delay
+}
+
+// Processor разбирает сообщение с заказом и обрабатывает его с повторами по RetryPolicy
+type Processor struct {
+	orderService service.OrderServicer
+	retry        RetryPolicy
+	logger       *slog.Logger
+}
+
+func NewProcessor(orderService service.OrderServicer, retry RetryPolicy, logger *slog.Logger) *Processor {
+	return &Processor{
+		orderService: orderService,
+		retry:        retry,
+		logger:       logger,
+	}
+}
+
+// RetryPolicy возвращает политику повторов
+func (p *Processor) RetryPolicy() RetryPolicy {
+	return p.retry
+}
+
+// Process разбирает заказ из payload и обрабатывает его. Нечитаемый JSON - постоянная ошибка.
+func (p *Processor) Process(ctx context.Context, payload []byte) error {
+	var order domain.Order
+	if err := json.Unmarshal(payload, &order); err != nil {
+		p.logger.Error("error unmarshaling order",
+			slog.String("error", err.Error()),
+			slog.String("value", string(payload)))
+		return fmt.Errorf("unmarshal order: %w", domain.Permanent(err))
+	}
+
+	p.logger.Debug("order unmarshaled successfully",
+		slog.String("order_uid", order.OrderUID))
+
+	// Обрабатываем заказ с повторными попытками
+	return p.processWithRetry(ctx, &order)
+}
+
+// processWithRetry обрабатывает заказ с механизмом повторных попыток и экспоненциальной задержкой
+func (p *Processor) processWithRetry(ctx context.Context, order *domain.Order) error {
+	p.logger.Debug("starting order processing with retry",
+		slog.String("order_uid", order.OrderUID),
+		slog.Int("max_retries", p.retry.MaxRetries))
+
+	var lastErr error
+
+	for attempt := 1; attempt <= p.retry.MaxRetries+1; attempt++ {
+		p.logger.Debug("attempting to process order",
+			slog.String("order_uid", order.OrderUID),
+			slog.Int("attempt", attempt))
+
+		err := p.orderService.ProcessOrderMessage(ctx, order)
+		if err == nil {
+			p.logger.Info("order processed successfully",
+				slog.String("order_uid", order.OrderUID),
+				slog.Int("attempt", attempt))
+			return nil
+		}
+
+		lastErr = err
+		class := domain.ClassifyError(err)
+		p.logger.Warn("order processing failed",
+			slog.String("order_uid", order.OrderUID),
+			slog.Int("attempt", attempt),
+			slog.Int("max_retries", p.retry.MaxRetries),
+			slog.String("error_class", string(class)),
+			slog.String("error", err.Error()))
+
+		// Постоянная ошибка повторится при любой попытке, сразу отправляем в DLQ
+		if class == domain.ErrorClassPermanent {
+			return err
+		}
+
+		if attempt <= p.retry.MaxRetries {
+			delay := p.retry.Backoff(attempt)
+			p.logger.Debug("waiting before retry",
+				slog.String("order_uid", order.OrderUID),
+				slog.Duration("delay", delay))
+
+			select {
+			case <-ctx.Done():
+				p.logger.Debug("context cancelled during retry wait",
+					slog.String("order_uid", order.OrderUID))
+				return ctx.Err()
+			case <-time.After(delay): // Продолжение после задержки
+			}
+		}
+	}
+
+	p.logger.Error("failed to process order after all retry attempts",
+		slog.String("order_uid", order.OrderUID),
+		slog.Int("max_retries", p.retry.MaxRetries),
+		slog.String("error", lastErr.Error()))
+
+	return fmt.Errorf("failed to process order after %d attempts: %w", p.retry.MaxRetries+1, lastErr)
+}
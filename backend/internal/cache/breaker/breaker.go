@@ -0,0 +1,161 @@
+// Package breaker реализует автоматический выключатель для внешнего кэша.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Stats состояние выключателя
+type Stats struct {
+	Open    bool  `json:"open"`
+	Trips   int64 `json:"trips"`
+	Skipped int64 `json:"skipped"`
+}
+
+// Breaker размыкается после threshold ошибок между проверками или при неудачной
+// проверке probe. Пока он разомкнут, обращения к кэшу пропускаются. Проверка
+// выполняется каждые interval; после первой успешной выключатель замыкается
+// и вызывает обработчик восстановления.
+type Breaker struct {
+	probe       func(ctx context.Context) error
+	threshold   int64
+	interval    time.Duration
+	beforeClose func(ctx context.Context) error
+	onRecover   func(ctx context.Context)
+	logger      *slog.Logger
+
+	open       atomic.Bool
+	failures   atomic.Int64
+	trips      atomic.Int64
+	skipped    atomic.Int64
+	recovering atomic.Bool
+}
+
+// DefaultInterval интервал проверки по умолчанию
+const DefaultInterval = time.Second
+
+// New создает замкнутый выключатель
+func New(probe func(ctx context.Context) error, threshold int, interval time.Duration, logger *slog.Logger) *Breaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Breaker{
+		probe:     probe,
+		threshold: int64(threshold),
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// OnRecover задает действие после восстановления (например, повторный прогрев кэша)
+func (b *Breaker) OnRecover(fn func(ctx context.Context)) {
+	b.onRecover = fn
+}
+
+// BeforeClose задает действие, которое должно выполниться до замыкания выключателя
+// (например, применение отложенных инвалидаций). Пока оно возвращает ошибку,
+// выключатель остается разомкнутым.
+func (b *Breaker) BeforeClose(fn func(ctx context.Context) error) {
+	b.beforeClose = fn
+}
+
+// Allow сообщает, можно ли обращаться к кэшу
+func (b *Breaker) Allow() bool {
+	if b.open.Load() {
+		b.skipped.Add(1)
+		return false
+	}
+	return true
+}
+
+// Available сообщает, замкнут ли выключатель, не учитывая обращение как пропущенное
+func (b *Breaker) Available() bool {
+	return !b.open.Load()
+}
+
+// Failure учитывает ошибку обращения к кэшу. Отмена контекста вызывающим ошибкой кэша не считается.
+func (b *Breaker) Failure(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if b.failures.Add(1) >= b.threshold {
+		b.trip(err)
+	}
+}
+
+// Check проверяет доступность кэша и переключает состояние выключателя
+func (b *Breaker) Check(ctx context.Context) {
+	probeCtx, cancel := context.WithTimeout(ctx, b.interval)
+	defer cancel()
+
+	if err := b.probe(probeCtx); err != nil {
+		if ctx.Err() == nil {
+			b.trip(err)
+		}
+		return
+	}
+
+	b.failures.Store(0)
+	if !b.open.Load() {
+		return
+	}
+	if b.beforeClose != nil {
+		if err := b.beforeClose(ctx); err != nil {
+			b.logger.Warn("cache is reachable, but circuit breaker stays open", slog.Any("error", err))
+			return
+		}
+	}
+	if b.open.CompareAndSwap(true, false) {
+		b.logger.Info("cache is available again, circuit breaker closed")
+		b.startRecovery(ctx)
+	}
+}
+
+// Run периодически проверяет доступность кэша до отмены контекста
+func (b *Breaker) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Check(ctx)
+		}
+	}
+}
+
+// Stats возвращает состояние выключателя
+func (b *Breaker) Stats() Stats {
+	return Stats{
+		Open:    b.open.Load(),
+		Trips:   b.trips.Load(),
+		Skipped: b.skipped.Load(),
+	}
+}
+
+func (b *Breaker) trip(err error) {
+	if b.open.CompareAndSwap(false, true) {
+		b.trips.Add(1)
+		b.logger.Warn("cache is unavailable, circuit breaker opened", slog.Any("error", err))
+	}
+}
+
+// startRecovery запускает обработчик восстановления, если он еще не выполняется
+func (b *Breaker) startRecovery(ctx context.Context) {
+	if b.onRecover == nil || !b.recovering.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer b.recovering.Store(false)
+		b.onRecover(ctx)
+	}()
+}
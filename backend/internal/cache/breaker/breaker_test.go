@@ -0,0 +1,125 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProbe проверка доступности с управляемым результатом.
+type fakeProbe struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *fakeProbe) set(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *fakeProbe) check(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func newTestBreaker(probe *fakeProbe) *Breaker {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return New(probe.check, 3, 10*time.Millisecond, logger)
+}
+
+func TestBreaker_TripsOnFailures(t *testing.T) {
+	probe := &fakeProbe{}
+	b := newTestBreaker(probe)
+
+	assert.True(t, b.Allow())
+	b.Failure(errors.New("timeout"))
+	b.Failure(context.Canceled)
+	b.Failure(errors.New("timeout"))
+	assert.True(t, b.Allow(), "canceled calls are not failures")
+
+	b.Failure(errors.New("timeout"))
+	assert.False(t, b.Allow())
+	assert.False(t, b.Available())
+
+	stats := b.Stats()
+	assert.True(t, stats.Open)
+	assert.Equal(t, int64(1), stats.Trips)
+	assert.Equal(t, int64(1), stats.Skipped)
+}
+
+func TestBreaker_ProbeResetsFailures(t *testing.T) {
+	probe := &fakeProbe{}
+	b := newTestBreaker(probe)
+
+	b.Failure(errors.New("timeout"))
+	b.Failure(errors.New("timeout"))
+	b.Check(context.Background())
+	b.Failure(errors.New("timeout"))
+	assert.True(t, b.Available())
+}
+
+func TestBreaker_RecoversAndRewarms(t *testing.T) {
+	probe := &fakeProbe{err: errors.New("connection refused")}
+	b := newTestBreaker(probe)
+
+	recovered := make(chan struct{}, 1)
+	b.OnRecover(func(ctx context.Context) { recovered <- struct{}{} })
+
+	b.Check(context.Background())
+	assert.False(t, b.Available())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	probe.set(errors.New("still down"))
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, b.Available())
+
+	probe.set(nil)
+	select {
+	case <-recovered:
+	case <-time.After(time.Second):
+		t.Fatal("recovery handler was not called")
+	}
+	assert.True(t, b.Available())
+	assert.Equal(t, int64(1), b.Stats().Trips)
+}
+
+func TestBreaker_BeforeClose(t *testing.T) {
+	probe := &fakeProbe{err: errors.New("connection refused")}
+	b := newTestBreaker(probe)
+
+	var calls int
+	replayErr := errors.New("replay failed")
+	b.BeforeClose(func(ctx context.Context) error {
+		calls++
+		return replayErr
+	})
+
+	b.Check(context.Background())
+	assert.False(t, b.Available())
+	assert.Zero(t, calls)
+
+	probe.set(nil)
+	b.Check(context.Background())
+	assert.False(t, b.Available())
+	assert.Equal(t, 1, calls)
+
+	replayErr = nil
+	b.Check(context.Background())
+	assert.True(t, b.Available())
+	assert.Equal(t, 2, calls)
+
+	// Замкнутый выключатель действие не повторяет
+	b.Check(context.Background())
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,271 @@
+// Package codec кодирует заказы для хранения в кэше.
+//
+// Первый байт записи - маркер формата, поэтому записи в разных форматах
+// читаются одновременно и формат можно сменить без очистки Redis.
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format формат сериализации заказа
+type Format byte
+
+const (
+	FormatJSON     Format = 0x01
+	FormatMsgpack  Format = 0x02
+	FormatProtobuf Format = 0x03
+)
+
+// compressedFlag бит маркера, означающий, что данные сжаты zstd
+const compressedFlag byte = 0x80
+
+// legacyMarker первый байт записей, сохраненных до появления маркера (JSON-объект)
+const legacyMarker byte = '{'
+
+// maxDecodedSize ограничение размера распакованной записи
+const maxDecodedSize = 16 << 20
+
+// ErrUnknownFormat возвращается для записи с неизвестным маркером формата
+var ErrUnknownFormat = errors.New("unknown cache codec format")
+
+var formatNames = map[Format]string{
+	FormatJSON:     "json",
+	FormatMsgpack:  "msgpack",
+	FormatProtobuf: "protobuf",
+}
+
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("format(0x%02x)", byte(f))
+}
+
+// Codec кодирует заказы в выбранном формате, опционально со сжатием zstd.
+// Декодирование не зависит от настроек кодека и определяется маркером записи.
+type Codec struct {
+	format   Format
+	compress bool
+}
+
+// JSON кодек по умолчанию
+var JSON = &Codec{format: FormatJSON}
+
+// New создает кодек для формата
+func New(format Format, compress bool) (*Codec, error) {
+	if _, ok := formatNames[format]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	return &Codec{format: format, compress: compress}, nil
+}
+
+// Parse создает кодек по имени: json, msgpack или protobuf,
+// с суффиксом +zstd для сжатия (например, msgpack+zstd)
+func Parse(name string) (*Codec, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	formatName, compress := strings.CutSuffix(name, "+zstd")
+	for format, n := range formatNames {
+		if n == formatName {
+			return New(format, compress)
+		}
+	}
+	return nil, fmt.Errorf("unknown cache codec %q", name)
+}
+
+// Format возвращает формат кодека
+func (c *Codec) Format() Format {
+	return c.format
+}
+
+func (c *Codec) String() string {
+	if c.compress {
+		return c.format.String() + "+zstd"
+	}
+	return c.format.String()
+}
+
+// Describe возвращает формат записи по ее маркеру, например msgpack+zstd
+func Describe(record []byte) string {
+	if len(record) == 0 {
+		return "empty"
+	}
+	if record[0] == legacyMarker {
+		return "json (legacy)"
+	}
+	c := Codec{format: Format(record[0] &^ compressedFlag), compress: record[0]&compressedFlag != 0}
+	return c.String()
+}
+
+// Encode кодирует заказ в запись с маркером формата
+func (c *Codec) Encode(order *domain.Order) ([]byte, error) {
+	marker := byte(c.format)
+	var (
+		data []byte
+		err  error
+	)
+	switch c.format {
+	case FormatJSON:
+		data, err = json.Marshal(order)
+	case FormatMsgpack:
+		data, err = marshalMsgpack(order)
+	case FormatProtobuf:
+		data = marshalProto(nil, order)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, c.format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode order as %s: %w", c.format, err)
+	}
+
+	if c.compress {
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return encoder.EncodeAll(data, []byte{marker | compressedFlag}), nil
+	}
+
+	record := make([]byte, 0, len(data)+1)
+	record = append(record, marker)
+	return append(record, data...), nil
+}
+
+// Decode декодирует запись любого поддерживаемого формата.
+// Записи без маркера считаются JSON.
+func Decode(record []byte) (*domain.Order, error) {
+	if len(record) == 0 {
+		return nil, errors.New("empty cache record")
+	}
+
+	var order domain.Order
+	if record[0] == legacyMarker {
+		if err := json.Unmarshal(record, &order); err != nil {
+			return nil, fmt.Errorf("failed to decode order as json: %w", err)
+		}
+		return &order, nil
+	}
+
+	marker, data := record[0], record[1:]
+	if marker&compressedFlag != 0 {
+		decoder, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		data, err = decoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress cache record: %w", err)
+		}
+	}
+
+	format := Format(marker &^ compressedFlag)
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &order)
+	case FormatMsgpack:
+		err = unmarshalMsgpack(data, &order)
+	case FormatProtobuf:
+		err = unmarshalProto(data, &order)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode order as %s: %w", format, err)
+	}
+	return &order, nil
+}
+
+// DecodeJSON возвращает заказ из записи в JSON. Записи в формате JSON
+// отдаются без декодирования (сжатые только распаковываются), остальные
+// форматы декодируются и сериализуются заново.
+func DecodeJSON(record []byte) ([]byte, error) {
+	if len(record) == 0 {
+		return nil, errors.New("empty cache record")
+	}
+	if record[0] == legacyMarker {
+		return record, nil
+	}
+
+	marker := record[0]
+	if Format(marker&^compressedFlag) != FormatJSON {
+		order, err := Decode(record)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(order)
+	}
+
+	if marker&compressedFlag == 0 {
+		return record[1:], nil
+	}
+	decoder, err := zstdDecoder()
+	if err != nil {
+		return nil, err
+	}
+	data, err := decoder.DecodeAll(record[1:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress cache record: %w", err)
+	}
+	return data, nil
+}
+
+// ErrNotSelfDescribing возвращается DecodeRaw для формата, который читается
+// только в форме текущей версии схемы
+var ErrNotSelfDescribing = errors.New("cache codec format is not self-describing")
+
+// DecodeRaw возвращает поля записи в JSON, не приводя их к текущей форме
+// domain.Order, чтобы запись прежней версии схемы можно было перевести
+// в текущую (domain.UpgradeOrder). Protobuf не описывает поля сам, поэтому
+// такие записи не переводятся.
+func DecodeRaw(record []byte) ([]byte, error) {
+	if len(record) == 0 {
+		return nil, errors.New("empty cache record")
+	}
+	format := Format(record[0] &^ compressedFlag)
+	if record[0] == legacyMarker || format == FormatJSON {
+		return DecodeJSON(record)
+	}
+
+	data := record[1:]
+	if record[0]&compressedFlag != 0 {
+		decoder, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		data, err = decoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress cache record: %w", err)
+		}
+	}
+
+	switch format {
+	case FormatMsgpack:
+		fields, err := unmarshalMsgpackFields(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode order as %s: %w", format, err)
+		}
+		return json.Marshal(fields)
+	case FormatProtobuf:
+		return nil, fmt.Errorf("%w: %s", ErrNotSelfDescribing, format)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// Энкодер и декодер zstd потокобезопасны в режиме EncodeAll/DecodeAll и создаются один раз
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxDecodedSize))
+	})
+)
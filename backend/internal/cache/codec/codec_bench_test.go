@@ -0,0 +1,75 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/mockorder"
+)
+
+// Бенчмарки на заказах генератора publisher. Кроме времени и аллокаций
+// выводят средний размер записи в кэше (bytes/order).
+//
+//	go test -run=^$ -bench=. -benchmem ./internal/cache/codec/
+
+const benchOrders = 1000
+
+func benchmarkOrders() []*domain.Order {
+	generated := mockorder.Generate(benchOrders)
+	orders := make([]*domain.Order, len(generated))
+	for i := range generated {
+		orders[i] = &generated[i]
+	}
+	return orders
+}
+
+func BenchmarkEncode(b *testing.B) {
+	orders := benchmarkOrders()
+
+	for _, name := range codecNames {
+		c, err := Parse(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			var size int
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				record, err := c.Encode(orders[i%len(orders)])
+				if err != nil {
+					b.Fatal(err)
+				}
+				size += len(record)
+			}
+			b.ReportMetric(float64(size)/float64(b.N), "bytes/order")
+		})
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	orders := benchmarkOrders()
+
+	for _, name := range codecNames {
+		c, err := Parse(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		records := make([][]byte, len(orders))
+		for i, order := range orders {
+			if records[i], err = c.Encode(order); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Decode(records[i%len(records)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
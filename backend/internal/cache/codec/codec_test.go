@@ -0,0 +1,181 @@
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/mockorder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var codecNames = []string{"json", "json+zstd", "msgpack", "msgpack+zstd", "protobuf", "protobuf+zstd"}
+
+func testOrder(t *testing.T) *domain.Order {
+	t.Helper()
+	order := mockorder.Generate(1)[0]
+	order.Items[0].ID = 42
+	order.CreatedAt = time.Date(2024, 5, 1, 10, 0, 0, 123, time.UTC)
+	order.UpdatedAt = order.CreatedAt.Add(time.Minute)
+	return &order
+}
+
+// assertOrderEqual сравнивает заказы с учетом того, что форматы по-разному сохраняют часовой пояс
+func assertOrderEqual(t *testing.T, expected, actual *domain.Order) {
+	t.Helper()
+	assert.True(t, expected.DateCreated.Equal(actual.DateCreated))
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt))
+	assert.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt))
+
+	e, a := *expected, *actual
+	e.DateCreated, a.DateCreated = time.Time{}, time.Time{}
+	e.CreatedAt, a.CreatedAt = time.Time{}, time.Time{}
+	e.UpdatedAt, a.UpdatedAt = time.Time{}, time.Time{}
+	assert.Equal(t, e, a)
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	order := testOrder(t)
+
+	for _, name := range codecNames {
+		t.Run(name, func(t *testing.T) {
+			c, err := Parse(name)
+			require.NoError(t, err)
+			assert.Equal(t, name, c.String())
+
+			record, err := c.Encode(order)
+			require.NoError(t, err)
+
+			decoded, err := Decode(record)
+			require.NoError(t, err)
+			assertOrderEqual(t, order, decoded)
+		})
+	}
+}
+
+func TestCodec_EmptyOrder(t *testing.T) {
+	for _, name := range codecNames {
+		c, err := Parse(name)
+		require.NoError(t, err)
+
+		record, err := c.Encode(&domain.Order{OrderUID: "empty"})
+		require.NoError(t, err, name)
+
+		decoded, err := Decode(record)
+		require.NoError(t, err, name)
+		assert.Equal(t, "empty", decoded.OrderUID, name)
+		assert.Empty(t, decoded.Items, name)
+		assert.True(t, decoded.DateCreated.IsZero(), name)
+	}
+}
+
+func TestDecode_LegacyJSON(t *testing.T) {
+	order := testOrder(t)
+	data, err := json.Marshal(order)
+	require.NoError(t, err)
+
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	assertOrderEqual(t, order, decoded)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	order := testOrder(t)
+	expected, err := json.Marshal(order)
+	require.NoError(t, err)
+
+	data, err := DecodeJSON(expected)
+	require.NoError(t, err)
+	assert.Equal(t, expected, data)
+
+	for _, name := range codecNames {
+		c, err := Parse(name)
+		require.NoError(t, err)
+		record, err := c.Encode(order)
+		require.NoError(t, err)
+
+		data, err := DecodeJSON(record)
+		require.NoError(t, err, name)
+
+		var decoded domain.Order
+		require.NoError(t, json.Unmarshal(data, &decoded), name)
+		assertOrderEqual(t, order, &decoded)
+	}
+
+	// Несжатый JSON отдается без копирования
+	record, err := JSON.Encode(order)
+	require.NoError(t, err)
+	data, err = DecodeJSON(record)
+	require.NoError(t, err)
+	assert.Same(t, &record[1], &data[0])
+}
+
+func TestDecodeRaw(t *testing.T) {
+	order := testOrder(t)
+
+	for _, name := range codecNames {
+		c, err := Parse(name)
+		require.NoError(t, err)
+		record, err := c.Encode(order)
+		require.NoError(t, err)
+
+		data, err := DecodeRaw(record)
+		if c.Format() == FormatProtobuf {
+			assert.ErrorIs(t, err, ErrNotSelfDescribing, name)
+			continue
+		}
+		require.NoError(t, err, name)
+
+		var decoded domain.Order
+		require.NoError(t, json.Unmarshal(data, &decoded), name)
+		assertOrderEqual(t, order, &decoded)
+	}
+
+	// Поля, которых нет в domain.Order, сохраняются для шагов перевода схемы
+	record, err := msgpack.Marshal(map[string]any{"order_uid": "uid", "legacy_field": "value"})
+	require.NoError(t, err)
+	data, err := DecodeRaw(append([]byte{byte(FormatMsgpack)}, record...))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"order_uid":"uid","legacy_field":"value"}`, string(data))
+}
+
+func TestDecode_Errors(t *testing.T) {
+	_, err := Decode(nil)
+	assert.Error(t, err)
+
+	_, err = Decode([]byte{0x7f, 1, 2})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = Decode([]byte{byte(FormatProtobuf), 0x0a, 0x10})
+	assert.Error(t, err)
+
+	_, err = Decode([]byte{byte(FormatJSON) | compressedFlag, 1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, name := range []string{"", "xml", "zstd", "json+gzip"} {
+		_, err := Parse(name)
+		assert.Error(t, err, name)
+	}
+
+	_, err := New(Format(0x10), false)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestDescribe(t *testing.T) {
+	order := testOrder(t)
+	for _, name := range codecNames {
+		c, err := Parse(name)
+		require.NoError(t, err)
+		record, err := c.Encode(order)
+		require.NoError(t, err)
+		assert.Equal(t, name, Describe(record))
+	}
+
+	assert.Equal(t, "json (legacy)", Describe([]byte(`{"order_uid":"a"}`)))
+	assert.Equal(t, "empty", Describe(nil))
+}
@@ -0,0 +1,46 @@
+package codec
+
+import (
+	"bytes"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Поля кодируются по json-тегам, чтобы набор полей совпадал с JSON-представлением заказа
+
+func marshalMsgpack(order *domain.Order) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.GetEncoder()
+	defer msgpack.PutEncoder(enc)
+
+	enc.Reset(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(order); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalMsgpack(data []byte, order *domain.Order) error {
+	dec := msgpack.GetDecoder()
+	defer msgpack.PutDecoder(dec)
+
+	dec.Reset(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(order)
+}
+
+// unmarshalMsgpackFields декодирует запись в словарь полей без привязки к domain.Order
+func unmarshalMsgpackFields(data []byte) (map[string]any, error) {
+	dec := msgpack.GetDecoder()
+	defer msgpack.PutDecoder(dec)
+
+	dec.Reset(bytes.NewReader(data))
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
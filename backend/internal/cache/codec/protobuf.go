@@ -0,0 +1,332 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Кодирование по схеме order.proto. Поля с нулевыми значениями не пишутся,
+// неизвестные поля при чтении пропускаются.
+
+var errWireType = errors.New("unexpected wire type")
+
+func marshalProto(b []byte, o *domain.Order) []byte {
+	b = appendString(b, 1, o.OrderUID)
+	b = appendString(b, 2, o.TrackNumber)
+	b = appendString(b, 3, o.Entry)
+	b = appendMessage(b, 4, marshalDelivery(nil, &o.Delivery))
+	b = appendMessage(b, 5, marshalPayment(nil, &o.Payment))
+	for i := range o.Items {
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendBytes(b, marshalItem(nil, &o.Items[i]))
+	}
+	b = appendString(b, 7, o.Locale)
+	b = appendString(b, 8, o.InternalSignature)
+	b = appendString(b, 9, o.CustomerID)
+	b = appendString(b, 10, o.DeliveryService)
+	b = appendString(b, 11, o.ShardKey)
+	b = appendInt(b, 12, int64(o.SmID))
+	b = appendTime(b, 13, o.DateCreated)
+	b = appendString(b, 14, o.OofShard)
+	b = appendTime(b, 15, o.CreatedAt)
+	b = appendTime(b, 16, o.UpdatedAt)
+	return b
+}
+
+func marshalDelivery(b []byte, d *domain.Delivery) []byte {
+	b = appendString(b, 1, d.Name)
+	b = appendString(b, 2, d.Phone)
+	b = appendString(b, 3, d.Zip)
+	b = appendString(b, 4, d.City)
+	b = appendString(b, 5, d.Address)
+	b = appendString(b, 6, d.Region)
+	b = appendString(b, 7, d.Email)
+	return b
+}
+
+func marshalPayment(b []byte, p *domain.Payment) []byte {
+	b = appendString(b, 1, p.Transaction)
+	b = appendString(b, 2, p.RequestID)
+	b = appendString(b, 3, p.Currency)
+	b = appendString(b, 4, p.Provider)
+	b = appendInt(b, 5, int64(p.Amount))
+	b = appendInt(b, 6, p.PaymentDt)
+	b = appendString(b, 7, p.Bank)
+	b = appendInt(b, 8, int64(p.DeliveryCost))
+	b = appendInt(b, 9, int64(p.GoodsTotal))
+	b = appendInt(b, 10, int64(p.CustomFee))
+	return b
+}
+
+func marshalItem(b []byte, it *domain.Item) []byte {
+	b = appendInt(b, 1, it.ID)
+	b = appendInt(b, 2, int64(it.ChrtID))
+	b = appendString(b, 3, it.TrackNumber)
+	b = appendInt(b, 4, int64(it.Price))
+	b = appendString(b, 5, it.Rid)
+	b = appendString(b, 6, it.Name)
+	b = appendInt(b, 7, int64(it.Sale))
+	b = appendString(b, 8, it.Size)
+	b = appendInt(b, 9, int64(it.TotalPrice))
+	b = appendInt(b, 10, int64(it.NmID))
+	b = appendString(b, 11, it.Brand)
+	b = appendInt(b, 12, int64(it.Status))
+	return b
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendInt(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	if len(msg) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendTime(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	var ts []byte
+	ts = appendInt(ts, 1, t.Unix())
+	ts = appendInt(ts, 2, int64(t.Nanosecond()))
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, ts)
+}
+
+// field значение поля сообщения: bytes для BytesType, varint для VarintType
+type field struct {
+	num    protowire.Number
+	typ    protowire.Type
+	bytes  []byte
+	varint uint64
+}
+
+func (f field) str() (string, error) {
+	if f.typ != protowire.BytesType {
+		return "", fmt.Errorf("field %d: %w", f.num, errWireType)
+	}
+	return string(f.bytes), nil
+}
+
+func (f field) int() (int64, error) {
+	if f.typ != protowire.VarintType {
+		return 0, fmt.Errorf("field %d: %w", f.num, errWireType)
+	}
+	return int64(f.varint), nil
+}
+
+func (f field) message() ([]byte, error) {
+	if f.typ != protowire.BytesType {
+		return nil, fmt.Errorf("field %d: %w", f.num, errWireType)
+	}
+	return f.bytes, nil
+}
+
+// forEachField вызывает fn для каждого поля сообщения
+func forEachField(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalProto(b []byte, o *domain.Order) error {
+	return forEachField(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			o.OrderUID, err = f.str()
+		case 2:
+			o.TrackNumber, err = f.str()
+		case 3:
+			o.Entry, err = f.str()
+		case 4:
+			err = unmarshalNested(f, func(b []byte) error { return unmarshalDelivery(b, &o.Delivery) })
+		case 5:
+			err = unmarshalNested(f, func(b []byte) error { return unmarshalPayment(b, &o.Payment) })
+		case 6:
+			var item domain.Item
+			err = unmarshalNested(f, func(b []byte) error { return unmarshalItem(b, &item) })
+			o.Items = append(o.Items, item)
+		case 7:
+			o.Locale, err = f.str()
+		case 8:
+			o.InternalSignature, err = f.str()
+		case 9:
+			o.CustomerID, err = f.str()
+		case 10:
+			o.DeliveryService, err = f.str()
+		case 11:
+			o.ShardKey, err = f.str()
+		case 12:
+			err = setInt(f, &o.SmID)
+		case 13:
+			err = unmarshalNested(f, func(b []byte) error { return unmarshalTime(b, &o.DateCreated) })
+		case 14:
+			o.OofShard, err = f.str()
+		case 15:
+			err = unmarshalNested(f, func(b []byte) error { return unmarshalTime(b, &o.CreatedAt) })
+		case 16:
+			err = unmarshalNested(f, func(b []byte) error { return unmarshalTime(b, &o.UpdatedAt) })
+		}
+		return err
+	})
+}
+
+func unmarshalDelivery(b []byte, d *domain.Delivery) error {
+	return forEachField(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			d.Name, err = f.str()
+		case 2:
+			d.Phone, err = f.str()
+		case 3:
+			d.Zip, err = f.str()
+		case 4:
+			d.City, err = f.str()
+		case 5:
+			d.Address, err = f.str()
+		case 6:
+			d.Region, err = f.str()
+		case 7:
+			d.Email, err = f.str()
+		}
+		return err
+	})
+}
+
+func unmarshalPayment(b []byte, p *domain.Payment) error {
+	return forEachField(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			p.Transaction, err = f.str()
+		case 2:
+			p.RequestID, err = f.str()
+		case 3:
+			p.Currency, err = f.str()
+		case 4:
+			p.Provider, err = f.str()
+		case 5:
+			err = setInt(f, &p.Amount)
+		case 6:
+			p.PaymentDt, err = f.int()
+		case 7:
+			p.Bank, err = f.str()
+		case 8:
+			err = setInt(f, &p.DeliveryCost)
+		case 9:
+			err = setInt(f, &p.GoodsTotal)
+		case 10:
+			err = setInt(f, &p.CustomFee)
+		}
+		return err
+	})
+}
+
+func unmarshalItem(b []byte, it *domain.Item) error {
+	return forEachField(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			it.ID, err = f.int()
+		case 2:
+			err = setInt(f, &it.ChrtID)
+		case 3:
+			it.TrackNumber, err = f.str()
+		case 4:
+			err = setInt(f, &it.Price)
+		case 5:
+			it.Rid, err = f.str()
+		case 6:
+			it.Name, err = f.str()
+		case 7:
+			err = setInt(f, &it.Sale)
+		case 8:
+			it.Size, err = f.str()
+		case 9:
+			err = setInt(f, &it.TotalPrice)
+		case 10:
+			err = setInt(f, &it.NmID)
+		case 11:
+			it.Brand, err = f.str()
+		case 12:
+			err = setInt(f, &it.Status)
+		}
+		return err
+	})
+}
+
+func unmarshalTime(b []byte, t *time.Time) error {
+	var seconds, nanos int64
+	err := forEachField(b, func(f field) (err error) {
+		switch f.num {
+		case 1:
+			seconds, err = f.int()
+		case 2:
+			nanos, err = f.int()
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	*t = time.Unix(seconds, nanos).UTC()
+	return nil
+}
+
+func unmarshalNested(f field, fn func(b []byte) error) error {
+	b, err := f.message()
+	if err != nil {
+		return err
+	}
+	if err := fn(b); err != nil {
+		return fmt.Errorf("field %d: %w", f.num, err)
+	}
+	return nil
+}
+
+func setInt(f field, dst *int) error {
+	v, err := f.int()
+	*dst = int(v)
+	return err
+}
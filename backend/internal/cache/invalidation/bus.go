@@ -0,0 +1,214 @@
+// Package invalidation рассылает изменения заказов между репликами сервиса,
+// чтобы каждая реплика удаляла устаревшие копии из своих локальных кэшей.
+package invalidation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Message сообщение об изменении заказов. Source - идентификатор отправившей
+// реплики, чтобы она могла пропускать собственные сообщения. Flush означает,
+// что локальные кэши нужно очистить полностью.
+type Message struct {
+	Source string   `json:"source"`
+	Keys   []string `json:"keys,omitempty"`
+	Flush  bool     `json:"flush,omitempty"`
+}
+
+// Transport доставляет сообщения между репликами (redis.Invalidations).
+// Subscribe блокируется до отмены контекста или обрыва подписки и вызывает
+// ready при каждом подтверждении подписки, в том числе после переподключения
+// внутри одного вызова.
+type Transport interface {
+	Publish(ctx context.Context, msg Message) error
+	Subscribe(ctx context.Context, ready func(), fn func(msg Message)) error
+}
+
+// Handler локальный кэш, получающий инвалидации (memory.Cache)
+type Handler interface {
+	Delete(keys ...string)
+	Purge()
+}
+
+// Stats счетчики шины инвалидаций
+type Stats struct {
+	Subscribed bool  `json:"subscribed"`
+	Published  int64 `json:"published"`
+	Received   int64 `json:"received"`
+	Flushes    int64 `json:"flushes"`
+	Reconnects int64 `json:"reconnects"`
+}
+
+const (
+	// minResubscribeDelay и maxResubscribeDelay ограничивают паузу перед повторной подпиской
+	minResubscribeDelay = time.Second
+	maxResubscribeDelay = 30 * time.Second
+)
+
+// Bus шина инвалидаций. Локальные кэши регистрируются через Register,
+// изменения публикуются через Publish и PublishFlush.
+type Bus struct {
+	transport Transport
+	id        string
+	logger    *slog.Logger
+
+	mu       sync.RWMutex
+	handlers []Handler
+
+	subscribed atomic.Bool
+	published  atomic.Int64
+	received   atomic.Int64
+	flushes    atomic.Int64
+	reconnects atomic.Int64
+}
+
+// New создает шину. transport может быть nil для единственной реплики:
+// тогда инвалидации применяются только к локальным кэшам этой реплики.
+func New(transport Transport, logger *slog.Logger) *Bus {
+	return &Bus{
+		transport: transport,
+		id:        newInstanceID(),
+		logger:    logger,
+	}
+}
+
+// ID возвращает идентификатор реплики
+func (b *Bus) ID() string {
+	return b.id
+}
+
+// Register подписывает локальный кэш на инвалидации других реплик
+func (b *Bus) Register(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish сообщает другим репликам об изменении заказов.
+// Локальные кэши этой реплики обновляет сам отправитель.
+func (b *Bus) Publish(ctx context.Context, keys ...string) {
+	if len(keys) == 0 || b.transport == nil {
+		return
+	}
+	if err := b.transport.Publish(ctx, Message{Source: b.id, Keys: keys}); err != nil {
+		b.logger.Error("failed to publish cache invalidation", slog.Int("count", len(keys)), slog.Any("error", err))
+		return
+	}
+	b.published.Add(1)
+}
+
+// PublishFlush очищает локальные кэши этой реплики и просит о том же все остальные
+func (b *Bus) PublishFlush(ctx context.Context) error {
+	b.purge()
+	b.logger.Warn("local caches flushed", slog.String("source", b.id))
+	if b.transport == nil {
+		return nil
+	}
+	if err := b.transport.Publish(ctx, Message{Source: b.id, Flush: true}); err != nil {
+		return err
+	}
+	b.published.Add(1)
+	return nil
+}
+
+// Run принимает инвалидации от других реплик до отмены контекста.
+// После обрыва подписка восстанавливается с экспоненциальной паузой; пока
+// подписки нет, сообщения теряются, поэтому локальные кэши очищаются как при
+// обрыве, так и после восстановления подписки - в том числе когда транспорт
+// переподключился сам и повторно вызвал ready.
+func (b *Bus) Run(ctx context.Context) {
+	if b.transport == nil {
+		<-ctx.Done()
+		return
+	}
+
+	delay := minResubscribeDelay
+	for attempt := 0; ; attempt++ {
+		// Повторное подтверждение внутри одного Subscribe - переподключение транспорта.
+		// ready может вызываться из горутины клиента, поэтому состояние атомарное.
+		var reconnect, confirmed atomic.Bool
+		reconnect.Store(attempt > 0)
+		err := b.transport.Subscribe(ctx, func() {
+			b.subscribed.Store(true)
+			confirmed.Store(true)
+			again := reconnect.Swap(true)
+			if again {
+				b.reconnects.Add(1)
+				b.purge()
+			}
+			b.logger.Info("subscribed to cache invalidations", slog.String("instance_id", b.id), slog.Bool("reconnect", again))
+		}, b.handle)
+		b.subscribed.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if confirmed.Load() {
+			delay = minResubscribeDelay
+		}
+
+		b.purge()
+		b.logger.Error("cache invalidation subscription failed, retrying",
+			slog.Duration("delay", delay),
+			slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxResubscribeDelay)
+	}
+}
+
+// Stats возвращает счетчики шины
+func (b *Bus) Stats() Stats {
+	return Stats{
+		Subscribed: b.subscribed.Load(),
+		Published:  b.published.Load(),
+		Received:   b.received.Load(),
+		Flushes:    b.flushes.Load(),
+		Reconnects: b.reconnects.Load(),
+	}
+}
+
+func (b *Bus) handle(msg Message) {
+	if msg.Source == b.id {
+		return
+	}
+	b.received.Add(1)
+
+	if msg.Flush {
+		b.flushes.Add(1)
+		b.purge()
+		b.logger.Warn("local caches flushed by another replica", slog.String("source", msg.Source))
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		h.Delete(msg.Keys...)
+	}
+	b.logger.Debug("local caches invalidated", slog.String("source", msg.Source), slog.Int("count", len(msg.Keys)))
+}
+
+func (b *Bus) purge() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		h.Purge()
+	}
+}
+
+// newInstanceID возвращает случайный идентификатор реплики
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,221 @@
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransport транспорт в памяти: первые failures подписок завершаются ошибкой,
+// drop обрывает текущую подписку, а resubscribe имитирует переподключение клиента.
+type fakeTransport struct {
+	mu        sync.Mutex
+	fn        func(msg Message)
+	ready     func()
+	failures  int
+	drop      chan struct{}
+	published []Message
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{drop: make(chan struct{})}
+}
+
+func (t *fakeTransport) Publish(ctx context.Context, msg Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.published = append(t.published, msg)
+	return nil
+}
+
+func (t *fakeTransport) Subscribe(ctx context.Context, ready func(), fn func(msg Message)) error {
+	t.mu.Lock()
+	if t.failures > 0 {
+		t.failures--
+		t.mu.Unlock()
+		return errors.New("connection refused")
+	}
+	t.fn = fn
+	t.ready = ready
+	drop := t.drop
+	t.mu.Unlock()
+	ready()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-drop:
+		return errors.New("connection reset")
+	}
+}
+
+// deliver передает сообщение подписчику, как если бы его отправила другая реплика
+func (t *fakeTransport) deliver(msg Message) {
+	t.mu.Lock()
+	fn := t.fn
+	t.mu.Unlock()
+	fn(msg)
+}
+
+// resubscribe повторно подтверждает подписку, не завершая Subscribe
+func (t *fakeTransport) resubscribe() {
+	t.mu.Lock()
+	ready := t.ready
+	t.mu.Unlock()
+	ready()
+}
+
+// fakeHandler локальный кэш, запоминающий инвалидации.
+type fakeHandler struct {
+	mu      sync.Mutex
+	deleted []string
+	purges  int
+}
+
+func (h *fakeHandler) Delete(keys ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deleted = append(h.deleted, keys...)
+}
+
+func (h *fakeHandler) Purge() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.purges++
+}
+
+func (h *fakeHandler) state() ([]string, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.deleted...), h.purges
+}
+
+func newTestBus(transport Transport) (*Bus, *fakeHandler) {
+	bus := New(transport, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	handler := &fakeHandler{}
+	bus.Register(handler)
+	return bus, handler
+}
+
+// runBus запускает шину и дожидается подписки
+func runBus(t *testing.T, ctx context.Context, bus *Bus) <-chan struct{} {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		bus.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return bus.Stats().Subscribed }, 5*time.Second, time.Millisecond)
+	return done
+}
+
+func TestBus_Handle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := newFakeTransport()
+	bus, handler := newTestBus(transport)
+	done := runBus(t, ctx, bus)
+
+	transport.deliver(Message{Source: "other", Keys: []string{"uid-1", "uid-2"}})
+	// Собственные сообщения пропускаются
+	transport.deliver(Message{Source: bus.ID(), Keys: []string{"uid-3"}})
+	transport.deliver(Message{Source: "other", Flush: true})
+
+	deleted, purges := handler.state()
+	assert.Equal(t, []string{"uid-1", "uid-2"}, deleted)
+	assert.Equal(t, 1, purges)
+	assert.Equal(t, Stats{Subscribed: true, Received: 2, Flushes: 1}, bus.Stats())
+
+	cancel()
+	<-done
+}
+
+func TestBus_Publish(t *testing.T) {
+	transport := newFakeTransport()
+	bus, handler := newTestBus(transport)
+
+	bus.Publish(context.Background(), "uid-1")
+	bus.Publish(context.Background())
+	require.NoError(t, bus.PublishFlush(context.Background()))
+
+	assert.Equal(t, []Message{
+		{Source: bus.ID(), Keys: []string{"uid-1"}},
+		{Source: bus.ID(), Flush: true},
+	}, transport.published)
+	_, purges := handler.state()
+	assert.Equal(t, 1, purges, "flush must purge local caches of the sender")
+	assert.Equal(t, int64(2), bus.Stats().Published)
+}
+
+func TestBus_WithoutTransport(t *testing.T) {
+	bus, handler := newTestBus(nil)
+	bus.Publish(context.Background(), "uid-1")
+	require.NoError(t, bus.PublishFlush(context.Background()))
+
+	_, purges := handler.state()
+	assert.Equal(t, 1, purges)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bus.Run(ctx)
+}
+
+func TestBus_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := newFakeTransport()
+	transport.failures = 1
+	bus, handler := newTestBus(transport)
+	// Первая попытка подписки завершается ошибкой, повтор - через minResubscribeDelay
+	done := runBus(t, ctx, bus)
+
+	// Локальные кэши очищены после сбоя и после восстановления подписки
+	_, purges := handler.state()
+	assert.Equal(t, 2, purges)
+	assert.Equal(t, int64(1), bus.Stats().Reconnects)
+
+	transport.mu.Lock()
+	close(transport.drop)
+	transport.drop = make(chan struct{})
+	transport.mu.Unlock()
+
+	require.Eventually(t, func() bool { return bus.Stats().Reconnects == 2 }, 5*time.Second, time.Millisecond)
+	_, purges = handler.state()
+	assert.Equal(t, 4, purges)
+
+	cancel()
+	<-done
+}
+
+func TestBus_TransportReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := newFakeTransport()
+	bus, handler := newTestBus(transport)
+	done := runBus(t, ctx, bus)
+
+	_, purges := handler.state()
+	assert.Equal(t, 0, purges)
+
+	// Клиент переподключился сам: Subscribe не вернулся, но подписка подтверждена повторно
+	transport.resubscribe()
+	_, purges = handler.state()
+	assert.Equal(t, 1, purges)
+	assert.Equal(t, int64(1), bus.Stats().Reconnects)
+
+	transport.resubscribe()
+	assert.Equal(t, int64(2), bus.Stats().Reconnects)
+
+	cancel()
+	<-done
+}
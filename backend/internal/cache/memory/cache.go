@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// Cache ограниченный по числу записей LRU кэш заказов в памяти процесса.
+// Заказы хранятся по указателю, поэтому возвращаемые значения нельзя изменять.
+// Запись хранит заказ и/или его JSON; недостающее представление строится
+// при первом обращении и запоминается.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries *list.List
+	items   map[string]*list.Element
+	now     func() time.Time
+
+	// generation растет при каждой записи и инвалидации, чтобы заполнение
+	// после чтения из Redis не вернуло удаленный за это время заказ
+	generation uint64
+}
+
+type entry struct {
+	key       string
+	order     *domain.Order
+	raw       []byte
+	expiresAt time.Time
+}
+
+// New создает кэш на size записей. ttl <= 0 - записи не устаревают.
+func New(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		entries: list.New(),
+		items:   make(map[string]*list.Element, size),
+		now:     time.Now,
+	}
+}
+
+// Get возвращает заказ, если он есть в кэше и не устарел
+func (c *Cache) Get(key string) (*domain.Order, bool) {
+	e, ok := c.lookup(key)
+	if !ok {
+		return nil, false
+	}
+	if e.order != nil {
+		return e.order, true
+	}
+
+	var order domain.Order
+	if err := json.Unmarshal(e.raw, &order); err != nil {
+		c.Delete(key)
+		return nil, false
+	}
+	c.update(key, e, &entry{key: key, order: &order, raw: e.raw, expiresAt: e.expiresAt})
+	return &order, true
+}
+
+// GetJSON возвращает заказ в JSON, если он есть в кэше и не устарел
+func (c *Cache) GetJSON(key string) ([]byte, bool) {
+	e, ok := c.lookup(key)
+	if !ok {
+		return nil, false
+	}
+	if e.raw != nil {
+		return e.raw, true
+	}
+
+	raw, err := json.Marshal(e.order)
+	if err != nil {
+		return nil, false
+	}
+	c.update(key, e, &entry{key: key, order: e.order, raw: raw, expiresAt: e.expiresAt})
+	return raw, true
+}
+
+// lookup возвращает актуальную запись и отмечает ее как недавно использованную
+func (c *Cache) lookup(key string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !e.expiresAt.IsZero() && c.now().After(e.expiresAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+	c.entries.MoveToFront(el)
+	return e, true
+}
+
+// update заменяет запись дополненной, если за время построения
+// представления ее не перезаписали и не удалили
+func (c *Cache) update(key string, old, updated *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok && el.Value == old {
+		el.Value = updated
+	}
+}
+
+// Generation возвращает текущее поколение кэша для FillIf и FillJSONIf
+func (c *Cache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Set сохраняет заказ, вытесняя давно не использованные записи при переполнении
+func (c *Cache) Set(key string, order *domain.Order) {
+	c.set(key, order, nil, 0, false)
+}
+
+// SetJSON сохраняет заказ в JSON; заказ будет декодирован при первом вызове Get
+func (c *Cache) SetJSON(key string, raw []byte) {
+	c.set(key, nil, raw, 0, false)
+}
+
+// FillIf сохраняет прочитанный из общего кэша заказ, только если с момента
+// получения generation кэш не менялся и не инвалидировался
+func (c *Cache) FillIf(generation uint64, key string, order *domain.Order) bool {
+	return c.set(key, order, nil, generation, true)
+}
+
+// FillJSONIf то же, что FillIf, для заказа в JSON
+func (c *Cache) FillJSONIf(generation uint64, key string, raw []byte) bool {
+	return c.set(key, nil, raw, generation, true)
+}
+
+func (c *Cache) set(key string, order *domain.Order, raw []byte, generation uint64, fill bool) bool {
+	if c.size <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if fill && generation != c.generation {
+		return false
+	}
+	if !fill {
+		c.generation++
+	}
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+
+	e := &entry{key: key, order: order, raw: raw, expiresAt: expiresAt}
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.entries.MoveToFront(el)
+		return true
+	}
+
+	c.items[key] = c.entries.PushFront(e)
+	for c.entries.Len() > c.size {
+		c.removeElement(c.entries.Back())
+	}
+	return true
+}
+
+// Delete удаляет заказы из кэша
+func (c *Cache) Delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.removeElement(el)
+		}
+	}
+}
+
+// Purge очищает кэш
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries.Init()
+	c.items = make(map[string]*list.Element, c.size)
+}
+
+// Len возвращает число записей в кэше
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries.Len()
+}
+
+func (c *Cache) removeElement(el *list.Element) {
+	c.entries.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
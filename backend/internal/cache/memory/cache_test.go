@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_LRU(t *testing.T) {
+	c := New(2, 0)
+	a, b, d := &domain.Order{OrderUID: "a"}, &domain.Order{OrderUID: "b"}, &domain.Order{OrderUID: "d"}
+
+	c.Set("a", a)
+	c.Set("b", b)
+
+	// a становится самым свежим, поэтому вытесняется b
+	got, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Same(t, a, got)
+
+	c.Set("d", d)
+	assert.Equal(t, 2, c.Len())
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	_, ok = c.Get("d")
+	assert.True(t, ok)
+}
+
+func TestCache_TTL(t *testing.T) {
+	c := New(10, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set("a", &domain.Order{OrderUID: "a"})
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Zero(t, c.Len())
+}
+
+func TestCache_DeleteAndPurge(t *testing.T) {
+	c := New(10, 0)
+	c.Set("a", &domain.Order{OrderUID: "a"})
+	c.Set("b", &domain.Order{OrderUID: "b"})
+
+	c.Delete("a", "missing")
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+
+	c.Purge()
+	assert.Zero(t, c.Len())
+}
+
+func TestCache_FillIf(t *testing.T) {
+	c := New(10, 0)
+
+	generation := c.Generation()
+	assert.True(t, c.FillIf(generation, "a", &domain.Order{OrderUID: "a"}))
+	assert.True(t, c.FillJSONIf(generation, "b", []byte(`{"order_uid":"b"}`)))
+
+	// Инвалидация после получения поколения запрещает заполнение даже отсутствующего ключа
+	generation = c.Generation()
+	c.Delete("c")
+	assert.False(t, c.FillIf(generation, "c", &domain.Order{OrderUID: "c"}))
+	_, ok := c.Get("c")
+	assert.False(t, ok)
+
+	// Явная запись тоже новее прочитанной из Redis копии
+	generation = c.Generation()
+	c.Set("a", &domain.Order{OrderUID: "a", TrackNumber: "new"})
+	assert.False(t, c.FillIf(generation, "a", &domain.Order{OrderUID: "a", TrackNumber: "old"}))
+	order, _ := c.Get("a")
+	assert.Equal(t, "new", order.TrackNumber)
+
+	generation = c.Generation()
+	c.Purge()
+	assert.False(t, c.FillJSONIf(generation, "b", []byte(`{"order_uid":"b"}`)))
+	assert.Zero(t, c.Len())
+}
+
+func TestCache_ZeroSize(t *testing.T) {
+	c := New(0, 0)
+	c.Set("a", &domain.Order{OrderUID: "a"})
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+}
+
+func TestCache_JSON(t *testing.T) {
+	c := New(10, 0)
+
+	c.Set("a", &domain.Order{OrderUID: "a"})
+	raw, ok := c.GetJSON("a")
+	assert.True(t, ok)
+	assert.JSONEq(t, `"a"`, string(mustField(t, raw, "order_uid")))
+
+	// Сериализация запоминается
+	again, _ := c.GetJSON("a")
+	assert.Same(t, &raw[0], &again[0])
+
+	c.SetJSON("b", []byte(`{"order_uid":"b"}`))
+	order, ok := c.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, "b", order.OrderUID)
+	cached, _ := c.Get("b")
+	assert.Same(t, order, cached)
+
+	c.SetJSON("broken", []byte(`{`))
+	_, ok = c.Get("broken")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+}
+
+func mustField(t *testing.T, raw []byte, name string) json.RawMessage {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(raw, &fields))
+	return fields[name]
+}
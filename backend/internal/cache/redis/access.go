@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// accessKeyPrefix префикс дневных sorted set'ов с числом обращений к заказам
+	// (member - UID, score - число обращений за день). Хеш-тег держит все корзины
+	// в одном слоте кластера, иначе их нельзя объединить через ZUNIONSTORE.
+	accessKeyPrefix = "{order_access}:"
+	// accessTopKey временный ключ для объединения корзин в TopAccessed
+	accessTopKey = accessKeyPrefix + "top"
+	// accessBuckets число учитываемых дней: более старые корзины удаляются по TTL
+	accessBuckets = 7
+	accessBucket  = 24 * time.Hour
+)
+
+// accessBucketKey возвращает ключ корзины обращений за день t
+func accessBucketKey(t time.Time) string {
+	return accessKeyPrefix + t.UTC().Format("20060102")
+}
+
+// RecordAccess увеличивает счетчик обращений к заказу за текущий день
+func (c *Cache) RecordAccess(ctx context.Context, uid string) {
+	c.recordAccessAt(ctx, uid, time.Now())
+}
+
+func (c *Cache) recordAccessAt(ctx context.Context, uid string, now time.Time) {
+	if !c.allow() {
+		return
+	}
+	key := accessBucketKey(now)
+	pipe := c.client.Pipeline()
+	pipe.ZIncrBy(ctx, key, 1, uid)
+	pipe.Expire(ctx, key, accessBuckets*accessBucket)
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.observe(err)
+		c.logger.Error("Failed to record order access",
+			slog.String("uid", uid),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// TopAccessed возвращает UID k наиболее часто запрашиваемых заказов. Обращения
+// за последние accessBuckets дней суммируются с весом, убывающим вдвое за каждый день,
+// поэтому недавно ставшие популярными заказы вытесняют давно не запрашиваемые.
+func (c *Cache) TopAccessed(ctx context.Context, k int) ([]string, error) {
+	return c.topAccessedAt(ctx, k, time.Now())
+}
+
+func (c *Cache) topAccessedAt(ctx context.Context, k int, now time.Time) ([]string, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	if !c.allow() {
+		return nil, ErrUnavailable
+	}
+
+	store := &redis.ZStore{Aggregate: "SUM"}
+	for day := 0; day < accessBuckets; day++ {
+		store.Keys = append(store.Keys, accessBucketKey(now.Add(-time.Duration(day)*accessBucket)))
+		store.Weights = append(store.Weights, math.Pow(0.5, float64(day)))
+	}
+
+	// Объединение, чтение и удаление в одной транзакции: реплики не мешают друг другу
+	var top *redis.StringSliceCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(ctx, accessTopKey, store)
+		top = pipe.ZRevRange(ctx, accessTopKey, 0, int64(k-1))
+		pipe.Del(ctx, accessTopKey)
+		return nil
+	})
+	c.observe(err)
+	if err != nil {
+		return nil, err
+	}
+	return top.Val(), nil
+}
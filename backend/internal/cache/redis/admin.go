@@ -0,0 +1,271 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Ravwvil/order-service/backend/internal/cache/codec"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// scanCount подсказка Redis о числе ключей, возвращаемых одним вызовом SCAN
+const scanCount = 1000
+
+// Inspect возвращает запись заказа в том виде, в котором она хранится в Redis, и ее TTL
+func (c *Cache) Inspect(ctx context.Context, key string) (*domain.CacheEntry, bool, error) {
+	if !c.allow() {
+		return nil, false, ErrUnavailable
+	}
+
+	redisKey := c.prefix + key
+	pipe := c.client.Pipeline()
+	getCmd := pipe.Get(ctx, redisKey)
+	ttlCmd := pipe.PTTL(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		c.observe(err)
+		return nil, false, fmt.Errorf("failed to inspect cache entry: %w", err)
+	}
+
+	record, err := getCmd.Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to inspect cache entry: %w", err)
+	}
+
+	entry := &domain.CacheEntry{
+		Key:        redisKey,
+		Format:     codec.Describe(record),
+		Size:       len(record),
+		TTLSeconds: -1,
+	}
+	if ttl := ttlCmd.Val(); ttl > 0 {
+		entry.TTLSeconds = int64(ttl.Seconds())
+	}
+	if data, err := codec.DecodeJSON(record); err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Order = data
+	}
+	return entry, true, nil
+}
+
+// flushPatterns шаблоны ключей, удаляемых при очистке кэша: заказы всех версий схемы и их индексы
+var flushPatterns = []string{legacyPattern, versionedPattern, customerPrefix + "*", trackPrefix + "*"}
+
+// Flush удаляет все заказы (всех версий схемы) и их индексы из Redis и возвращает число удаленных ключей.
+// Ключи перебираются через SCAN, чтобы не блокировать сервер, как KEYS;
+// в кластере обходятся все мастера.
+func (c *Cache) Flush(ctx context.Context) (int64, error) {
+	if !c.allow() {
+		return 0, ErrUnavailable
+	}
+	return c.flush(ctx, flushPatterns)
+}
+
+// flush удаляет ключи по шаблонам, не проверяя выключатель
+func (c *Cache) flush(ctx context.Context, patterns []string) (int64, error) {
+	var deleted atomic.Int64
+	err := c.forEachNode(ctx, func(ctx context.Context, node redis.Cmdable) error {
+		for _, pattern := range patterns {
+			err := scanKeys(ctx, node, pattern, func(keys []string) error {
+				return unlink(ctx, node, keys, &deleted)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.observe(err)
+		return deleted.Load(), fmt.Errorf("failed to flush cache: %w", err)
+	}
+	return deleted.Load(), nil
+}
+
+// unlink удаляет ключи узла и добавляет число удаленных к deleted
+func unlink(ctx context.Context, node redis.Cmdable, keys []string, deleted *atomic.Int64) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// Ключи пачки могут относиться к разным слотам, поэтому удаляются по одному в pipeline
+	pipe := node.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Unlink(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		deleted.Add(cmd.Val())
+	}
+	return nil
+}
+
+// Stats возвращает число заказов в Redis, занятую память и долю попаданий.
+// Число ключей считается через SCAN и может быть неточным при одновременных изменениях.
+func (c *Cache) Stats(ctx context.Context) (*domain.CacheStats, error) {
+	if !c.allow() {
+		return nil, ErrUnavailable
+	}
+
+	stats := &domain.CacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		SchemaVersion: c.version,
+		Upgraded:      c.upgraded.Load(),
+	}
+	var mu sync.Mutex
+	err := c.forEachNode(ctx, func(ctx context.Context, node redis.Cmdable) error {
+		var keys int64
+		err := scanKeys(ctx, node, c.prefix+"*", func(batch []string) error {
+			keys += int64(len(batch))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		info, err := node.Info(ctx).Result()
+		if err != nil {
+			return err
+		}
+		fields := parseInfo(info)
+
+		mu.Lock()
+		defer mu.Unlock()
+		stats.Keys += keys
+		stats.UsedMemory += fields["used_memory"]
+		stats.ServerHits += fields["keyspace_hits"]
+		stats.ServerMisses += fields["keyspace_misses"]
+		return nil
+	})
+	if err != nil {
+		c.observe(err)
+		return nil, fmt.Errorf("failed to collect cache stats: %w", err)
+	}
+
+	stats.HitRatio = domain.HitRatio(stats.Hits, stats.Misses)
+	stats.ServerHitRatio = domain.HitRatio(stats.ServerHits, stats.ServerMisses)
+	return stats, nil
+}
+
+// ScanKeys перебирает ключи заказов (без префикса order:) пачками.
+// В кластере мастера обходятся параллельно, но fn вызывается последовательно.
+func (c *Cache) ScanKeys(ctx context.Context, fn func(keys []string) error) error {
+	if !c.allow() {
+		return ErrUnavailable
+	}
+
+	var mu sync.Mutex
+	err := c.forEachNode(ctx, func(ctx context.Context, node redis.Cmdable) error {
+		return scanKeys(ctx, node, c.prefix+"*", func(redisKeys []string) error {
+			keys := make([]string, len(redisKeys))
+			for i, redisKey := range redisKeys {
+				keys[i] = strings.TrimPrefix(redisKey, c.prefix)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(keys)
+		})
+	})
+	if err != nil {
+		c.observe(err)
+		return fmt.Errorf("failed to scan cache keys: %w", err)
+	}
+	return nil
+}
+
+// Entries читает заказы по ключам, не влияя на счетчики попаданий.
+// Отсутствующие ключи в результат не попадают, нечитаемые записи возвращаются как nil.
+func (c *Cache) Entries(ctx context.Context, keys []string) (map[string]*domain.Order, error) {
+	if len(keys) == 0 {
+		return map[string]*domain.Order{}, nil
+	}
+	if !c.allow() {
+		return nil, ErrUnavailable
+	}
+
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, c.prefix+key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		c.observe(err)
+		return nil, fmt.Errorf("failed to read cache entries: %w", err)
+	}
+
+	entries := make(map[string]*domain.Order, len(keys))
+	for i, cmd := range cmds {
+		record, err := cmd.Bytes()
+		if err != nil {
+			continue
+		}
+		order, err := codec.Decode(record)
+		if err != nil {
+			entries[keys[i]] = nil
+			continue
+		}
+		entries[keys[i]] = order
+	}
+	return entries, nil
+}
+
+// forEachNode вызывает fn для сервера Redis, а в режиме кластера - для каждого мастера
+func (c *Cache) forEachNode(ctx context.Context, fn func(ctx context.Context, node redis.Cmdable) error) error {
+	if cluster, ok := c.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return fn(ctx, node)
+		})
+	}
+	return fn(ctx, c.client)
+}
+
+// scanKeys перебирает ключи по шаблону и передает их в fn пачками
+func scanKeys(ctx context.Context, node redis.Cmdable, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := node.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// parseInfo извлекает целочисленные поля из ответа INFO
+func parseInfo(info string) map[string]int64 {
+	fields := make(map[string]int64)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || strings.HasPrefix(name, "#") {
+			continue
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			fields[name] = n
+		}
+	}
+	return fields
+}
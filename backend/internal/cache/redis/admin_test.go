@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Inspect(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+	redisCache.Set(ctx, "inspect", order)
+
+	entry, found, err := redisCache.Inspect(ctx, "inspect")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, redisCache.prefix+"inspect", entry.Key)
+	assert.Equal(t, "json", entry.Format)
+	assert.Positive(t, entry.Size)
+	assert.InDelta(t, time.Hour.Seconds(), entry.TTLSeconds, 5)
+	assert.Contains(t, string(entry.Order), order.OrderUID)
+	assert.Empty(t, entry.Error)
+
+	require.NoError(t, redisClient.Set(ctx, redisCache.prefix+"broken", []byte{0x7f, 1}, 0).Err())
+	entry, found, err = redisCache.Inspect(ctx, "broken")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, int64(-1), entry.TTLSeconds)
+	assert.NotEmpty(t, entry.Error)
+
+	_, found, err = redisCache.Inspect(ctx, "absent")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestCache_FlushAndStats(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+
+	_, err := redisCache.Flush(ctx)
+	require.NoError(t, err)
+
+	orders := make(map[string]*domain.Order)
+	for i := 0; i < 2500; i++ {
+		orders[fmt.Sprintf("flush-%d", i)] = order
+	}
+	redisCache.LoadFromDB(ctx, orders)
+	redisCache.SetMissing(ctx, "flush-missing", time.Minute)
+
+	_, _ = redisCache.Get(ctx, "flush-1")
+	_, _ = redisCache.Get(ctx, "flush-absent")
+
+	stats, err := redisCache.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2500), stats.Keys)
+	assert.Positive(t, stats.UsedMemory)
+	assert.Positive(t, stats.Hits)
+	assert.Positive(t, stats.Misses)
+	assert.Greater(t, stats.HitRatio, 0.0)
+	assert.Less(t, stats.HitRatio, 1.0)
+
+	deleted, err := redisCache.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2500), deleted)
+
+	_, found := redisCache.Get(ctx, "flush-1")
+	assert.False(t, found)
+	// Ключи отрицательного кэша не относятся к order:* и не удаляются
+	assert.True(t, redisCache.IsMissing(ctx, "flush-missing"))
+}
+
+func TestParseInfo(t *testing.T) {
+	info := "# Memory\r\nused_memory:1024\r\nused_memory_human:1.00K\r\n\r\n# Stats\r\nkeyspace_hits:7\r\nkeyspace_misses:3\r\n"
+	fields := parseInfo(info)
+	assert.Equal(t, int64(1024), fields["used_memory"])
+	assert.Equal(t, int64(7), fields["keyspace_hits"])
+	assert.Equal(t, int64(3), fields["keyspace_misses"])
+	assert.NotContains(t, fields, "used_memory_human")
+}
+
+func TestCache_ScanKeysAndEntries(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+
+	_, err := redisCache.Flush(ctx)
+	require.NoError(t, err)
+	redisCache.LoadFromDB(ctx, map[string]*domain.Order{"scan-1": order, "scan-2": order})
+	require.NoError(t, redisClient.Set(ctx, redisCache.prefix+"scan-broken", []byte{0x7f}, time.Hour).Err())
+
+	var keys []string
+	require.NoError(t, redisCache.ScanKeys(ctx, func(batch []string) error {
+		keys = append(keys, batch...)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"scan-1", "scan-2", "scan-broken"}, keys)
+
+	hits := redisCache.hits.Load()
+	entries, err := redisCache.Entries(ctx, []string{"scan-1", "scan-broken", "scan-absent"})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, order.OrderUID, entries["scan-1"].OrderUID)
+	assert.Nil(t, entries["scan-broken"])
+	assert.Equal(t, hits, redisCache.hits.Load())
+}
@@ -5,37 +5,93 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
+	"github.com/Ravwvil/order-service/backend/internal/cache/codec"
 	"github.com/Ravwvil/order-service/backend/internal/domain"
 	"github.com/redis/go-redis/v9"
 )
 
 // Cache Redis для заказов
 type Cache struct {
-	client *redis.Client
-	ttl    time.Duration
-	logger *slog.Logger
+	client  redis.UniversalClient
+	ttl     time.Duration
+	codec   *codec.Codec
+	breaker Breaker
+	logger  *slog.Logger
+
+	// version версия схемы заказов, prefix - префикс ключей ее записей (см. keyPrefix)
+	version  int
+	prefix   string
+	fallback bool
+
+	hits     atomic.Int64
+	misses   atomic.Int64
+	upgraded atomic.Int64
+
+	pending pendingInvalidations
+}
+
+// Breaker автоматический выключатель обращений к Redis (breaker.Breaker)
+type Breaker interface {
+	Allow() bool
+	Available() bool
+	Failure(err error)
 }
 
-// New создает новый экземпляр Redis кэша
-func New(addr, password string, db int, ttl time.Duration, logger *slog.Logger) *Cache {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       db,
-	})
+// ErrUnavailable возвращается, пока выключатель не пропускает обращения к Redis
+var ErrUnavailable = errors.New("redis is unavailable")
 
+// New создает Redis кэш поверх общего клиента (см. NewClient).
+// Чтение записей предыдущей версии схемы выключено (см. SetSchemaFallback).
+func New(client redis.UniversalClient, ttl time.Duration, logger *slog.Logger) *Cache {
 	return &Cache{
-		client: rdb,
-		ttl:    ttl,
-		logger: logger,
+		client:  client,
+		ttl:     ttl,
+		codec:   codec.JSON,
+		logger:  logger,
+		version: domain.OrderSchemaVersion,
+		prefix:  keyPrefix(domain.OrderSchemaVersion),
+	}
+}
+
+// SetCodec задает формат, в котором сохраняются новые записи.
+// Записи в прежнем формате продолжают читаться до истечения их TTL.
+func (c *Cache) SetCodec(orderCodec *codec.Codec) {
+	c.codec = orderCodec
+}
+
+// SetBreaker включает автоматический выключатель: пока Redis недоступен,
+// обращения к нему пропускаются, а чтения считаются промахами
+func (c *Cache) SetBreaker(b Breaker) {
+	c.breaker = b
+}
+
+// allow сообщает, можно ли обращаться к Redis
+func (c *Cache) allow() bool {
+	return c.breaker == nil || c.breaker.Allow()
+}
+
+// available сообщает, замкнут ли выключатель, не учитывая обращение как пропущенное
+func (c *Cache) available() bool {
+	return c.breaker == nil || c.breaker.Available()
+}
+
+// observe передает выключателю ошибку обращения к Redis
+func (c *Cache) observe(err error) {
+	if c.breaker != nil && err != nil && !errors.Is(err, redis.Nil) {
+		c.breaker.Failure(err)
 	}
 }
 
-// Set сохраняет заказ в кэше
+// Set сохраняет заказ в кэше и обновляет индексы по покупателю и трек-номеру
 func (c *Cache) Set(ctx context.Context, key string, order *domain.Order) {
-	data, err := json.Marshal(order)
+	if !c.allow() {
+		return
+	}
+
+	data, err := c.codec.Encode(order)
 	if err != nil {
 		c.logger.Error("Failed to marshal order for Redis cache",
 			slog.String("key", key),
@@ -44,8 +100,11 @@ func (c *Cache) Set(ctx context.Context, key string, order *domain.Order) {
 		return
 	}
 
-	err = c.client.Set(ctx, "order:"+key, data, c.ttl).Err()
-	if err != nil {
+	pipe := c.client.Pipeline()
+	pipe.Set(ctx, c.prefix+key, data, c.ttl)
+	c.addIndexes(ctx, pipe, key, order)
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.observe(err)
 		c.logger.Error("Failed to set order in Redis cache",
 			slog.String("key", key),
 			slog.Any("error", err),
@@ -59,13 +118,23 @@ func (c *Cache) Set(ctx context.Context, key string, order *domain.Order) {
 
 // Get получает заказ из кэша
 func (c *Cache) Get(ctx context.Context, key string) (*domain.Order, bool) {
-	data, err := c.client.Get(ctx, "order:"+key).Result()
+	if !c.allow() {
+		return nil, false
+	}
+
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
+			if order, ok := c.getPrevious(ctx, key); ok {
+				c.hits.Add(1)
+				return order, true
+			}
+			c.misses.Add(1)
 			c.logger.Debug("Order not found in Redis cache",
 				slog.String("key", key),
 			)
 		} else {
+			c.observe(err)
 			c.logger.Error("Failed to get order from Redis cache",
 				slog.String("key", key),
 				slog.Any("error", err),
@@ -74,8 +143,8 @@ func (c *Cache) Get(ctx context.Context, key string) (*domain.Order, bool) {
 		return nil, false
 	}
 
-	var order domain.Order
-	if err := json.Unmarshal([]byte(data), &order); err != nil {
+	order, err := codec.Decode(data)
+	if err != nil {
 		c.logger.Error("Failed to unmarshal order from Redis cache",
 			slog.String("key", key),
 			slog.Any("error", err),
@@ -83,25 +152,82 @@ func (c *Cache) Get(ctx context.Context, key string) (*domain.Order, bool) {
 		return nil, false
 	}
 
+	c.hits.Add(1)
 	c.logger.Debug("Order retrieved from Redis cache",
 		slog.String("key", key),
 	)
-	return &order, true
+	return order, true
 }
 
-// LoadFromDB загружает данные из БД в кэш
+// GetJSON получает заказ из кэша в JSON. Записи в формате JSON
+// возвращаются без декодирования.
+func (c *Cache) GetJSON(ctx context.Context, key string) ([]byte, bool) {
+	if !c.allow() {
+		return nil, false
+	}
+
+	record, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			if order, ok := c.getPrevious(ctx, key); ok {
+				if data, err := json.Marshal(order); err == nil {
+					c.hits.Add(1)
+					return data, true
+				}
+			}
+			c.misses.Add(1)
+		} else {
+			c.observe(err)
+			c.logger.Error("Failed to get order from Redis cache",
+				slog.String("key", key),
+				slog.Any("error", err),
+			)
+		}
+		return nil, false
+	}
+
+	data, err := codec.DecodeJSON(record)
+	if err != nil {
+		c.logger.Error("Failed to unmarshal order from Redis cache",
+			slog.String("key", key),
+			slog.Any("error", err),
+		)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return data, true
+}
+
+// pipelineChunkSize максимальное число команд в одном Redis pipeline
+const pipelineChunkSize = 500
+
+// LoadFromDB загружает данные из БД в кэш пачками по pipelineChunkSize
 func (c *Cache) LoadFromDB(ctx context.Context, orders map[string]*domain.Order) {
-	c.logger.Info("Loading orders from database to Redis cache",
+	c.logger.Debug("Loading orders from database to Redis cache",
 		slog.Int("count", len(orders)),
 	)
 
-	if len(orders) == 0 {
+	if len(orders) == 0 || !c.allow() {
 		return
 	}
 
 	pipe := c.client.Pipeline()
+	flush := func() bool {
+		if pipe.Len() == 0 {
+			return true
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			c.observe(err)
+			c.logger.Error("Failed to execute Redis pipeline",
+				slog.Any("error", err),
+			)
+			return ctx.Err() == nil && c.allow()
+		}
+		return true
+	}
+
 	for key, order := range orders {
-		data, err := json.Marshal(order)
+		data, err := c.codec.Encode(order)
 		if err != nil {
 			c.logger.Error("Failed to marshal order for Redis cache",
 				slog.String("key", key),
@@ -109,21 +235,72 @@ func (c *Cache) LoadFromDB(ctx context.Context, orders map[string]*domain.Order)
 			)
 			continue
 		}
-		pipe.Set(ctx, "order:"+key, data, c.ttl)
+		pipe.Set(ctx, c.prefix+key, data, c.ttl)
+		c.addIndexes(ctx, pipe, key, order)
+
+		if pipe.Len() >= pipelineChunkSize && !flush() {
+			return
+		}
 	}
+	flush()
 
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		c.logger.Error("Failed to execute Redis pipeline",
+	c.logger.Debug("Finished loading orders from database to Redis cache",
+		slog.Int("count", len(orders)),
+	)
+}
+
+// Delete удаляет заказы из кэша вместе с их индексами. Записи предыдущей версии
+// схемы тоже удаляются, иначе они снова попали бы в кэш при чтении.
+// Пока выключатель разомкнут, удаление откладывается до восстановления Redis (см. ReplayPending).
+func (c *Cache) Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	if !c.allow() {
+		c.pending.addKeys(keys)
+		// Выключатель мог замкнуться сразу после применения отложенных удалений
+		if !c.available() {
+			return
+		}
+	}
+
+	if err := c.delete(ctx, keys); err != nil {
+		// Ошибка может быть первой перед размыканием выключателя: удаление повторится вместе с отложенными
+		c.pending.addKeys(keys)
+		c.logger.Error("Failed to delete orders from Redis cache",
+			slog.Int("count", len(keys)),
 			slog.Any("error", err),
 		)
+		return
 	}
 
-	c.logger.Info("Finished loading orders from database to Redis cache",
-		slog.Int("count", len(orders)),
+	c.logger.Debug("Orders deleted from Redis cache",
+		slog.Int("count", len(keys)),
 	)
 }
 
+// delete удаляет заказы и их индексы, не проверяя выключатель
+func (c *Cache) delete(ctx context.Context, keys []string) error {
+	orders := c.indexedOrders(ctx, keys)
+
+	// Ключи могут относиться к разным слотам кластера, поэтому удаляются по одному в pipeline
+	pipe := c.client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, c.prefix+key)
+		if c.fallback {
+			pipe.Del(ctx, keyPrefix(c.version-1)+key)
+		}
+		if order, ok := orders[key]; ok {
+			dropIndexes(ctx, pipe, key, order)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.observe(err)
+		return err
+	}
+	return nil
+}
+
 // Close закрывает соединение
 func (c *Cache) Close() error {
 	return c.client.Close()
@@ -6,12 +6,17 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/Ravwvil/order-service/backend/internal/cache/codec"
+	"github.com/Ravwvil/order-service/backend/internal/cache/invalidation"
+	"github.com/Ravwvil/order-service/backend/internal/config"
 	"github.com/Ravwvil/order-service/backend/internal/domain"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
@@ -71,7 +76,7 @@ func TestMain(m *testing.M) {
 	}
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	redisCache = New(endpoint, "", 0, 1*time.Hour, logger)
+	redisCache = New(redisClient, 1*time.Hour, logger)
 
 	code := m.Run()
 
@@ -84,7 +89,7 @@ func TestCache_SetAndGet(t *testing.T) {
 	order := loadOrderFromJSON(t, "testdata/valid_order.json")
 	key := order.OrderUID
 
-	redisClient.Del(ctx, "order:"+key)
+	redisClient.Del(ctx, redisCache.prefix+key)
 
 	t.Run("Get miss", func(t *testing.T) {
 		result, found := redisCache.Get(ctx, key)
@@ -103,7 +108,7 @@ func TestCache_SetAndGet(t *testing.T) {
 		actualJSON, _ := json.Marshal(cachedOrder)
 		assert.JSONEq(t, string(expectedJSON), string(actualJSON))
 
-		ttl := redisClient.TTL(ctx, "order:"+key).Val()
+		ttl := redisClient.TTL(ctx, redisCache.prefix+key).Val()
 		assert.Greater(t, ttl.Seconds(), float64(0))
 		assert.LessOrEqual(t, ttl.Seconds(), float64(3600))
 	})
@@ -121,7 +126,7 @@ func TestCache_LoadFromDB(t *testing.T) {
 		order2.OrderUID: order2,
 	}
 
-	redisClient.Del(ctx, "order:"+order1.OrderUID, "order:"+order2.OrderUID)
+	redisClient.Del(ctx, redisCache.prefix+order1.OrderUID, redisCache.prefix+order2.OrderUID)
 
 	redisCache.LoadFromDB(ctx, ordersMap)
 
@@ -146,11 +151,29 @@ func TestCache_LoadFromDB(t *testing.T) {
 	})
 }
 
+// TestCache_Delete тестирует удаление заказов из кэша.
+func TestCache_Delete(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+	key := order.OrderUID
+
+	redisCache.Set(ctx, key, order)
+	_, found := redisCache.Get(ctx, key)
+	assert.True(t, found)
+
+	redisCache.Delete(ctx, key, "missing-key")
+
+	_, found = redisCache.Get(ctx, key)
+	assert.False(t, found)
+}
+
 // TestCache_Close тестирует метод Close кэша.
 func TestCache_Close(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	tempCache := New("localhost:6379", "", 0, 1*time.Hour, logger)
-	err := tempCache.Close()
+	client, err := NewClient(config.RedisConfig{Addrs: []string{"localhost:6379"}})
+	require.NoError(t, err)
+	tempCache := New(client, 1*time.Hour, logger)
+	err = tempCache.Close()
 	assert.NoError(t, err)
 }
 
@@ -159,10 +182,303 @@ func TestCache_Get_UnmarshalError(t *testing.T) {
 	ctx := context.Background()
 	key := "unmarshal-error-key"
 
-	err := redisClient.Set(ctx, "order:"+key, "{invalid-json}", 1*time.Hour).Err()
+	err := redisClient.Set(ctx, redisCache.prefix+key, "{invalid-json}", 1*time.Hour).Err()
 	assert.NoError(t, err)
 
 	order, found := redisCache.Get(ctx, key)
 	assert.False(t, found)
 	assert.Nil(t, order)
 }
+
+// TestCache_AccessTracking тестирует учет обращений к заказам.
+func TestCache_AccessTracking(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	for day := 0; day <= accessBuckets; day++ {
+		redisClient.Del(ctx, accessBucketKey(now.Add(-time.Duration(day)*accessBucket)))
+	}
+
+	for i := 0; i < 3; i++ {
+		redisCache.RecordAccess(ctx, "popular")
+	}
+	redisCache.RecordAccess(ctx, "rare")
+	redisCache.RecordAccess(ctx, "popular-2")
+	redisCache.RecordAccess(ctx, "popular-2")
+
+	top, err := redisCache.TopAccessed(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"popular", "popular-2"}, top)
+
+	top, err = redisCache.TopAccessed(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, top)
+
+	t.Run("old accesses decay", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			redisCache.recordAccessAt(ctx, "stale", now.Add(-4*accessBucket))
+		}
+		redisCache.recordAccessAt(ctx, "fresh", now)
+		redisCache.recordAccessAt(ctx, "fresh", now)
+
+		top, err := redisCache.TopAccessed(ctx, 10)
+		require.NoError(t, err)
+		assert.Less(t, slices.Index(top, "fresh"), slices.Index(top, "stale"))
+
+		ttl, err := redisClient.TTL(ctx, accessBucketKey(now)).Result()
+		require.NoError(t, err)
+		assert.Positive(t, ttl)
+	})
+}
+
+// TestInvalidations тестирует рассылку инвалидаций через pub/sub.
+func TestInvalidations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	invalidations := NewInvalidations(redisClient, "test_invalidations", logger)
+
+	received := make(chan invalidation.Message, 2)
+	ready := make(chan struct{})
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- invalidations.Subscribe(ctx, func() { close(ready) }, func(msg invalidation.Message) {
+			received <- msg
+		})
+	}()
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription was not confirmed")
+	}
+
+	require.NoError(t, invalidations.Publish(ctx, invalidation.Message{Source: "replica-1", Keys: []string{"uid-1", "uid-2"}}))
+	require.NoError(t, invalidations.Publish(ctx, invalidation.Message{Source: "replica-1", Flush: true}))
+
+	for _, expected := range []invalidation.Message{
+		{Source: "replica-1", Keys: []string{"uid-1", "uid-2"}},
+		{Source: "replica-1", Flush: true},
+	} {
+		select {
+		case msg := <-received:
+			assert.Equal(t, expected, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("invalidation was not received")
+		}
+	}
+
+	cancel()
+	assert.NoError(t, <-subscribed)
+}
+
+// TestInvalidations_Reconnect тестирует повторное подтверждение подписки после разрыва соединения.
+func TestInvalidations_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	invalidations := NewInvalidations(redisClient, "test_invalidations_reconnect", logger)
+
+	received := make(chan invalidation.Message, 1)
+	ready := make(chan struct{}, 2)
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- invalidations.Subscribe(ctx, func() { ready <- struct{}{} }, func(msg invalidation.Message) {
+			received <- msg
+		})
+	}()
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription was not confirmed")
+	}
+
+	// Обрываем соединение подписчика на стороне Redis, клиент переподключается сам
+	require.NoError(t, redisClient.Do(ctx, "CLIENT", "KILL", "TYPE", "pubsub").Err())
+
+	select {
+	case <-ready:
+	case <-time.After(10 * time.Second):
+		t.Fatal("reconnect was not reported")
+	}
+
+	require.NoError(t, invalidations.Publish(ctx, invalidation.Message{Source: "replica-1", Keys: []string{"uid-1"}}))
+	select {
+	case msg := <-received:
+		assert.Equal(t, []string{"uid-1"}, msg.Keys)
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalidation was not received after reconnect")
+	}
+
+	cancel()
+	assert.NoError(t, <-subscribed)
+}
+
+// TestCache_Missing тестирует отрицательный кэш.
+func TestCache_Missing(t *testing.T) {
+	ctx := context.Background()
+
+	assert.False(t, redisCache.IsMissing(ctx, "ghost"))
+
+	redisCache.SetMissing(ctx, "ghost", time.Minute)
+	assert.True(t, redisCache.IsMissing(ctx, "ghost"))
+	_, found := redisCache.Get(ctx, "ghost")
+	assert.False(t, found)
+
+	redisCache.ClearMissing(ctx, "ghost")
+	assert.False(t, redisCache.IsMissing(ctx, "ghost"))
+}
+
+// TestCache_SwitchCodec тестирует чтение записей, сохраненных в разных форматах.
+func TestCache_SwitchCodec(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+	defer redisCache.SetCodec(codec.JSON)
+
+	legacy, _ := json.Marshal(order)
+	assert.NoError(t, redisClient.Set(ctx, redisCache.prefix+"legacy", legacy, time.Hour).Err())
+
+	msgpackCodec, err := codec.Parse("msgpack+zstd")
+	assert.NoError(t, err)
+	redisCache.SetCodec(msgpackCodec)
+	redisCache.Set(ctx, "packed", order)
+
+	protoCodec, err := codec.Parse("protobuf")
+	assert.NoError(t, err)
+	redisCache.SetCodec(protoCodec)
+	redisCache.Set(ctx, "proto", order)
+
+	for _, key := range []string{"legacy", "packed", "proto"} {
+		cachedOrder, found := redisCache.Get(ctx, key)
+		assert.True(t, found, key)
+		if assert.NotNil(t, cachedOrder, key) {
+			assert.Equal(t, order.OrderUID, cachedOrder.OrderUID, key)
+			assert.Equal(t, order.Items, cachedOrder.Items, key)
+		}
+	}
+}
+
+// TestCache_GetJSON тестирует получение заказа из кэша в JSON.
+func TestCache_GetJSON(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+	expected, _ := json.Marshal(order)
+
+	redisCache.Set(ctx, "raw", order)
+	data, found := redisCache.GetJSON(ctx, "raw")
+	assert.True(t, found)
+	assert.JSONEq(t, string(expected), string(data))
+
+	_, found = redisCache.GetJSON(ctx, "raw-missing")
+	assert.False(t, found)
+}
+
+// stubBreaker выключатель с управляемым состоянием.
+type stubBreaker struct {
+	open     bool
+	failures int
+}
+
+func (b *stubBreaker) Allow() bool       { return !b.open }
+func (b *stubBreaker) Available() bool   { return !b.open }
+func (b *stubBreaker) Failure(err error) { b.failures++ }
+
+// TestCache_Breaker тестирует работу кэша через выключатель при недоступном Redis.
+func TestCache_Breaker(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	client, err := NewClient(config.RedisConfig{Addrs: []string{"127.0.0.1:1"}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	unavailable := New(client, time.Hour, logger)
+	b := &stubBreaker{}
+	unavailable.SetBreaker(b)
+
+	_, found := unavailable.Get(ctx, order.OrderUID)
+	assert.False(t, found)
+	unavailable.Set(ctx, order.OrderUID, order)
+	assert.Equal(t, 2, b.failures)
+
+	// Разомкнутый выключатель не пропускает обращения к Redis
+	b.open = true
+	_, found = unavailable.Get(ctx, order.OrderUID)
+	assert.False(t, found)
+	unavailable.Set(ctx, order.OrderUID, order)
+	assert.False(t, unavailable.IsMissing(ctx, order.OrderUID))
+	_, err = unavailable.TopAccessed(ctx, 10)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 2, b.failures)
+}
+
+// TestCache_PendingInvalidations тестирует удаления, отложенные при разомкнутом выключателе.
+func TestCache_PendingInvalidations(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	c := New(redisClient, time.Hour, logger)
+	b := &stubBreaker{}
+	c.SetBreaker(b)
+
+	c.Set(ctx, "pending-order", order)
+	c.SetMissing(ctx, "pending-missing", time.Hour)
+
+	// Удаления во время недоступности не теряются, а откладываются
+	b.open = true
+	c.Delete(ctx, "pending-order")
+	c.ClearMissing(ctx, "pending-missing")
+	b.open = false
+
+	_, found := c.Get(ctx, "pending-order")
+	assert.True(t, found)
+	assert.True(t, c.IsMissing(ctx, "pending-missing"))
+
+	require.NoError(t, c.ReplayPending(ctx))
+	_, found = c.Get(ctx, "pending-order")
+	assert.False(t, found)
+	assert.False(t, c.IsMissing(ctx, "pending-missing"))
+
+	t.Run("overflow flushes cache", func(t *testing.T) {
+		c.Set(ctx, "pending-order", order)
+		c.SetMissing(ctx, "pending-missing", time.Hour)
+		c.pending.restore(nil, nil, true)
+
+		require.NoError(t, c.ReplayPending(ctx))
+		_, found := c.Get(ctx, "pending-order")
+		assert.False(t, found)
+		assert.False(t, c.IsMissing(ctx, "pending-missing"))
+	})
+
+	t.Run("failed replay is kept", func(t *testing.T) {
+		client, err := NewClient(config.RedisConfig{Addrs: []string{"127.0.0.1:1"}})
+		require.NoError(t, err)
+		defer client.Close()
+
+		unavailable := New(client, time.Hour, logger)
+		unavailable.SetBreaker(&stubBreaker{open: true})
+		unavailable.Delete(ctx, "pending-order")
+
+		assert.Error(t, unavailable.ReplayPending(ctx))
+		keys, _, _ := unavailable.pending.take()
+		assert.Equal(t, []string{"pending-order"}, keys)
+	})
+	t.Run("failed delete with closed breaker is kept", func(t *testing.T) {
+		client, err := NewClient(config.RedisConfig{Addrs: []string{"127.0.0.1:1"}})
+		require.NoError(t, err)
+		defer client.Close()
+
+		unavailable := New(client, time.Hour, logger)
+		unavailable.SetBreaker(&stubBreaker{})
+		unavailable.Delete(ctx, "pending-order")
+		unavailable.ClearMissing(ctx, "pending-missing")
+
+		keys, missing, _ := unavailable.pending.take()
+		assert.Equal(t, []string{"pending-order"}, keys)
+		assert.Equal(t, []string{"pending-missing"}, missing)
+	})
+}
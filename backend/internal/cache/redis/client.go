@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Ravwvil/order-service/backend/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewClient создает клиент Redis для одиночного узла, sentinel или кластера.
+// Один клиент используется кэшем, инвалидациями и проверкой здоровья.
+func NewClient(cfg config.RedisConfig) (redis.UniversalClient, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("redis address is required")
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addrs,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelUsername: cfg.SentinelUsername,
+		SentinelPassword: cfg.SentinelPassword,
+		DB:               cfg.DB,
+	}
+
+	switch cfg.Mode {
+	case config.RedisModeStandalone, "":
+		if len(cfg.Addrs) > 1 {
+			return nil, errors.New("standalone redis mode accepts a single address")
+		}
+	case config.RedisModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, errors.New("sentinel master name is required")
+		}
+		opts.MasterName = cfg.MasterName
+	case config.RedisModeCluster:
+		if cfg.DB != 0 {
+			return nil, errors.New("redis cluster supports only database 0")
+		}
+		opts.IsClusterMode = true
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", cfg.Mode)
+	}
+
+	if cfg.TLS {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	return redis.NewUniversalClient(opts), nil
+}
+
+func newTLSConfig(cfg config.RedisConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.TLSServerName,
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	}
+	if cfg.TLSCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.TLSCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCAFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/Ravwvil/order-service/backend/internal/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewClient тестирует выбор типа клиента по режиму.
+func TestNewClient(t *testing.T) {
+	t.Run("standalone", func(t *testing.T) {
+		client, err := NewClient(config.RedisConfig{Mode: config.RedisModeStandalone, Addrs: []string{"localhost:6379"}})
+		require.NoError(t, err)
+		defer client.Close()
+		assert.IsType(t, &redis.Client{}, client)
+	})
+
+	t.Run("sentinel", func(t *testing.T) {
+		client, err := NewClient(config.RedisConfig{
+			Mode:       config.RedisModeSentinel,
+			Addrs:      []string{"sentinel-1:26379", "sentinel-2:26379"},
+			MasterName: "mymaster",
+		})
+		require.NoError(t, err)
+		defer client.Close()
+		assert.IsType(t, &redis.Client{}, client)
+	})
+
+	t.Run("cluster with single seed", func(t *testing.T) {
+		client, err := NewClient(config.RedisConfig{Mode: config.RedisModeCluster, Addrs: []string{"node-1:6379"}, TLS: true})
+		require.NoError(t, err)
+		defer client.Close()
+		assert.IsType(t, &redis.ClusterClient{}, client)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, cfg := range map[string]config.RedisConfig{
+			"no address":       {Mode: config.RedisModeStandalone},
+			"many standalone":  {Mode: config.RedisModeStandalone, Addrs: []string{"a:1", "b:2"}},
+			"no master name":   {Mode: config.RedisModeSentinel, Addrs: []string{"a:1"}},
+			"cluster db":       {Mode: config.RedisModeCluster, Addrs: []string{"a:1"}, DB: 1},
+			"unknown mode":     {Mode: "ring", Addrs: []string{"a:1"}},
+			"missing ca file":  {Addrs: []string{"a:1"}, TLS: true, TLSCAFile: "testdata/missing.pem"},
+			"ca without certs": {Addrs: []string{"a:1"}, TLS: true, TLSCAFile: "testdata/valid_order.json"},
+		} {
+			_, err := NewClient(cfg)
+			assert.Error(t, err, name)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/Ravwvil/order-service/backend/internal/cache/codec"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// customerPrefix префикс set с UID заказов покупателя
+	customerPrefix = "order_customer:"
+	// trackPrefix префикс ключей трек-номер -> UID заказа
+	trackPrefix = "order_track:"
+	// completeMarker элемент set покупателя, означающий, что в нем все заказы покупателя из базы.
+	// Без него set содержит только заказы, попавшие в кэш, и не может заменить запрос к базе.
+	completeMarker = ""
+)
+
+// Ключи индексов строятся так же, как ключи заказов: при изоляции тенантов ключ заказа
+// имеет вид "<entry>:<uid>", а ключ индекса - "<entry>:<customer_id>" или "<entry>:<track_number>".
+// indexScope возвращает общую часть ключа (все, что перед UID).
+func indexScope(key string, order *domain.Order) (string, bool) {
+	if order.OrderUID == "" || !strings.HasSuffix(key, order.OrderUID) {
+		return "", false
+	}
+	return strings.TrimSuffix(key, order.OrderUID), true
+}
+
+// addIndexes добавляет в pipeline обновление индексов заказа
+func (c *Cache) addIndexes(ctx context.Context, pipe redis.Pipeliner, key string, order *domain.Order) {
+	scope, ok := indexScope(key, order)
+	if !ok {
+		return
+	}
+	if order.CustomerID != "" {
+		customerKey := customerPrefix + scope + order.CustomerID
+		pipe.SAdd(ctx, customerKey, order.OrderUID)
+		if c.ttl > 0 {
+			pipe.Expire(ctx, customerKey, c.ttl)
+		}
+	}
+	if order.TrackNumber != "" {
+		pipe.Set(ctx, trackPrefix+scope+order.TrackNumber, order.OrderUID, c.ttl)
+	}
+}
+
+// dropIndexes добавляет в pipeline удаление индексов заказа. Set покупателя удаляется
+// целиком: заказ может остаться в базе, и без него set перестал бы быть полным.
+func dropIndexes(ctx context.Context, pipe redis.Pipeliner, key string, order *domain.Order) {
+	scope, ok := indexScope(key, order)
+	if !ok {
+		return
+	}
+	if order.CustomerID != "" {
+		pipe.Del(ctx, customerPrefix+scope+order.CustomerID)
+	}
+	if order.TrackNumber != "" {
+		pipe.Del(ctx, trackPrefix+scope+order.TrackNumber)
+	}
+}
+
+// indexedOrders читает удаляемые заказы, чтобы найти их индексы.
+// Отсутствующие и нечитаемые записи пропускаются: их индексы устареют,
+// но читающая сторона сверяет найденные заказы с запросом.
+func (c *Cache) indexedOrders(ctx context.Context, keys []string) map[string]*domain.Order {
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, c.prefix+key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		c.observe(err)
+		c.logger.Error("Failed to read orders for index cleanup",
+			slog.Int("count", len(keys)),
+			slog.Any("error", err),
+		)
+	}
+
+	orders := make(map[string]*domain.Order, len(keys))
+	for i, cmd := range cmds {
+		record, err := cmd.Bytes()
+		if err != nil {
+			continue
+		}
+		if order, err := codec.Decode(record); err == nil {
+			orders[keys[i]] = order
+		}
+	}
+	return orders
+}
+
+// CustomerUIDs возвращает UID заказов покупателя. false, если индекс покупателя
+// отсутствует или неполон и заказы нужно запросить из базы.
+func (c *Cache) CustomerUIDs(ctx context.Context, key string) ([]string, bool) {
+	if !c.allow() {
+		return nil, false
+	}
+
+	members, err := c.client.SMembers(ctx, customerPrefix+key).Result()
+	if err != nil {
+		c.observe(err)
+		c.logger.Error("Failed to get customer index from Redis cache",
+			slog.String("key", key),
+			slog.Any("error", err),
+		)
+		return nil, false
+	}
+
+	complete := false
+	uids := make([]string, 0, len(members))
+	for _, member := range members {
+		if member == completeMarker {
+			complete = true
+			continue
+		}
+		uids = append(uids, member)
+	}
+	if !complete {
+		return nil, false
+	}
+	return uids, true
+}
+
+// SetCustomerUIDs сохраняет полный список заказов покупателя, загруженный из базы.
+// UID добавляются к уже записанным, чтобы не потерять заказы, сохраненные одновременно.
+func (c *Cache) SetCustomerUIDs(ctx context.Context, key string, uids []string) {
+	if !c.allow() {
+		return
+	}
+
+	members := make([]any, 0, len(uids)+1)
+	members = append(members, completeMarker)
+	for _, uid := range uids {
+		members = append(members, uid)
+	}
+
+	pipe := c.client.Pipeline()
+	pipe.SAdd(ctx, customerPrefix+key, members...)
+	if c.ttl > 0 {
+		pipe.Expire(ctx, customerPrefix+key, c.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.observe(err)
+		c.logger.Error("Failed to set customer index in Redis cache",
+			slog.String("key", key),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// TrackUID возвращает UID заказа с трек-номером
+func (c *Cache) TrackUID(ctx context.Context, key string) (string, bool) {
+	if !c.allow() {
+		return "", false
+	}
+
+	uid, err := c.client.Get(ctx, trackPrefix+key).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.observe(err)
+			c.logger.Error("Failed to get track number index from Redis cache",
+				slog.String("key", key),
+				slog.Any("error", err),
+			)
+		}
+		return "", false
+	}
+	return uid, true
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Indexes(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+	other := loadOrderFromJSON(t, "testdata/valid_order.json")
+	other.OrderUID = "index-other-uid"
+	other.TrackNumber = "INDEXOTHERTRACK"
+
+	_, err := redisCache.Flush(ctx)
+	require.NoError(t, err)
+
+	t.Run("set indexes order without completing customer", func(t *testing.T) {
+		redisCache.Set(ctx, order.OrderUID, order)
+
+		uid, found := redisCache.TrackUID(ctx, order.TrackNumber)
+		assert.True(t, found)
+		assert.Equal(t, order.OrderUID, uid)
+
+		// Не все заказы покупателя могут быть в кэше, поэтому индекс неполон
+		_, found = redisCache.CustomerUIDs(ctx, order.CustomerID)
+		assert.False(t, found)
+		assert.True(t, redisClient.SIsMember(ctx, customerPrefix+order.CustomerID, order.OrderUID).Val())
+	})
+
+	t.Run("complete customer index keeps new orders", func(t *testing.T) {
+		redisCache.SetCustomerUIDs(ctx, order.CustomerID, []string{order.OrderUID})
+		redisCache.LoadFromDB(ctx, map[string]*domain.Order{other.OrderUID: other})
+
+		uids, found := redisCache.CustomerUIDs(ctx, order.CustomerID)
+		assert.True(t, found)
+		assert.ElementsMatch(t, []string{order.OrderUID, other.OrderUID}, uids)
+
+		uid, found := redisCache.TrackUID(ctx, other.TrackNumber)
+		assert.True(t, found)
+		assert.Equal(t, other.OrderUID, uid)
+
+		ttl := redisClient.TTL(ctx, customerPrefix+order.CustomerID).Val()
+		assert.Positive(t, ttl.Seconds())
+	})
+
+	t.Run("customer without orders", func(t *testing.T) {
+		redisCache.SetCustomerUIDs(ctx, "index-empty-customer", nil)
+
+		uids, found := redisCache.CustomerUIDs(ctx, "index-empty-customer")
+		assert.True(t, found)
+		assert.Empty(t, uids)
+	})
+
+	t.Run("tenant scoped keys", func(t *testing.T) {
+		redisCache.Set(ctx, "WBIL:"+order.OrderUID, order)
+
+		uid, found := redisCache.TrackUID(ctx, "WBIL:"+order.TrackNumber)
+		assert.True(t, found)
+		assert.Equal(t, order.OrderUID, uid)
+		assert.True(t, redisClient.SIsMember(ctx, customerPrefix+"WBIL:"+order.CustomerID, order.OrderUID).Val())
+	})
+
+	t.Run("delete drops indexes", func(t *testing.T) {
+		redisCache.Delete(ctx, other.OrderUID)
+
+		_, found := redisCache.TrackUID(ctx, other.TrackNumber)
+		assert.False(t, found)
+		_, found = redisCache.CustomerUIDs(ctx, order.CustomerID)
+		assert.False(t, found)
+
+		uid, found := redisCache.TrackUID(ctx, order.TrackNumber)
+		assert.True(t, found)
+		assert.Equal(t, order.OrderUID, uid)
+	})
+
+	t.Run("flush drops indexes", func(t *testing.T) {
+		_, err := redisCache.Flush(ctx)
+		require.NoError(t, err)
+
+		_, found := redisCache.TrackUID(ctx, order.TrackNumber)
+		assert.False(t, found)
+		_, found = redisCache.CustomerUIDs(ctx, "index-empty-customer")
+		assert.False(t, found)
+	})
+}
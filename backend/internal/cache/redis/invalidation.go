@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/Ravwvil/order-service/backend/internal/cache/invalidation"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultInvalidationChannel канал Redis pub/sub для инвалидации локальных кэшей реплик
+const DefaultInvalidationChannel = "order_invalidations"
+
+// Invalidations транспорт шины инвалидаций поверх Redis pub/sub
+type Invalidations struct {
+	client  redis.UniversalClient
+	channel string
+	logger  *slog.Logger
+}
+
+// NewInvalidations создает канал инвалидации поверх клиента Redis
+func NewInvalidations(client redis.UniversalClient, channel string, logger *slog.Logger) *Invalidations {
+	return &Invalidations{
+		client:  client,
+		channel: channel,
+		logger:  logger,
+	}
+}
+
+// Publish отправляет сообщение всем репликам
+func (i *Invalidations) Publish(ctx context.Context, msg invalidation.Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invalidation: %w", err)
+	}
+	if err := i.client.Publish(ctx, i.channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// Subscribe вызывает fn для каждого полученного сообщения до отмены контекста
+// или обрыва подписки. ready вызывается после каждого подтверждения подписки:
+// клиент Redis сам переподключается после разрыва соединения, и сообщения,
+// отправленные в это время, теряются, поэтому повторное подтверждение означает переподключение.
+func (i *Invalidations) Subscribe(ctx context.Context, ready func(), fn func(msg invalidation.Message)) error {
+	pubsub := i.client.Subscribe(ctx, i.channel)
+	defer pubsub.Close()
+
+	// Дожидаемся подтверждения подписки, чтобы не терять сообщения после возврата ошибки
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", i.channel, err)
+	}
+	if ready != nil {
+		ready()
+	}
+
+	messages := pubsub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("subscription to %s closed", i.channel)
+			}
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				if msg.Kind != "subscribe" {
+					continue
+				}
+				i.logger.Warn("resubscribed to cache invalidations after reconnect", slog.String("channel", i.channel))
+				if ready != nil {
+					ready()
+				}
+			case *redis.Message:
+				var message invalidation.Message
+				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+					i.logger.Error("Failed to unmarshal cache invalidation",
+						slog.String("payload", msg.Payload),
+						slog.Any("error", err),
+					)
+					continue
+				}
+				fn(message)
+			}
+		}
+	}
+}
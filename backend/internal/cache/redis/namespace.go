@@ -0,0 +1,176 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/cache/codec"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Записи заказов хранятся в пространстве ключей версии схемы domain.OrderSchemaVersion:
+// "order.v<N>:<key>". Версии 0 соответствуют ключи "order:<key>", записанные до введения версий.
+// Шаблоны SCAN ниже не пересекаются с ключами индексов ("order_customer:", "order_track:").
+const (
+	legacyPattern    = "order:*"
+	versionedPattern = "order.v*"
+)
+
+// keyPrefix возвращает префикс ключей заказов версии схемы
+func keyPrefix(version int) string {
+	if version <= 0 {
+		return "order:"
+	}
+	return "order.v" + strconv.Itoa(version) + ":"
+}
+
+// keyVersion возвращает версию схемы, к пространству ключей которой относится ключ заказа
+func keyVersion(key string) (int, bool) {
+	if strings.HasPrefix(key, "order:") {
+		return 0, true
+	}
+	rest, ok := strings.CutPrefix(key, "order.v")
+	if !ok {
+		return 0, false
+	}
+	number, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.Atoi(number)
+	if err != nil || version < 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// SetSchemaFallback включает чтение записей предыдущей версии схемы на время
+// обновления реплик: найденная запись переводится в текущую форму (domain.UpgradeOrder)
+// и сохраняется в текущем пространстве ключей. По умолчанию выключено.
+func (c *Cache) SetSchemaFallback(enabled bool) {
+	c.fallback = enabled && c.version > 0
+}
+
+// getPrevious ищет заказ в пространстве ключей предыдущей версии схемы
+func (c *Cache) getPrevious(ctx context.Context, key string) (*domain.Order, bool) {
+	if !c.fallback {
+		return nil, false
+	}
+
+	previous := c.version - 1
+	record, err := c.client.Get(ctx, keyPrefix(previous)+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.observe(err)
+			c.logger.Error("Failed to get order of previous schema version from Redis cache",
+				slog.String("key", key),
+				slog.Any("error", err),
+			)
+		}
+		return nil, false
+	}
+
+	raw, err := codec.DecodeRaw(record)
+	var order *domain.Order
+	if err == nil {
+		order, err = domain.UpgradeOrder(raw, previous)
+	}
+	if err != nil {
+		c.logger.Error("Failed to upgrade order of previous schema version",
+			slog.String("key", key),
+			slog.Int("version", previous),
+			slog.Any("error", err),
+		)
+		return nil, false
+	}
+
+	c.upgraded.Add(1)
+	c.Set(ctx, key, order)
+	return order, true
+}
+
+// CleanupNamespaces удаляет записи заказов версий схемы ниже текущей и возвращает
+// число удаленных ключей. Записи более новых версий не трогаются: их пишут уже
+// обновленные реплики, пока эта реплика еще работает на прежней версии.
+func (c *Cache) CleanupNamespaces(ctx context.Context) (int64, error) {
+	if !c.allow() {
+		return 0, ErrUnavailable
+	}
+
+	var deleted atomic.Int64
+	err := c.forEachNode(ctx, func(ctx context.Context, node redis.Cmdable) error {
+		for _, pattern := range []string{legacyPattern, versionedPattern} {
+			err := scanKeys(ctx, node, pattern, func(keys []string) error {
+				stale := keys[:0]
+				for _, key := range keys {
+					if version, ok := keyVersion(key); ok && version < c.version {
+						stale = append(stale, key)
+					}
+				}
+				return unlink(ctx, node, stale, &deleted)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.observe(err)
+		return deleted.Load(), fmt.Errorf("failed to clean up cache namespaces: %w", err)
+	}
+	return deleted.Load(), nil
+}
+
+// NamespaceCleaner периодически удаляет записи прежних версий схемы.
+// Первый прогон выполняется через interval после запуска, чтобы не мешать
+// чтению прежней версии, пока обновляются остальные реплики.
+type NamespaceCleaner struct {
+	cache    *Cache
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewNamespaceCleaner(cache *Cache, interval time.Duration, logger *slog.Logger) *NamespaceCleaner {
+	return &NamespaceCleaner{
+		cache:    cache,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run удаляет записи прежних версий схемы с заданным интервалом до отмены контекста
+func (n *NamespaceCleaner) Run(ctx context.Context) {
+	n.logger.Info("starting cache namespace cleanup job",
+		slog.Int("schema_version", n.cache.version),
+		slog.Duration("interval", n.interval))
+
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			n.logger.Info("cache namespace cleanup job stopped")
+			return
+		case <-ticker.C:
+			deleted, err := n.cache.CleanupNamespaces(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					n.logger.Error("cache namespace cleanup failed", slog.Int64("deleted", deleted), slog.Any("error", err))
+				}
+				continue
+			}
+			if deleted > 0 {
+				n.logger.Info("stale cache namespaces cleaned up", slog.Int64("deleted", deleted))
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	assert.Equal(t, "order:", keyPrefix(0))
+	assert.Equal(t, "order.v1:", keyPrefix(1))
+	assert.Equal(t, "order.v12:", keyPrefix(12))
+	assert.Equal(t, keyPrefix(domain.OrderSchemaVersion), redisCache.prefix)
+}
+
+func TestKeyVersion(t *testing.T) {
+	for key, want := range map[string]int{"order:a": 0, "order.v1:a": 1, "order.v12:a:b": 12} {
+		version, ok := keyVersion(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, version, key)
+	}
+	for _, key := range []string{"order.vx:a", "order.v1", "order_track:a"} {
+		_, ok := keyVersion(key)
+		assert.False(t, ok, key)
+	}
+}
+
+func TestCache_SchemaFallback(t *testing.T) {
+	ctx := context.Background()
+	order := loadOrderFromJSON(t, "testdata/valid_order.json")
+	previous := keyPrefix(domain.OrderSchemaVersion - 1)
+
+	_, err := redisCache.Flush(ctx)
+	require.NoError(t, err)
+
+	redisCache.SetSchemaFallback(true)
+	defer redisCache.SetSchemaFallback(false)
+
+	record, err := json.Marshal(order)
+	require.NoError(t, err)
+
+	t.Run("previous version is upgraded on read", func(t *testing.T) {
+		require.NoError(t, redisClient.Set(ctx, previous+"fallback", record, time.Hour).Err())
+
+		cached, found := redisCache.Get(ctx, "fallback")
+		require.True(t, found)
+		assert.Equal(t, order.OrderUID, cached.OrderUID)
+		assert.Equal(t, int64(1), redisClient.Exists(ctx, redisCache.prefix+"fallback").Val())
+
+		stats, err := redisCache.Stats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderSchemaVersion, stats.SchemaVersion)
+		assert.Positive(t, stats.Upgraded)
+	})
+
+	t.Run("previous version as JSON", func(t *testing.T) {
+		require.NoError(t, redisClient.Set(ctx, previous+"fallback-json", record, time.Hour).Err())
+
+		data, found := redisCache.GetJSON(ctx, "fallback-json")
+		require.True(t, found)
+		assert.Contains(t, string(data), order.OrderUID)
+	})
+
+	t.Run("delete removes both versions", func(t *testing.T) {
+		redisCache.Delete(ctx, "fallback")
+
+		assert.Zero(t, redisClient.Exists(ctx, previous+"fallback", redisCache.prefix+"fallback").Val())
+		_, found := redisCache.Get(ctx, "fallback")
+		assert.False(t, found)
+	})
+
+	t.Run("fallback disabled", func(t *testing.T) {
+		redisCache.SetSchemaFallback(false)
+		defer redisCache.SetSchemaFallback(true)
+
+		require.NoError(t, redisClient.Set(ctx, previous+"no-fallback", record, time.Hour).Err())
+		_, found := redisCache.Get(ctx, "no-fallback")
+		assert.False(t, found)
+	})
+
+	t.Run("cleanup keeps current version and indexes", func(t *testing.T) {
+		redisCache.Set(ctx, order.OrderUID, order)
+		require.NoError(t, redisClient.Set(ctx, "order.v0:stale", record, time.Hour).Err())
+		newer := keyPrefix(domain.OrderSchemaVersion+1) + "newer"
+		require.NoError(t, redisClient.Set(ctx, newer, record, time.Hour).Err())
+
+		deleted, err := redisCache.CleanupNamespaces(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), deleted) // fallback-json и no-fallback прежней версии и order.v0:stale
+
+		// Записи обновленных реплик более новой версии схемы сохраняются
+		exists, err := redisClient.Exists(ctx, newer).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), exists)
+		require.NoError(t, redisClient.Del(ctx, newer).Err())
+
+		_, found := redisCache.Get(ctx, order.OrderUID)
+		assert.True(t, found)
+		_, found = redisCache.TrackUID(ctx, order.TrackNumber)
+		assert.True(t, found)
+	})
+}
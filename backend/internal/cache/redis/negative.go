@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// missingPrefix префикс ключей отрицательного кэша (заказ не найден в базе)
+const missingPrefix = "order_missing:"
+
+// SetMissing запоминает, что заказа нет в базе, на время ttl
+func (c *Cache) SetMissing(ctx context.Context, key string, ttl time.Duration) {
+	if !c.allow() {
+		return
+	}
+	if err := c.client.Set(ctx, missingPrefix+key, 1, ttl).Err(); err != nil {
+		c.observe(err)
+		c.logger.Error("Failed to set negative cache entry",
+			slog.String("key", key),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// IsMissing сообщает, что заказ недавно не был найден в базе
+func (c *Cache) IsMissing(ctx context.Context, key string) bool {
+	if !c.allow() {
+		return false
+	}
+	n, err := c.client.Exists(ctx, missingPrefix+key).Result()
+	if err != nil {
+		c.observe(err)
+		c.logger.Error("Failed to check negative cache entry",
+			slog.String("key", key),
+			slog.Any("error", err),
+		)
+		return false
+	}
+	return n > 0
+}
+
+// ClearMissing удаляет отрицательную запись. Пока выключатель разомкнут,
+// удаление откладывается до восстановления Redis (см. ReplayPending).
+func (c *Cache) ClearMissing(ctx context.Context, key string) {
+	if !c.allow() {
+		c.pending.addMissing(key)
+		if !c.available() {
+			return
+		}
+	}
+	if err := c.client.Del(ctx, missingPrefix+key).Err(); err != nil {
+		c.observe(err)
+		c.pending.addMissing(key)
+		c.logger.Error("Failed to clear negative cache entry",
+			slog.String("key", key),
+			slog.Any("error", err),
+		)
+	}
+}
@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// maxPendingInvalidations ограничивает число отложенных удалений: при переполнении
+// после восстановления Redis очищаются все заказы и отрицательные записи
+const maxPendingInvalidations = 100000
+
+// pendingInvalidations удаления, отложенные, пока выключатель разомкнут. Без них
+// Redis после восстановления отдавал бы удаленные или обезличенные заказы до истечения TTL:
+// повторный прогрев только добавляет ключи.
+type pendingInvalidations struct {
+	mu       sync.Mutex
+	keys     map[string]struct{}
+	missing  map[string]struct{}
+	overflow bool
+}
+
+func (p *pendingInvalidations) addKeys(keys []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range keys {
+		p.keys = p.add(p.keys, key)
+	}
+}
+
+func (p *pendingInvalidations) addMissing(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.missing = p.add(p.missing, key)
+}
+
+func (p *pendingInvalidations) add(set map[string]struct{}, key string) map[string]struct{} {
+	if p.overflow {
+		return set
+	}
+	if len(p.keys)+len(p.missing) >= maxPendingInvalidations {
+		p.overflow = true
+		p.keys, p.missing = nil, nil
+		return nil
+	}
+	if set == nil {
+		set = make(map[string]struct{})
+	}
+	set[key] = struct{}{}
+	return set
+}
+
+// take забирает отложенные удаления
+func (p *pendingInvalidations) take() (keys, missing []string, overflow bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.keys {
+		keys = append(keys, key)
+	}
+	for key := range p.missing {
+		missing = append(missing, key)
+	}
+	overflow = p.overflow
+	p.keys, p.missing, p.overflow = nil, nil, false
+	return keys, missing, overflow
+}
+
+// restore возвращает удаления, которые не удалось применить
+func (p *pendingInvalidations) restore(keys, missing []string, overflow bool) {
+	if overflow {
+		p.mu.Lock()
+		p.overflow = true
+		p.keys, p.missing = nil, nil
+		p.mu.Unlock()
+		return
+	}
+	p.addKeys(keys)
+	for _, key := range missing {
+		p.addMissing(key)
+	}
+}
+
+// ReplayPending применяет удаления, отложенные, пока выключатель был разомкнут.
+// Вызывается выключателем до замыкания (breaker.Breaker.BeforeClose): при ошибке
+// удаления сохраняются, а выключатель остается разомкнутым.
+func (c *Cache) ReplayPending(ctx context.Context) error {
+	keys, missing, overflow := c.pending.take()
+	if len(keys) == 0 && len(missing) == 0 && !overflow {
+		return nil
+	}
+
+	err := c.replay(ctx, keys, missing, overflow)
+	if err != nil {
+		c.pending.restore(keys, missing, overflow)
+		return fmt.Errorf("failed to replay pending invalidations: %w", err)
+	}
+
+	c.logger.Info("Pending cache invalidations replayed",
+		slog.Int("keys", len(keys)),
+		slog.Int("missing", len(missing)),
+		slog.Bool("flushed", overflow),
+	)
+	return nil
+}
+
+func (c *Cache) replay(ctx context.Context, keys, missing []string, overflow bool) error {
+	if overflow {
+		patterns := append([]string{missingPrefix + "*"}, flushPatterns...)
+		_, err := c.flush(ctx, patterns)
+		return err
+	}
+
+	if len(keys) > 0 {
+		if err := c.delete(ctx, keys); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		pipe := c.client.Pipeline()
+		for _, key := range missing {
+			pipe.Del(ctx, missingPrefix+key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			c.observe(err)
+			return err
+		}
+	}
+	return nil
+}
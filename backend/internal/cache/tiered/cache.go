@@ -0,0 +1,144 @@
+package tiered
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/Ravwvil/order-service/backend/internal/cache/invalidation"
+	"github.com/Ravwvil/order-service/backend/internal/cache/memory"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// Remote общий для всех реплик кэш (redis.Cache)
+type Remote interface {
+	Set(ctx context.Context, key string, order *domain.Order)
+	Get(ctx context.Context, key string) (*domain.Order, bool)
+	GetJSON(ctx context.Context, key string) ([]byte, bool)
+	LoadFromDB(ctx context.Context, orders map[string]*domain.Order)
+	Delete(ctx context.Context, keys ...string)
+}
+
+// Stats счетчики попаданий и промахов по уровням кэша
+type Stats struct {
+	LocalHits    int64 `json:"local_hits"`
+	LocalMisses  int64 `json:"local_misses"`
+	RemoteHits   int64 `json:"remote_hits"`
+	RemoteMisses int64 `json:"remote_misses"`
+	LocalSize    int   `json:"local_size"`
+}
+
+// Cache двухуровневый кэш: локальный LRU в памяти процесса перед общим Redis.
+// Изменения заказов рассылаются через шину инвалидаций, и другие реплики удаляют свои локальные копии.
+type Cache struct {
+	local  *memory.Cache
+	remote Remote
+	bus    *invalidation.Bus
+
+	localHits    atomic.Int64
+	localMisses  atomic.Int64
+	remoteHits   atomic.Int64
+	remoteMisses atomic.Int64
+}
+
+// New создает двухуровневый кэш и подписывает локальный уровень на шину инвалидаций.
+// bus может быть nil для единственной реплики.
+func New(local *memory.Cache, remote Remote, bus *invalidation.Bus) *Cache {
+	if bus != nil {
+		bus.Register(local)
+	}
+	return &Cache{
+		local:  local,
+		remote: remote,
+		bus:    bus,
+	}
+}
+
+// Get ищет заказ сначала в локальном кэше, затем в Redis
+func (c *Cache) Get(ctx context.Context, key string) (*domain.Order, bool) {
+	if order, ok := c.local.Get(key); ok {
+		c.localHits.Add(1)
+		return order, true
+	}
+	c.localMisses.Add(1)
+
+	// Поколение берется до чтения Redis: если за время чтения заказ
+	// изменили или удалили, прочитанная копия в локальный кэш не попадет
+	generation := c.local.Generation()
+	order, ok := c.remote.Get(ctx, key)
+	if !ok {
+		c.remoteMisses.Add(1)
+		return nil, false
+	}
+	c.remoteHits.Add(1)
+
+	c.local.FillIf(generation, key, order)
+	return order, true
+}
+
+// GetJSON ищет заказ в JSON сначала в локальном кэше, затем в Redis
+func (c *Cache) GetJSON(ctx context.Context, key string) ([]byte, bool) {
+	if raw, ok := c.local.GetJSON(key); ok {
+		c.localHits.Add(1)
+		return raw, true
+	}
+	c.localMisses.Add(1)
+
+	generation := c.local.Generation()
+	raw, ok := c.remote.GetJSON(ctx, key)
+	if !ok {
+		c.remoteMisses.Add(1)
+		return nil, false
+	}
+	c.remoteHits.Add(1)
+
+	c.local.FillJSONIf(generation, key, raw)
+	return raw, true
+}
+
+// Set сохраняет заказ в оба уровня и сообщает другим репликам об изменении
+func (c *Cache) Set(ctx context.Context, key string, order *domain.Order) {
+	c.remote.Set(ctx, key, order)
+	c.local.Set(key, order)
+	c.publish(ctx, key)
+}
+
+// LoadFromDB загружает заказы в Redis и сбрасывает их локальные копии на всех репликах.
+// Локальный кэш заполняется по мере обращений.
+func (c *Cache) LoadFromDB(ctx context.Context, orders map[string]*domain.Order) {
+	c.remote.LoadFromDB(ctx, orders)
+
+	keys := make([]string, 0, len(orders))
+	for key := range orders {
+		keys = append(keys, key)
+	}
+	c.local.Delete(keys...)
+	c.publish(ctx, keys...)
+}
+
+// Delete удаляет заказы из обоих уровней на всех репликах.
+// Redis очищается первым, чтобы параллельное чтение не вернуло удаленный заказ в локальный кэш.
+func (c *Cache) Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	c.remote.Delete(ctx, keys...)
+	c.local.Delete(keys...)
+	c.publish(ctx, keys...)
+}
+
+// Stats возвращает счетчики попаданий и промахов
+func (c *Cache) Stats() Stats {
+	return Stats{
+		LocalHits:    c.localHits.Load(),
+		LocalMisses:  c.localMisses.Load(),
+		RemoteHits:   c.remoteHits.Load(),
+		RemoteMisses: c.remoteMisses.Load(),
+		LocalSize:    c.local.Len(),
+	}
+}
+
+func (c *Cache) publish(ctx context.Context, keys ...string) {
+	if c.bus != nil {
+		c.bus.Publish(ctx, keys...)
+	}
+}
@@ -0,0 +1,268 @@
+package tiered
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/cache/invalidation"
+	"github.com/Ravwvil/order-service/backend/internal/cache/memory"
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRemote общий кэш в памяти для тестов.
+type fakeRemote struct {
+	mu     sync.Mutex
+	orders map[string]*domain.Order
+	gets   int
+	// onGet вызывается после чтения, имитируя изменение заказа во время запроса к Redis
+	onGet func()
+}
+
+func newFakeRemote() *fakeRemote {
+	return &fakeRemote{orders: make(map[string]*domain.Order)}
+}
+
+func (r *fakeRemote) Set(ctx context.Context, key string, order *domain.Order) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[key] = order
+}
+
+func (r *fakeRemote) Get(ctx context.Context, key string) (*domain.Order, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gets++
+	order, ok := r.orders[key]
+	onGet := r.onGet
+	r.mu.Unlock()
+
+	if onGet != nil {
+		onGet()
+	}
+	r.mu.Lock()
+	return order, ok
+}
+
+func (r *fakeRemote) GetJSON(ctx context.Context, key string) ([]byte, bool) {
+	order, ok := r.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	raw, err := json.Marshal(order)
+	return raw, err == nil
+}
+
+func (r *fakeRemote) LoadFromDB(ctx context.Context, orders map[string]*domain.Order) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, order := range orders {
+		r.orders[key] = order
+	}
+}
+
+func (r *fakeRemote) Delete(ctx context.Context, keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		delete(r.orders, key)
+	}
+}
+
+// fakeTransport синхронный транспорт инвалидаций в памяти для тестов.
+type fakeTransport struct {
+	mu          sync.Mutex
+	subscribers []func(msg invalidation.Message)
+}
+
+func (t *fakeTransport) Publish(ctx context.Context, msg invalidation.Message) error {
+	t.mu.Lock()
+	subscribers := append([]func(invalidation.Message){}, t.subscribers...)
+	t.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(msg)
+	}
+	return nil
+}
+
+func (t *fakeTransport) Subscribe(ctx context.Context, ready func(), fn func(msg invalidation.Message)) error {
+	t.mu.Lock()
+	t.subscribers = append(t.subscribers, fn)
+	t.mu.Unlock()
+	ready()
+
+	<-ctx.Done()
+	return nil
+}
+
+func newTestCache(remote Remote, bus *invalidation.Bus) *Cache {
+	return New(memory.New(100, time.Minute), remote, bus)
+}
+
+func TestCache_GetThroughTiers(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	cache := newTestCache(remote, nil)
+
+	order := &domain.Order{OrderUID: "uid-1"}
+	remote.Set(ctx, "uid-1", order)
+
+	got, ok := cache.Get(ctx, "uid-1")
+	require.True(t, ok)
+	assert.Same(t, order, got)
+
+	// Второе обращение обслуживается локальным кэшем
+	_, ok = cache.Get(ctx, "uid-1")
+	require.True(t, ok)
+	assert.Equal(t, 1, remote.gets)
+
+	_, ok = cache.Get(ctx, "missing")
+	assert.False(t, ok)
+
+	assert.Equal(t, Stats{LocalHits: 1, LocalMisses: 2, RemoteHits: 1, RemoteMisses: 1, LocalSize: 1}, cache.Stats())
+}
+
+func TestCache_GetJSONThroughTiers(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	cache := newTestCache(remote, nil)
+
+	remote.Set(ctx, "uid-1", &domain.Order{OrderUID: "uid-1"})
+
+	raw, ok := cache.GetJSON(ctx, "uid-1")
+	require.True(t, ok)
+	assert.Contains(t, string(raw), `"order_uid":"uid-1"`)
+
+	// Локальная копия обслуживает и JSON, и декодированный заказ
+	_, ok = cache.GetJSON(ctx, "uid-1")
+	require.True(t, ok)
+	order, ok := cache.Get(ctx, "uid-1")
+	require.True(t, ok)
+	assert.Equal(t, "uid-1", order.OrderUID)
+	assert.Equal(t, 1, remote.gets)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(2), stats.LocalHits)
+	assert.Equal(t, int64(1), stats.RemoteHits)
+}
+
+func TestCache_LoadFromDBDropsLocalCopies(t *testing.T) {
+	ctx := context.Background()
+	remote := newFakeRemote()
+	cache := newTestCache(remote, nil)
+
+	cache.Set(ctx, "uid-1", &domain.Order{OrderUID: "uid-1"})
+	fresh := &domain.Order{OrderUID: "uid-1", TrackNumber: "fresh"}
+	cache.LoadFromDB(ctx, map[string]*domain.Order{"uid-1": fresh})
+
+	got, ok := cache.Get(ctx, "uid-1")
+	require.True(t, ok)
+	assert.Same(t, fresh, got)
+}
+
+func TestCache_ReadRacingInvalidation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("delete during remote read", func(t *testing.T) {
+		remote := newFakeRemote()
+		cache := newTestCache(remote, nil)
+		remote.Set(ctx, "uid-1", &domain.Order{OrderUID: "uid-1"})
+
+		remote.onGet = func() {
+			remote.onGet = nil
+			cache.Delete(ctx, "uid-1")
+		}
+		_, ok := cache.Get(ctx, "uid-1")
+		require.True(t, ok)
+
+		// Прочитанная до удаления копия не должна остаться в локальном кэше
+		assert.Zero(t, cache.Stats().LocalSize)
+		_, ok = cache.Get(ctx, "uid-1")
+		assert.False(t, ok)
+	})
+
+	t.Run("update during remote JSON read", func(t *testing.T) {
+		remote := newFakeRemote()
+		cache := newTestCache(remote, nil)
+		remote.Set(ctx, "uid-1", &domain.Order{OrderUID: "uid-1", TrackNumber: "old"})
+
+		remote.onGet = func() {
+			remote.onGet = nil
+			cache.Set(ctx, "uid-1", &domain.Order{OrderUID: "uid-1", TrackNumber: "new"})
+		}
+		raw, ok := cache.GetJSON(ctx, "uid-1")
+		require.True(t, ok)
+		assert.Contains(t, string(raw), `"old"`)
+
+		order, ok := cache.Get(ctx, "uid-1")
+		require.True(t, ok)
+		assert.Equal(t, "new", order.TrackNumber)
+	})
+}
+
+func TestCache_InvalidationAcrossReplicas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	remote := newFakeRemote()
+	transport := &fakeTransport{}
+	busA, busB := invalidation.New(transport, logger), invalidation.New(transport, logger)
+	replicaA := newTestCache(remote, busA)
+	replicaB := newTestCache(remote, busB)
+
+	var wg sync.WaitGroup
+	for _, bus := range []*invalidation.Bus{busA, busB} {
+		wg.Add(1)
+		go func(b *invalidation.Bus) {
+			defer wg.Done()
+			b.Run(ctx)
+		}(bus)
+		require.Eventually(t, func() bool { return bus.Stats().Subscribed }, time.Second, time.Millisecond)
+	}
+
+	old := &domain.Order{OrderUID: "uid-1", TrackNumber: "old"}
+	replicaA.Set(ctx, "uid-1", old)
+	_, ok := replicaB.Get(ctx, "uid-1")
+	require.True(t, ok)
+
+	updated := &domain.Order{OrderUID: "uid-1", TrackNumber: "new"}
+	replicaA.Set(ctx, "uid-1", updated)
+
+	// Реплика A не удаляет собственную свежую копию, реплика B перечитывает заказ из Redis
+	assert.Equal(t, 1, replicaA.Stats().LocalSize)
+	got, ok := replicaB.Get(ctx, "uid-1")
+	require.True(t, ok)
+	assert.Equal(t, "new", got.TrackNumber)
+
+	replicaA.Delete(ctx, "uid-1")
+	_, ok = replicaB.Get(ctx, "uid-1")
+	assert.False(t, ok)
+
+	// Загрузка из БД на одной реплике сбрасывает локальные копии остальных
+	replicaA.Set(ctx, "uid-3", &domain.Order{OrderUID: "uid-3", TrackNumber: "old"})
+	_, ok = replicaB.Get(ctx, "uid-3")
+	require.True(t, ok)
+	replicaA.LoadFromDB(ctx, map[string]*domain.Order{"uid-3": {OrderUID: "uid-3", TrackNumber: "new"}})
+	got, ok = replicaB.Get(ctx, "uid-3")
+	require.True(t, ok)
+	assert.Equal(t, "new", got.TrackNumber)
+	replicaA.Delete(ctx, "uid-3")
+
+	// Полная очистка сбрасывает локальные уровни обеих реплик
+	replicaA.Set(ctx, "uid-2", &domain.Order{OrderUID: "uid-2"})
+	_, ok = replicaB.Get(ctx, "uid-2")
+	require.True(t, ok)
+	require.NoError(t, busA.PublishFlush(ctx))
+	assert.Zero(t, replicaA.Stats().LocalSize)
+	assert.Zero(t, replicaB.Stats().LocalSize)
+
+	cancel()
+	wg.Wait()
+}
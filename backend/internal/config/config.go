@@ -8,11 +8,18 @@ import (
 )
 
 type Config struct {
-	LogLevel string
-	HTTP     HTTPConfig
-	Postgres PostgresConfig
-	Kafka    KafkaConfig
-	Redis    RedisConfig
+	LogLevel  string
+	HTTP      HTTPConfig
+	Postgres  PostgresConfig
+	Broker    BrokerConfig
+	Kafka     KafkaConfig
+	NATS      NATSConfig
+	Redis     RedisConfig
+	Cache     CacheConfig
+	Retention RetentionConfig
+	Tenant    TenantConfig
+	Sharding  ShardingConfig
+	Admin     AdminConfig
 }
 
 type HTTPConfig struct {
@@ -40,18 +47,108 @@ type KafkaConfig struct {
 	Topic             string
 	GroupID           string
 	MaxRetries        int
-	InitialRetryDelay int     // в секундах
-	MaxRetryDelay     int     // в секундах
+	InitialRetryDelay int // в секундах
+	MaxRetryDelay     int // в секундах
 	BackoffFactor     float64
 	DLQTopic          string
 	Concurrency       int
+	RetryTopics       bool  // повторы через топики уровней вместо ожидания в воркере
+	RetryTiers        []int // задержки уровней повторов в секундах
+	DLQSink           bool  // сохранение сообщений DLQ в базе для разбора через административное API
+	MaxInFlight       int   // предел незакоммиченных сообщений одной партиции
+}
+
+type BrokerConfig struct {
+	Transport string // kafka или nats
+}
+
+const (
+	BrokerKafka = "kafka"
+	BrokerNATS  = "nats"
+)
+
+type NATSConfig struct {
+	URL         string
+	Stream      string // поток JetStream с заказами
+	Subject     string // subject'ы потока, заказы публикуются в orders.<order_uid>
+	Durable     string // имя durable consumer'а
+	DLQStream   string
+	DLQSubject  string
+	RetryDelays []int // задержки повторной доставки в секундах
+	AckWait     int   // в секундах
+	Concurrency int
 }
 
 type RedisConfig struct {
-	Addr     string
-	Password string
-	DB       int
-	TTL      int // в секундах
+	Mode             string   // standalone, sentinel или cluster
+	Addrs            []string // адрес Redis, адреса sentinel или seed-узлы кластера
+	MasterName       string   // имя мастера в режиме sentinel
+	Username         string   // пользователь ACL
+	Password         string
+	SentinelUsername string
+	SentinelPassword string
+	DB               int // не поддерживается в режиме cluster
+	TLS              bool
+	TLSCAFile        string // CA для проверки сертификата сервера, по умолчанию системные
+	TLSServerName    string
+	TLSSkipVerify    bool
+	TTL              int // в секундах
+}
+
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+type CacheConfig struct {
+	RestoreBatchSize    int    // размер пачки заказов при восстановлении кэша
+	RestoreConcurrency  int    // число пачек, одновременно загружаемых в Redis
+	WarmupStrategy      string // none, all, recent, top или list
+	WarmupRecentDays    int
+	WarmupTopK          int
+	WarmupUIDsFile      string
+	LocalSize           int // число заказов в локальном кэше процесса, 0 - без локального кэша
+	LocalTTL            int // в секундах
+	InvalidationChannel string
+	NegativeTTL         int    // в секундах, 0 - без кэширования отсутствующих заказов
+	Codec               string // json, msgpack или protobuf, с суффиксом +zstd для сжатия
+	BreakerThreshold    int    // число ошибок Redis, после которого он считается недоступным
+	BreakerProbe        int    // интервал проверки доступности Redis в секундах
+	VerifyEnabled       bool
+	VerifySample        float64 // доля проверяемых заказов от 0 до 1
+	VerifyLimit         int     // максимум проверяемых заказов за прогон, 0 - без ограничения
+	VerifyInterval      int     // в секундах
+	VerifyRepair        bool
+	SchemaFallback      bool // чтение записей предыдущей версии схемы заказов на время обновления
+	NamespaceCleanup    int  // интервал удаления записей прежних версий схемы в секундах, 0 - не удалять
+}
+
+type RetentionConfig struct {
+	Enabled   bool
+	Mode      string // delete или anonymize
+	Rules     string // entry[:locale]=days через запятую, "*" - любое значение
+	BatchSize int
+	Interval  int // в секундах
+	DryRun    bool
+}
+
+type TenantConfig struct {
+	Enabled     bool
+	APIKeys     map[string]string // API-ключ -> тенант (entry)
+	PostgresRLS bool
+}
+
+type ShardingConfig struct {
+	Enabled   bool
+	DSNs      map[string]string // шард -> DSN
+	Map       string            // карта шардов, например "0-4:s1,5-9:s2,*:s1"
+	TargetMap string            // новая карта шардов для решардинга
+	Directory string            // шард, в котором хранится справочник UID -> шард
+}
+
+type AdminConfig struct {
+	APIKey string // ключ административного API, пустой - API отключено
 }
 
 func New() (*Config, error) {
@@ -78,15 +175,140 @@ func New() (*Config, error) {
 			BackoffFactor:     getEnvFloat("KAFKA_BACKOFF_FACTOR", 2.0),
 			DLQTopic:          getEnv("KAFKA_DLQ_TOPIC", "orders-dlq"),
 			Concurrency:       getEnvInt("KAFKA_CONCURRENCY", 0),
+			RetryTopics:       getEnvBool("KAFKA_RETRY_TOPICS_ENABLED", false),
+			DLQSink:           getEnvBool("KAFKA_DLQ_SINK_ENABLED", false),
+			MaxInFlight:       getEnvInt("KAFKA_MAX_IN_FLIGHT", 1000),
+		},
+		Broker: BrokerConfig{
+			Transport: getEnv("BROKER_TRANSPORT", BrokerKafka),
+		},
+		NATS: NATSConfig{
+			URL:         getEnv("NATS_URL", "nats://nats:4222"),
+			Stream:      getEnv("NATS_STREAM", "ORDERS"),
+			Subject:     getEnv("NATS_SUBJECT", "orders.>"),
+			Durable:     getEnv("NATS_DURABLE", "order-service-consumer"),
+			DLQStream:   getEnv("NATS_DLQ_STREAM", "ORDERS_DLQ"),
+			DLQSubject:  getEnv("NATS_DLQ_SUBJECT", "orders-dlq"),
+			AckWait:     getEnvInt("NATS_ACK_WAIT_S", 30),
+			Concurrency: getEnvInt("NATS_CONCURRENCY", 0),
 		},
 		Redis: RedisConfig{
-			Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getEnvInt("REDIS_DB", 0),
-			TTL:      getEnvInt("REDIS_TTL", 3600),
+			Mode:             getEnv("REDIS_MODE", RedisModeStandalone),
+			Addrs:            getEnvSlice("REDIS_ADDRS", []string{getEnv("REDIS_ADDR", "localhost:6379")}),
+			MasterName:       getEnv("REDIS_MASTER_NAME", ""),
+			Username:         getEnv("REDIS_USERNAME", ""),
+			Password:         getEnv("REDIS_PASSWORD", ""),
+			SentinelUsername: getEnv("REDIS_SENTINEL_USERNAME", ""),
+			SentinelPassword: getEnv("REDIS_SENTINEL_PASSWORD", ""),
+			DB:               getEnvInt("REDIS_DB", 0),
+			TLS:              getEnvBool("REDIS_TLS", false),
+			TLSCAFile:        getEnv("REDIS_TLS_CA_FILE", ""),
+			TLSServerName:    getEnv("REDIS_TLS_SERVER_NAME", ""),
+			TLSSkipVerify:    getEnvBool("REDIS_TLS_SKIP_VERIFY", false),
+			TTL:              getEnvInt("REDIS_TTL", 3600),
+		},
+		Cache: CacheConfig{
+			RestoreBatchSize:    getEnvInt("CACHE_RESTORE_BATCH_SIZE", 1000),
+			RestoreConcurrency:  getEnvInt("CACHE_RESTORE_CONCURRENCY", 4),
+			WarmupStrategy:      getEnv("CACHE_WARMUP_STRATEGY", "all"),
+			WarmupRecentDays:    getEnvInt("CACHE_WARMUP_RECENT_DAYS", 1),
+			WarmupTopK:          getEnvInt("CACHE_WARMUP_TOP_K", 10000),
+			WarmupUIDsFile:      getEnv("CACHE_WARMUP_UIDS_FILE", ""),
+			LocalSize:           getEnvInt("CACHE_LOCAL_SIZE", 0),
+			LocalTTL:            getEnvInt("CACHE_LOCAL_TTL_S", 60),
+			InvalidationChannel: getEnv("CACHE_INVALIDATION_CHANNEL", "order_invalidations"),
+			NegativeTTL:         getEnvInt("CACHE_NEGATIVE_TTL_S", 0),
+			Codec:               getEnv("CACHE_CODEC", "json"),
+			BreakerThreshold:    getEnvInt("CACHE_BREAKER_THRESHOLD", 5),
+			BreakerProbe:        getEnvInt("CACHE_BREAKER_PROBE_S", 1),
+			VerifyEnabled:       getEnvBool("CACHE_VERIFY_ENABLED", false),
+			VerifySample:        getEnvFloat("CACHE_VERIFY_SAMPLE", 0.1),
+			VerifyLimit:         getEnvInt("CACHE_VERIFY_LIMIT", 10000),
+			VerifyInterval:      getEnvInt("CACHE_VERIFY_INTERVAL_S", 3600),
+			VerifyRepair:        getEnvBool("CACHE_VERIFY_REPAIR", false),
+			SchemaFallback:      getEnvBool("CACHE_SCHEMA_FALLBACK", false),
+			NamespaceCleanup:    getEnvInt("CACHE_NAMESPACE_CLEANUP_INTERVAL_S", 3600),
+		},
+		Retention: RetentionConfig{
+			Enabled:   getEnvBool("RETENTION_ENABLED", false),
+			Mode:      getEnv("RETENTION_MODE", "delete"),
+			Rules:     getEnv("RETENTION_RULES", ""),
+			BatchSize: getEnvInt("RETENTION_BATCH_SIZE", 500),
+			Interval:  getEnvInt("RETENTION_INTERVAL_S", 3600),
+			DryRun:    getEnvBool("RETENTION_DRY_RUN", true),
 		},
+		Tenant: TenantConfig{
+			Enabled:     getEnvBool("TENANT_ISOLATION_ENABLED", false),
+			PostgresRLS: getEnvBool("TENANT_POSTGRES_RLS", false),
+		},
+		Sharding: ShardingConfig{
+			Enabled:   getEnvBool("SHARDING_ENABLED", false),
+			Map:       getEnv("SHARD_MAP", ""),
+			TargetMap: getEnv("SHARD_MAP_TARGET", ""),
+			Directory: getEnv("SHARD_DIRECTORY", ""),
+		},
+		Admin: AdminConfig{
+			APIKey: getEnv("ADMIN_API_KEY", ""),
+		},
+	}
+
+	switch cfg.Redis.Mode {
+	case RedisModeStandalone, RedisModeCluster:
+	case RedisModeSentinel:
+		if cfg.Redis.MasterName == "" {
+			return nil, fmt.Errorf("REDIS_MASTER_NAME must be set in sentinel mode")
+		}
+	default:
+		return nil, fmt.Errorf("unknown REDIS_MODE %q", cfg.Redis.Mode)
+	}
+
+	switch cfg.Broker.Transport {
+	case BrokerKafka, BrokerNATS:
+	default:
+		return nil, fmt.Errorf("unknown BROKER_TRANSPORT %q", cfg.Broker.Transport)
+	}
+
+	retryTiers, err := parseRetryTiers("KAFKA_RETRY_TIERS_S", getEnv("KAFKA_RETRY_TIERS_S", "10,60,600"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Kafka.RetryTiers = retryTiers
+	if cfg.Kafka.RetryTopics && len(cfg.Kafka.RetryTiers) == 0 {
+		return nil, fmt.Errorf("KAFKA_RETRY_TIERS_S must be set when retry topics are enabled")
+	}
+
+	retryDelays, err := parseRetryTiers("NATS_RETRY_DELAYS_S", getEnv("NATS_RETRY_DELAYS_S", "10,60,600"))
+	if err != nil {
+		return nil, err
 	}
-	
+	cfg.NATS.RetryDelays = retryDelays
+
+	apiKeys, err := parseAPIKeys(getEnv("TENANT_API_KEYS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Tenant.APIKeys = apiKeys
+	if cfg.Tenant.Enabled && len(cfg.Tenant.APIKeys) == 0 {
+		return nil, fmt.Errorf("TENANT_API_KEYS must be set when tenant isolation is enabled")
+	}
+
+	shardDSNs, err := ParseShardDSNs(getEnv("SHARD_DSNS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Sharding.DSNs = shardDSNs
+	if cfg.Sharding.Enabled && (len(cfg.Sharding.DSNs) == 0 || cfg.Sharding.Map == "") {
+		return nil, fmt.Errorf("SHARD_DSNS and SHARD_MAP must be set when sharding is enabled")
+	}
+	if len(cfg.Sharding.DSNs) > 0 {
+		if cfg.Sharding.Directory == "" {
+			cfg.Sharding.Directory = firstShard(cfg.Sharding.DSNs)
+		}
+		if _, ok := cfg.Sharding.DSNs[cfg.Sharding.Directory]; !ok {
+			return nil, fmt.Errorf("SHARD_DIRECTORY references unknown shard %q", cfg.Sharding.Directory)
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -115,9 +337,85 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return strings.Split(value, ",")
 	}
 	return defaultValue
 }
+
+// parseRetryTiers разбирает список задержек уровней повторов вида "10,60,600" из переменной name
+func parseRetryTiers(name, value string) ([]int, error) {
+	var tiers []int
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		seconds, err := strconv.Atoi(item)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid %s entry %q: expected positive number of seconds", name, item)
+		}
+		tiers = append(tiers, seconds)
+	}
+	return tiers, nil
+}
+
+// parseAPIKeys разбирает список вида "key1:TENANT1,key2:TENANT2"
+func parseAPIKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, tenant, ok := strings.Cut(pair, ":")
+		key, tenant = strings.TrimSpace(key), strings.TrimSpace(tenant)
+		if !ok || key == "" || tenant == "" {
+			return nil, fmt.Errorf("invalid TENANT_API_KEYS entry: expected key:tenant")
+		}
+		keys[key] = tenant
+	}
+	return keys, nil
+}
+
+// ParseShardDSNs разбирает список вида "s1=postgres://...,s2=postgres://..."
+func ParseShardDSNs(value string) (map[string]string, error) {
+	dsns := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, dsn, ok := strings.Cut(pair, "=")
+		name, dsn = strings.TrimSpace(name), strings.TrimSpace(dsn)
+		if !ok || name == "" || dsn == "" {
+			return nil, fmt.Errorf("invalid SHARD_DSNS entry: expected name=dsn")
+		}
+		if _, exists := dsns[name]; exists {
+			return nil, fmt.Errorf("duplicate shard %q in SHARD_DSNS", name)
+		}
+		dsns[name] = dsn
+	}
+	return dsns, nil
+}
+
+// firstShard возвращает имя шарда, первое по алфавиту
+func firstShard(dsns map[string]string) string {
+	first := ""
+	for name := range dsns {
+		if first == "" || name < first {
+			first = name
+		}
+	}
+	return first
+}
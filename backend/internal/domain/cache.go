@@ -0,0 +1,36 @@
+package domain
+
+import "encoding/json"
+
+// CacheEntry запись заказа в кэше, как она хранится в Redis
+type CacheEntry struct {
+	Key        string          `json:"key"`
+	Format     string          `json:"format"`
+	Size       int             `json:"size_bytes"`
+	TTLSeconds int64           `json:"ttl_seconds"` // -1 - без срока хранения
+	Order      json.RawMessage `json:"order,omitempty"`
+	Error      string          `json:"error,omitempty"` // ошибка декодирования записи
+}
+
+// CacheStats статистика кэша заказов. Hits и Misses считаются этой репликой,
+// ServerHits и ServerMisses - сервером Redis по всем ключам.
+type CacheStats struct {
+	Keys           int64   `json:"keys"`
+	UsedMemory     int64   `json:"used_memory_bytes"`
+	Hits           int64   `json:"hits"`
+	Misses         int64   `json:"misses"`
+	HitRatio       float64 `json:"hit_ratio"`
+	ServerHits     int64   `json:"server_hits"`
+	ServerMisses   int64   `json:"server_misses"`
+	ServerHitRatio float64 `json:"server_hit_ratio"`
+	SchemaVersion  int     `json:"schema_version"`
+	Upgraded       int64   `json:"upgraded"` // записи предыдущей версии схемы, переведенные в текущую
+}
+
+// HitRatio доля попаданий, 0 при отсутствии обращений
+func HitRatio(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrDLQEntryNotFound = errors.New("dlq entry not found")
+
+// DLQStatus состояние разбора сообщения DLQ
+type DLQStatus string
+
+const (
+	DLQStatusOpen      DLQStatus = "open"      // ожидает разбора
+	DLQStatusDiscarded DLQStatus = "discarded" // отброшено оператором
+	DLQStatusRequeued  DLQStatus = "requeued"  // опубликовано обратно в исходный топик
+)
+
+// DLQEntry сообщение DLQ, сохраненное в базе для разбора.
+// Повторное попадание того же сообщения увеличивает Attempts и обновляет LastSeen.
+type DLQEntry struct {
+	ID            int64             `json:"id" db:"id"`
+	Key           string            `json:"key" db:"message_key"`
+	Payload       []byte            `json:"-" db:"payload"`
+	Headers       map[string]string `json:"headers,omitempty" db:"-"`
+	OriginalTopic string            `json:"original_topic" db:"original_topic"`
+	ErrorClass    ErrorClass        `json:"error_class" db:"error_class"`
+	FailureReason string            `json:"failure_reason" db:"failure_reason"`
+	Attempts      int               `json:"attempts" db:"attempts"`
+	FirstSeen     time.Time         `json:"first_seen" db:"first_seen"`
+	LastSeen      time.Time         `json:"last_seen" db:"last_seen"`
+	DLQPartition  int               `json:"dlq_partition" db:"dlq_partition"`
+	DLQOffset     int64             `json:"dlq_offset" db:"dlq_offset"`
+	Status        DLQStatus         `json:"status" db:"status"`
+	Note          string            `json:"note" db:"note"`
+	UpdatedAt     time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// NewerThan сообщает, что сообщение попало в DLQ позже other: в одной партиции
+// порядок задает offset, в разных - время попадания
+func (e *DLQEntry) NewerThan(other *DLQEntry) bool {
+	if e.DLQPartition == other.DLQPartition {
+		return e.DLQOffset > other.DLQOffset
+	}
+	return e.LastSeen.After(other.LastSeen)
+}
+
+// DLQFilter отбор сообщений DLQ. Пустые поля не ограничивают выборку.
+type DLQFilter struct {
+	Status     DLQStatus
+	ErrorClass ErrorClass
+	Key        string
+	Reason     string // подстрока причины ошибки
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "errors"
+
+// ErrorClass класс ошибки обработки заказа: определяет, имеет ли смысл повторять попытку
+type ErrorClass string
+
+const (
+	// ErrorClassRetryable временная ошибка (недоступность базы, таймаут), повтор может пройти
+	ErrorClassRetryable ErrorClass = "retryable"
+	// ErrorClassPermanent ошибка данных (невалидный заказ, нарушение ограничений базы),
+	// которая повторится при любой попытке
+	ErrorClassPermanent ErrorClass = "permanent"
+)
+
+// PermanentError помечает ошибку как постоянную
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent помечает ошибку как постоянную. Пометка сохраняется при оборачивании через %w.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// ClassifyError возвращает класс ошибки. Ошибки без пометки считаются временными:
+// лишний повтор дешевле потери заказа.
+func ClassifyError(err error) ErrorClass {
+	var permanent *PermanentError
+	if errors.As(err, &permanent) {
+		return ErrorClassPermanent
+	}
+	return ErrorClassRetryable
+}
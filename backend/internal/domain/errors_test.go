@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	cause := errors.New("order_uid: required field")
+	permanent := fmt.Errorf("failed to save order: %w", Permanent(cause))
+
+	assert.Equal(t, ErrorClassPermanent, ClassifyError(permanent))
+	assert.ErrorIs(t, permanent, cause)
+	assert.Equal(t, "failed to save order: order_uid: required field", permanent.Error())
+
+	assert.Equal(t, ErrorClassRetryable, ClassifyError(cause))
+	assert.Equal(t, ErrorClassRetryable, ClassifyError(context.DeadlineExceeded))
+	assert.NoError(t, Permanent(nil))
+}
@@ -1,6 +1,20 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+// ErrOrderNotFound возвращается, если заказ не найден в хранилище
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrOrderExists возвращается при сохранении заказа, который уже есть в хранилище
+// (повторная доставка или переигрывание сообщения)
+var ErrOrderExists = errors.New("order already exists")
+
+// ErrOrderConflict возвращается, если сохраненный заказ с тем же order_uid отличается от нового
+var ErrOrderConflict = errors.New("order already exists with different content")
 
 type Order struct {
 	OrderUID          string    `json:"order_uid" db:"order_uid"`
@@ -21,6 +35,34 @@ type Order struct {
 	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// SameContent сообщает, совпадают ли заказы по данным из сообщения. Служебные поля
+// хранилища (CreatedAt, UpdatedAt, id товаров) не сравниваются, время создания
+// сравнивается с точностью PostgreSQL до микросекунд.
+func (o *Order) SameContent(other *Order) bool {
+	return reflect.DeepEqual(o.content(), other.content())
+}
+
+// content возвращает копию заказа без служебных полей хранилища
+func (o *Order) content() Order {
+	c := *o
+	c.CreatedAt, c.UpdatedAt = time.Time{}, time.Time{}
+	c.DateCreated = o.DateCreated.Truncate(time.Microsecond).UTC()
+	c.Delivery.OrderUID, c.Payment.OrderUID = "", ""
+	c.Items = make([]Item, len(o.Items))
+	for i, item := range o.Items {
+		item.ID, item.OrderUID = 0, ""
+		c.Items[i] = item
+	}
+	return c
+}
+
+// OrderRef минимальная ссылка на заказ
+type OrderRef struct {
+	OrderUID string `db:"order_uid"`
+	Entry    string `db:"entry"`
+	ShardKey string `db:"shardkey"`
+}
+
 type Delivery struct {
 	OrderUID string `json:"-" db:"order_uid"`
 	Name     string `json:"name" db:"name"`
@@ -47,6 +89,7 @@ type Payment struct {
 }
 
 type Item struct {
+	ID          int64  `json:"id,omitempty" db:"id"`
 	OrderUID    string `json:"-" db:"order_uid"`
 	ChrtID      int    `json:"chrt_id" db:"chrt_id"`
 	TrackNumber string `json:"track_number" db:"track_number"`
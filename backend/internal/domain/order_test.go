@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrder_SameContent(t *testing.T) {
+	created := time.Date(2021, 11, 26, 6, 22, 19, 123456789, time.FixedZone("MSK", 3*60*60))
+	incoming := &Order{
+		OrderUID:    "uid-1",
+		TrackNumber: "WBILMTESTTRACK",
+		DateCreated: created,
+		Delivery:    Delivery{Name: "Test Testov"},
+		Payment:     Payment{Transaction: "uid-1", Amount: 1817},
+		Items:       []Item{{ChrtID: 9934930, Price: 453}},
+	}
+
+	// Заказ после сохранения: служебные поля заполнены, время в UTC с точностью до микросекунд
+	stored := *incoming
+	stored.CreatedAt, stored.UpdatedAt = time.Now(), time.Now()
+	stored.DateCreated = created.UTC().Truncate(time.Microsecond)
+	stored.Delivery.OrderUID, stored.Payment.OrderUID = "uid-1", "uid-1"
+	stored.Items = []Item{{ID: 7, OrderUID: "uid-1", ChrtID: 9934930, Price: 453}}
+	assert.True(t, stored.SameContent(incoming))
+
+	changed := stored
+	changed.Payment.Amount = 1
+	assert.False(t, changed.SameContent(incoming))
+
+	changed = stored
+	changed.Items = append([]Item{}, stored.Items[0], Item{ChrtID: 1})
+	assert.False(t, changed.SameContent(incoming))
+	assert.Zero(t, incoming.Items[0].ID) // исходный заказ не меняется
+	assert.Equal(t, int64(7), stored.Items[0].ID)
+}
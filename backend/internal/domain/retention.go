@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RetentionMode определяет, что делать с заказами после истечения срока хранения
+type RetentionMode string
+
+const (
+	RetentionModeDelete    RetentionMode = "delete"
+	RetentionModeAnonymize RetentionMode = "anonymize"
+)
+
+// RetentionRule задает срок хранения заказов для entry/locale.
+// Пустые Entry или Locale означают "любое значение".
+// Days <= 0 означает бессрочное хранение.
+type RetentionRule struct {
+	Entry  string
+	Locale string
+	Days   int
+}
+
+// String возвращает правило в формате entry:locale=days
+func (r RetentionRule) String() string {
+	entry, locale := r.Entry, r.Locale
+	if entry == "" {
+		entry = "*"
+	}
+	if locale == "" {
+		locale = "*"
+	}
+	return fmt.Sprintf("%s:%s=%d", entry, locale, r.Days)
+}
+
+// RetentionPolicy политика хранения заказов.
+// Для каждого заказа применяется наиболее специфичное правило:
+// совпадение по entry важнее совпадения по locale.
+type RetentionPolicy struct {
+	Mode  RetentionMode
+	Rules []RetentionRule
+}
+
+// RetentionStat количество просроченных заказов по правилу
+type RetentionStat struct {
+	Rule  RetentionRule
+	Count int64
+}
+
+// ParseRetentionMode разбирает режим политики хранения
+func ParseRetentionMode(s string) (RetentionMode, error) {
+	switch mode := RetentionMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case RetentionModeDelete, RetentionModeAnonymize:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown retention mode %q", s)
+	}
+}
+
+// ParseRetentionRules разбирает правила вида "entry[:locale]=days" через запятую.
+// "*" означает любое значение, например: "*=365,WBIL=90,WBIL:ru=30".
+func ParseRetentionRules(s string) ([]RetentionRule, error) {
+	var rules []RetentionRule
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		scope, daysStr, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention rule %q: expected entry[:locale]=days", part)
+		}
+
+		days, err := strconv.Atoi(strings.TrimSpace(daysStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention rule %q: days must be an integer", part)
+		}
+
+		entry, locale, _ := strings.Cut(strings.TrimSpace(scope), ":")
+		rule := RetentionRule{
+			Entry:  wildcardToEmpty(entry),
+			Locale: wildcardToEmpty(locale),
+			Days:   days,
+		}
+
+		key := rule.Entry + ":" + rule.Locale
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate retention rule for %q", scope)
+		}
+		seen[key] = true
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func wildcardToEmpty(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRetentionRules тестирует разбор правил хранения.
+func TestParseRetentionRules(t *testing.T) {
+	t.Run("valid rules", func(t *testing.T) {
+		rules, err := ParseRetentionRules("*=365, WBIL=90,WBIL:ru=30,*:en=0")
+		require.NoError(t, err)
+		assert.Equal(t, []RetentionRule{
+			{Entry: "", Locale: "", Days: 365},
+			{Entry: "WBIL", Locale: "", Days: 90},
+			{Entry: "WBIL", Locale: "ru", Days: 30},
+			{Entry: "", Locale: "en", Days: 0},
+		}, rules)
+	})
+
+	t.Run("empty string", func(t *testing.T) {
+		rules, err := ParseRetentionRules("")
+		require.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("missing days", func(t *testing.T) {
+		_, err := ParseRetentionRules("WBIL")
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid days", func(t *testing.T) {
+		_, err := ParseRetentionRules("WBIL=abc")
+		assert.Error(t, err)
+	})
+
+	t.Run("duplicate scope", func(t *testing.T) {
+		_, err := ParseRetentionRules("WBIL:*=30,WBIL=60")
+		assert.Error(t, err)
+	})
+}
+
+// TestParseRetentionMode тестирует разбор режима хранения.
+func TestParseRetentionMode(t *testing.T) {
+	mode, err := ParseRetentionMode("Anonymize")
+	require.NoError(t, err)
+	assert.Equal(t, RetentionModeAnonymize, mode)
+
+	_, err = ParseRetentionMode("archive")
+	assert.Error(t, err)
+}
+
+// TestRetentionRule_String тестирует строковое представление правила.
+func TestRetentionRule_String(t *testing.T) {
+	assert.Equal(t, "*:*=365", RetentionRule{Days: 365}.String())
+	assert.Equal(t, "WBIL:ru=30", RetentionRule{Entry: "WBIL", Locale: "ru", Days: 30}.String())
+}
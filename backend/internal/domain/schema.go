@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// OrderSchemaVersion версия формы Order. Увеличивается при любом изменении полей,
+// меняющем сериализацию: от нее зависит пространство ключей заказов в кэше,
+// поэтому записи прежней формы не читаются как записи новой.
+const OrderSchemaVersion = 1
+
+// orderUpgrades шаги перевода заказа в JSON-форме версии v в форму версии v+1.
+// Шаги работают с исходными полями записи, а не с Order: переименованное или
+// удаленное поле еще доступно шагу и не теряется при декодировании в новую форму.
+// При увеличении OrderSchemaVersion сюда добавляется шаг для предыдущей версии.
+var orderUpgrades = map[int]func(order map[string]any) error{
+	// Версия 1 совпадает с формой записей, сохранявшихся до введения версий
+	0: func(order map[string]any) error { return nil },
+}
+
+// UpgradeOrder переводит заказ в JSON-форме версии from в текущую и декодирует его
+func UpgradeOrder(raw []byte, from int) (*Order, error) {
+	if from < OrderSchemaVersion {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var fields map[string]any
+		if err := decoder.Decode(&fields); err != nil {
+			return nil, fmt.Errorf("failed to decode order of schema version %d: %w", from, err)
+		}
+		for version := from; version < OrderSchemaVersion; version++ {
+			upgrade, ok := orderUpgrades[version]
+			if !ok {
+				return nil, fmt.Errorf("no upgrade from order schema version %d", version)
+			}
+			if err := upgrade(fields); err != nil {
+				return nil, fmt.Errorf("failed to upgrade order from schema version %d: %w", version, err)
+			}
+		}
+		var err error
+		if raw, err = json.Marshal(fields); err != nil {
+			return nil, fmt.Errorf("failed to encode upgraded order: %w", err)
+		}
+	}
+
+	var order Order
+	if err := json.Unmarshal(raw, &order); err != nil {
+		return nil, fmt.Errorf("failed to decode upgraded order: %w", err)
+	}
+	return &order, nil
+}
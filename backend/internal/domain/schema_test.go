@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderShapes отпечатки формы Order по версиям схемы. При изменении полей Order
+// нужно увеличить OrderSchemaVersion, добавить шаг в orderUpgrades и записать
+// сюда новый отпечаток.
+var orderShapes = map[int]string{
+	1: "60190c46d4535fc0c4c7fec7c620000e973b7cc6ce0a598fd364c7ba28060f82",
+}
+
+func TestUpgradeOrder(t *testing.T) {
+	order, err := UpgradeOrder([]byte(`{"order_uid":"uid","sm_id":99}`), OrderSchemaVersion-1)
+	require.NoError(t, err)
+	assert.Equal(t, "uid", order.OrderUID)
+	assert.Equal(t, 99, order.SmID)
+
+	order, err = UpgradeOrder([]byte(`{"order_uid":"uid"}`), OrderSchemaVersion)
+	require.NoError(t, err)
+	assert.Equal(t, "uid", order.OrderUID)
+
+	_, err = UpgradeOrder([]byte(`{"order_uid":"uid"}`), -1)
+	assert.Error(t, err)
+	_, err = UpgradeOrder([]byte(`not json`), OrderSchemaVersion-1)
+	assert.Error(t, err)
+}
+
+func TestOrderUpgrades_CoverPreviousVersions(t *testing.T) {
+	// Шаг нужен для каждой версии, записи которой могут остаться в кэше
+	for version := 0; version < OrderSchemaVersion; version++ {
+		assert.Contains(t, orderUpgrades, version)
+	}
+}
+
+func TestOrderSchemaVersion_MatchesShape(t *testing.T) {
+	assert.Equal(t, orderShapes[OrderSchemaVersion], shapeOf(reflect.TypeOf(Order{})),
+		"Order fields changed: bump OrderSchemaVersion, add an upgrade step and record the new shape")
+}
+
+// shapeOf возвращает отпечаток сериализуемой формы типа: имена, типы и json-теги полей
+func shapeOf(t reflect.Type) string {
+	var b strings.Builder
+	describeType(&b, t)
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func describeType(b *strings.Builder, t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.PkgPath() != "" && t.PkgPath() != reflect.TypeOf(Order{}).PkgPath() {
+			// Типы стандартной библиотеки (time.Time) сериализуются сами
+			b.WriteString(t.String())
+			return
+		}
+		b.WriteString("{")
+		for i := range t.NumField() {
+			field := t.Field(i)
+			fmt.Fprintf(b, "%s %q ", field.Name, field.Tag.Get("json"))
+			describeType(b, field.Type)
+			b.WriteString(";")
+		}
+		b.WriteString("}")
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		fmt.Fprintf(b, "%s(", t.Kind())
+		describeType(b, t.Elem())
+		b.WriteString(")")
+	default:
+		b.WriteString(t.Kind().String())
+	}
+}
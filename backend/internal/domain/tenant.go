@@ -0,0 +1,21 @@
+package domain
+
+import "context"
+
+// Тенант - канал продаж, определяемый полем Order.Entry (например, "WBIL")
+
+type tenantCtxKey struct{}
+
+// WithTenant возвращает контекст с указанным тенантом
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenant)
+}
+
+// TenantFromContext возвращает тенанта из контекста, если он задан
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantCtxKey{}).(string)
+	if !ok || tenant == "" {
+		return "", false
+	}
+	return tenant, true
+}
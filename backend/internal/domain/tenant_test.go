@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTenantContext тестирует сохранение тенанта в контексте.
+func TestTenantContext(t *testing.T) {
+	_, ok := TenantFromContext(context.Background())
+	assert.False(t, ok)
+
+	_, ok = TenantFromContext(WithTenant(context.Background(), ""))
+	assert.False(t, ok)
+
+	tenant, ok := TenantFromContext(WithTenant(context.Background(), "WBIL"))
+	assert.True(t, ok)
+	assert.Equal(t, "WBIL", tenant)
+}
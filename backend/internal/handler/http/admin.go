@@ -0,0 +1,169 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"strings"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// CacheAdministrator определяет интерфейс сервиса администрирования кэша
+type CacheAdministrator interface {
+	Entry(ctx context.Context, tenant, uid string) (*domain.CacheEntry, error)
+	Evict(ctx context.Context, tenant, uid string) error
+	Flush(ctx context.Context) (int64, error)
+	FlushLocal(ctx context.Context) error
+	Restore(ctx context.Context) error
+	Restoring() bool
+	Stats(ctx context.Context) (*domain.CacheStats, error)
+}
+
+type CacheAdminHandler struct {
+	cacheAdmin CacheAdministrator
+}
+
+func NewCacheAdminHandler(cacheAdmin CacheAdministrator) *CacheAdminHandler {
+	return &CacheAdminHandler{
+		cacheAdmin: cacheAdmin,
+	}
+}
+
+// GetEntry отдает запись заказа в кэше и ее TTL. При изоляции тенантов
+// тенант передается параметром ?tenant=.
+func (h *CacheAdminHandler) GetEntry(w http.ResponseWriter, r *http.Request) {
+	entry, err := h.cacheAdmin.Entry(r.Context(), r.URL.Query().Get("tenant"), chi.URLParam(r, "order_uid"))
+	if err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// Evict удаляет заказ из кэша
+func (h *CacheAdminHandler) Evict(w http.ResponseWriter, r *http.Request) {
+	if err := h.cacheAdmin.Evict(r.Context(), r.URL.Query().Get("tenant"), chi.URLParam(r, "order_uid")); err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Flush удаляет все заказы из кэша
+func (h *CacheAdminHandler) Flush(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.cacheAdmin.Flush(r.Context())
+	if err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"deleted": deleted})
+}
+
+// FlushLocal очищает локальные кэши всех реплик, не затрагивая Redis
+func (h *CacheAdminHandler) FlushLocal(w http.ResponseWriter, r *http.Request) {
+	if err := h.cacheAdmin.FlushLocal(r.Context()); err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Restore запускает восстановление кэша из базы и сразу возвращает 202 Accepted
+func (h *CacheAdminHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	if err := h.cacheAdmin.Restore(r.Context()); err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// cacheStatsResponse статистика кэша с признаком выполняющегося восстановления
+type cacheStatsResponse struct {
+	*domain.CacheStats
+	Restoring bool `json:"restoring"`
+}
+
+// Stats отдает число заказов в кэше, занятую память и долю попаданий
+func (h *CacheAdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.cacheAdmin.Stats(r.Context())
+	if err != nil {
+		writeCacheAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, cacheStatsResponse{CacheStats: stats, Restoring: h.cacheAdmin.Restoring()})
+}
+
+func writeCacheAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotCached):
+		http.Error(w, "Order is not cached", http.StatusNotFound)
+	case errors.Is(err, service.ErrTenantRequired):
+		http.Error(w, "tenant is required", http.StatusBadRequest)
+	case errors.Is(err, service.ErrRestoreInProgress):
+		http.Error(w, "Cache restore is already in progress", http.StatusConflict)
+	default:
+		http.Error(w, "Cache operation failed", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// AdminAuthMiddleware пропускает запросы с ключом администратора в заголовке
+// X-Admin-Key (или Authorization: Bearer)
+func AdminAuthMiddleware(adminKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-Admin-Key")
+			if key == "" {
+				key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+
+			if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) != 1 {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewAdminRouter создает роутер административного API, монтируемый в /admin.
+// dlqHandler может быть nil, если сообщения DLQ не сохраняются в базе.
+func NewAdminRouter(adminKey string, cacheAdminHandler *CacheAdminHandler, dlqHandler *DLQHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(AdminAuthMiddleware(adminKey))
+
+	// Метрики (состояние кэша, прогрева, шины инвалидаций) доступны только администратору
+	r.Handle("/debug/vars", expvar.Handler())
+
+	r.Route("/cache", func(r chi.Router) {
+		r.Get("/stats", cacheAdminHandler.Stats)
+		r.Post("/restore", cacheAdminHandler.Restore)
+		r.Delete("/orders", cacheAdminHandler.Flush)
+		r.Delete("/local", cacheAdminHandler.FlushLocal)
+		r.Get("/orders/{order_uid}", cacheAdminHandler.GetEntry)
+		r.Delete("/orders/{order_uid}", cacheAdminHandler.Evict)
+	})
+
+	if dlqHandler != nil {
+		r.Route("/dlq", func(r chi.Router) {
+			r.Get("/", dlqHandler.List)
+			r.Get("/{id}", dlqHandler.Get)
+			r.Patch("/{id}", dlqHandler.Annotate)
+			r.Post("/{id}/discard", dlqHandler.Discard)
+			r.Post("/{id}/requeue", dlqHandler.Requeue)
+		})
+	}
+
+	return r
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCacheAdmin является моком для интерфейса CacheAdministrator
+type mockCacheAdmin struct {
+	mock.Mock
+}
+
+func (m *mockCacheAdmin) Entry(ctx context.Context, tenant, uid string) (*domain.CacheEntry, error) {
+	args := m.Called(ctx, tenant, uid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CacheEntry), args.Error(1)
+}
+
+func (m *mockCacheAdmin) Evict(ctx context.Context, tenant, uid string) error {
+	return m.Called(ctx, tenant, uid).Error(0)
+}
+
+func (m *mockCacheAdmin) Flush(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockCacheAdmin) FlushLocal(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *mockCacheAdmin) Restore(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *mockCacheAdmin) Restoring() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *mockCacheAdmin) Stats(ctx context.Context) (*domain.CacheStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CacheStats), args.Error(1)
+}
+
+const testAdminKey = "admin-secret"
+
+// serveAdmin выполняет запрос к административному API через общий роутер
+func serveAdmin(cacheAdmin *mockCacheAdmin, method, target, key string) *httptest.ResponseRecorder {
+	router := NewRouter(nil, NewAdminRouter(testAdminKey, NewCacheAdminHandler(cacheAdmin), nil), func(ctx context.Context) error { return nil })
+	req := httptest.NewRequest(method, target, nil)
+	if key != "" {
+		req.Header.Set("X-Admin-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestCacheAdminHandler_Auth тестирует проверку ключа администратора.
+func TestCacheAdminHandler_Auth(t *testing.T) {
+	cacheAdmin := new(mockCacheAdmin)
+
+	assert.Equal(t, http.StatusUnauthorized, serveAdmin(cacheAdmin, http.MethodGet, "/admin/cache/stats", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serveAdmin(cacheAdmin, http.MethodGet, "/admin/cache/stats", "wrong").Code)
+	cacheAdmin.AssertNotCalled(t, "Stats", mock.Anything)
+
+	t.Run("debug vars", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serveAdmin(cacheAdmin, http.MethodGet, "/admin/debug/vars", "").Code)
+		assert.Equal(t, http.StatusOK, serveAdmin(cacheAdmin, http.MethodGet, "/admin/debug/vars", testAdminKey).Code)
+		assert.Equal(t, http.StatusNotFound, serveAdmin(cacheAdmin, http.MethodGet, "/debug/vars", testAdminKey).Code)
+	})
+}
+
+// TestCacheAdminHandler_GetEntry тестирует получение записи кэша.
+func TestCacheAdminHandler_GetEntry(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		cacheAdmin := new(mockCacheAdmin)
+		entry := &domain.CacheEntry{Key: "order:WBIL:test-uid", Format: "msgpack+zstd", Size: 120, TTLSeconds: 30, Order: json.RawMessage(`{"order_uid":"test-uid"}`)}
+		cacheAdmin.On("Entry", mock.Anything, "WBIL", "test-uid").Return(entry, nil).Once()
+
+		w := serveAdmin(cacheAdmin, http.MethodGet, "/admin/cache/orders/test-uid?tenant=WBIL", testAdminKey)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"key":"order:WBIL:test-uid","format":"msgpack+zstd","size_bytes":120,"ttl_seconds":30,"order":{"order_uid":"test-uid"}}`, w.Body.String())
+		cacheAdmin.AssertExpectations(t)
+	})
+
+	t.Run("not cached", func(t *testing.T) {
+		cacheAdmin := new(mockCacheAdmin)
+		cacheAdmin.On("Entry", mock.Anything, "", "test-uid").Return(nil, service.ErrNotCached).Once()
+
+		w := serveAdmin(cacheAdmin, http.MethodGet, "/admin/cache/orders/test-uid", testAdminKey)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("tenant required", func(t *testing.T) {
+		cacheAdmin := new(mockCacheAdmin)
+		cacheAdmin.On("Entry", mock.Anything, "", "test-uid").Return(nil, service.ErrTenantRequired).Once()
+
+		w := serveAdmin(cacheAdmin, http.MethodGet, "/admin/cache/orders/test-uid", testAdminKey)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// TestCacheAdminHandler_EvictAndFlush тестирует удаление заказа и очистку кэша.
+func TestCacheAdminHandler_EvictAndFlush(t *testing.T) {
+	cacheAdmin := new(mockCacheAdmin)
+	cacheAdmin.On("Evict", mock.Anything, "", "test-uid").Return(nil).Once()
+	cacheAdmin.On("Flush", mock.Anything).Return(int64(42), nil).Once()
+	cacheAdmin.On("FlushLocal", mock.Anything).Return(nil).Once()
+
+	w := serveAdmin(cacheAdmin, http.MethodDelete, "/admin/cache/orders/test-uid", testAdminKey)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serveAdmin(cacheAdmin, http.MethodDelete, "/admin/cache/orders", testAdminKey)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"deleted":42}`, w.Body.String())
+
+	w = serveAdmin(cacheAdmin, http.MethodDelete, "/admin/cache/local", testAdminKey)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	cacheAdmin.AssertExpectations(t)
+}
+
+// TestCacheAdminHandler_Restore тестирует запуск восстановления кэша.
+func TestCacheAdminHandler_Restore(t *testing.T) {
+	cacheAdmin := new(mockCacheAdmin)
+	cacheAdmin.On("Restore", mock.Anything).Return(nil).Once()
+	cacheAdmin.On("Restore", mock.Anything).Return(service.ErrRestoreInProgress).Once()
+
+	assert.Equal(t, http.StatusAccepted, serveAdmin(cacheAdmin, http.MethodPost, "/admin/cache/restore", testAdminKey).Code)
+	assert.Equal(t, http.StatusConflict, serveAdmin(cacheAdmin, http.MethodPost, "/admin/cache/restore", testAdminKey).Code)
+	cacheAdmin.AssertExpectations(t)
+}
+
+// TestCacheAdminHandler_Stats тестирует получение статистики кэша.
+func TestCacheAdminHandler_Stats(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cacheAdmin := new(mockCacheAdmin)
+		cacheAdmin.On("Stats", mock.Anything).Return(&domain.CacheStats{Keys: 10, UsedMemory: 2048, Hits: 3, Misses: 1, HitRatio: 0.75}, nil).Once()
+		cacheAdmin.On("Restoring").Return(true).Once()
+
+		w := serveAdmin(cacheAdmin, http.MethodGet, "/admin/cache/stats", testAdminKey)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, float64(10), body["keys"])
+		assert.Equal(t, 0.75, body["hit_ratio"])
+		assert.Equal(t, true, body["restoring"])
+	})
+
+	t.Run("redis unavailable", func(t *testing.T) {
+		cacheAdmin := new(mockCacheAdmin)
+		cacheAdmin.On("Stats", mock.Anything).Return(nil, errors.New("redis is unavailable")).Once()
+
+		w := serveAdmin(cacheAdmin, http.MethodGet, "/admin/cache/stats", testAdminKey)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
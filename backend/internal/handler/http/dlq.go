@@ -0,0 +1,163 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// DLQAdministrator определяет интерфейс сервиса разбора сообщений DLQ
+type DLQAdministrator interface {
+	List(ctx context.Context, filter domain.DLQFilter) ([]*domain.DLQEntry, error)
+	Get(ctx context.Context, id int64) (*domain.DLQEntry, error)
+	Annotate(ctx context.Context, id int64, note string) error
+	Discard(ctx context.Context, id int64) error
+	Requeue(ctx context.Context, id int64) error
+}
+
+type DLQHandler struct {
+	dlq DLQAdministrator
+}
+
+func NewDLQHandler(dlq DLQAdministrator) *DLQHandler {
+	return &DLQHandler{
+		dlq: dlq,
+	}
+}
+
+// List отдает сообщения DLQ без payload. Фильтры: ?status=, ?error_class=, ?key=, ?reason= (подстрока),
+// постраничный вывод через ?limit= и ?offset=.
+func (h *DLQHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.DLQFilter{
+		Status:     domain.DLQStatus(query.Get("status")),
+		ErrorClass: domain.ErrorClass(query.Get("error_class")),
+		Key:        query.Get("key"),
+		Reason:     query.Get("reason"),
+	}
+
+	var err error
+	if filter.Limit, err = intParam(query.Get("limit")); err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	if filter.Offset, err = intParam(query.Get("offset")); err != nil {
+		http.Error(w, "Invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.dlq.List(r.Context(), filter)
+	if err != nil {
+		writeDLQError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// dlqEntryResponse сообщение DLQ с payload: payload может быть невалидным JSON, поэтому отдается строкой
+type dlqEntryResponse struct {
+	*domain.DLQEntry
+	Payload string `json:"payload"`
+}
+
+// Get отдает сообщение DLQ с payload и заголовками
+func (h *DLQHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := dlqID(w, r)
+	if !ok {
+		return
+	}
+	entry, err := h.dlq.Get(r.Context(), id)
+	if err != nil {
+		writeDLQError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dlqEntryResponse{DLQEntry: entry, Payload: string(entry.Payload)})
+}
+
+// annotateRequest тело PATCH /dlq/{id}
+type annotateRequest struct {
+	Note string `json:"note"`
+}
+
+// Annotate сохраняет заметку оператора
+func (h *DLQHandler) Annotate(w http.ResponseWriter, r *http.Request) {
+	id, ok := dlqID(w, r)
+	if !ok {
+		return
+	}
+	var req annotateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.dlq.Annotate(r.Context(), id, req.Note); err != nil {
+		writeDLQError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Discard отбрасывает сообщение
+func (h *DLQHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	id, ok := dlqID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.dlq.Discard(r.Context(), id); err != nil {
+		writeDLQError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Requeue публикует сообщение обратно в исходный топик
+func (h *DLQHandler) Requeue(w http.ResponseWriter, r *http.Request) {
+	id, ok := dlqID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.dlq.Requeue(r.Context(), id); err != nil {
+		writeDLQError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": string(domain.DLQStatusRequeued)})
+}
+
+// dlqID разбирает id из пути, при ошибке отвечает 400
+func dlqID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// intParam разбирает необязательный неотрицательный числовой параметр запроса
+func intParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid number")
+	}
+	return n, nil
+}
+
+func writeDLQError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrDLQEntryNotFound):
+		http.Error(w, "DLQ entry not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrDLQEntryClosed):
+		http.Error(w, "DLQ entry is already discarded or requeued", http.StatusConflict)
+	default:
+		http.Error(w, "DLQ operation failed", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/Ravwvil/order-service/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDLQAdmin является моком для интерфейса DLQAdministrator
+type mockDLQAdmin struct {
+	mock.Mock
+}
+
+func (m *mockDLQAdmin) List(ctx context.Context, filter domain.DLQFilter) ([]*domain.DLQEntry, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.DLQEntry), args.Error(1)
+}
+
+func (m *mockDLQAdmin) Get(ctx context.Context, id int64) (*domain.DLQEntry, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DLQEntry), args.Error(1)
+}
+
+func (m *mockDLQAdmin) Annotate(ctx context.Context, id int64, note string) error {
+	return m.Called(ctx, id, note).Error(0)
+}
+
+func (m *mockDLQAdmin) Discard(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *mockDLQAdmin) Requeue(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+// serveDLQ выполняет запрос к API разбора DLQ через общий роутер
+func serveDLQ(dlqAdmin *mockDLQAdmin, method, target, body string) *httptest.ResponseRecorder {
+	adminRouter := NewAdminRouter(testAdminKey, NewCacheAdminHandler(new(mockCacheAdmin)), NewDLQHandler(dlqAdmin))
+	router := NewRouter(nil, adminRouter, func(ctx context.Context) error { return nil })
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-Admin-Key", testAdminKey)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestDLQHandler_List тестирует выборку сообщений DLQ.
+func TestDLQHandler_List(t *testing.T) {
+	dlqAdmin := new(mockDLQAdmin)
+	filter := domain.DLQFilter{
+		Status:     domain.DLQStatusOpen,
+		ErrorClass: domain.ErrorClassPermanent,
+		Reason:     "validation",
+		Limit:      20,
+		Offset:     40,
+	}
+	dlqAdmin.On("List", mock.Anything, filter).Return([]*domain.DLQEntry{{ID: 1, Key: "uid-1", Payload: []byte("{}")}}, nil).Once()
+
+	w := serveDLQ(dlqAdmin, http.MethodGet, "/admin/dlq?status=open&error_class=permanent&reason=validation&limit=20&offset=40", "")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var body []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body, 1)
+	assert.Equal(t, "uid-1", body[0]["key"])
+	assert.NotContains(t, body[0], "payload")
+	dlqAdmin.AssertExpectations(t)
+
+	assert.Equal(t, http.StatusBadRequest, serveDLQ(dlqAdmin, http.MethodGet, "/admin/dlq?limit=-1", "").Code)
+}
+
+// TestDLQHandler_Get тестирует получение сообщения DLQ.
+func TestDLQHandler_Get(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		dlqAdmin := new(mockDLQAdmin)
+		entry := &domain.DLQEntry{ID: 7, Key: "uid-1", Payload: []byte("not json"), Headers: map[string]string{"x-failure-reason": "unmarshal order"}}
+		dlqAdmin.On("Get", mock.Anything, int64(7)).Return(entry, nil).Once()
+
+		w := serveDLQ(dlqAdmin, http.MethodGet, "/admin/dlq/7", "")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "not json", body["payload"])
+		assert.Equal(t, map[string]any{"x-failure-reason": "unmarshal order"}, body["headers"])
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		dlqAdmin := new(mockDLQAdmin)
+		dlqAdmin.On("Get", mock.Anything, int64(7)).Return(nil, domain.ErrDLQEntryNotFound).Once()
+
+		assert.Equal(t, http.StatusNotFound, serveDLQ(dlqAdmin, http.MethodGet, "/admin/dlq/7", "").Code)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serveDLQ(new(mockDLQAdmin), http.MethodGet, "/admin/dlq/abc", "").Code)
+	})
+}
+
+// TestDLQHandler_Triage тестирует заметку, отбрасывание и переотправку сообщения.
+func TestDLQHandler_Triage(t *testing.T) {
+	dlqAdmin := new(mockDLQAdmin)
+	dlqAdmin.On("Annotate", mock.Anything, int64(7), "bad producer build").Return(nil).Once()
+	dlqAdmin.On("Discard", mock.Anything, int64(7)).Return(nil).Once()
+	dlqAdmin.On("Requeue", mock.Anything, int64(7)).Return(service.ErrDLQEntryClosed).Once()
+	dlqAdmin.On("Requeue", mock.Anything, int64(8)).Return(nil).Once()
+
+	assert.Equal(t, http.StatusNoContent, serveDLQ(dlqAdmin, http.MethodPatch, "/admin/dlq/7", `{"note":"bad producer build"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveDLQ(dlqAdmin, http.MethodPatch, "/admin/dlq/7", `{`).Code)
+	assert.Equal(t, http.StatusNoContent, serveDLQ(dlqAdmin, http.MethodPost, "/admin/dlq/7/discard", "").Code)
+	assert.Equal(t, http.StatusConflict, serveDLQ(dlqAdmin, http.MethodPost, "/admin/dlq/7/requeue", "").Code)
+	assert.Equal(t, http.StatusAccepted, serveDLQ(dlqAdmin, http.MethodPost, "/admin/dlq/8/requeue", "").Code)
+	dlqAdmin.AssertExpectations(t)
+}
+
+// TestDLQHandler_NotMounted проверяет, что без DLQHandler маршруты DLQ не регистрируются.
+func TestDLQHandler_NotMounted(t *testing.T) {
+	w := serveAdmin(new(mockCacheAdmin), http.MethodGet, "/admin/dlq", testAdminKey)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -3,7 +3,11 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"hash/fnv"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/Ravwvil/order-service/backend/internal/config"
 	"github.com/Ravwvil/order-service/backend/internal/domain"
@@ -11,11 +15,17 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// OrderServicer определяет интерфейс для сервиса 
+// OrderServicer определяет интерфейс для сервиса
 type OrderServicer interface {
-	GetOrderByUID(ctx context.Context, uid string) (*domain.Order, error)
+	GetOrderJSON(ctx context.Context, uid string) ([]byte, error)
+	GetOrdersByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error)
+	GetOrdersByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error)
+	GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error)
 }
 
+// maxBatchSize ограничивает число UID в одном пакетном запросе
+const maxBatchSize = 100
+
 type OrderHandler struct {
 	orderService OrderServicer
 }
@@ -26,6 +36,8 @@ func NewOrderHandler(orderService OrderServicer) *OrderHandler {
 	}
 }
 
+// GetOrderByUID отдает заказ в JSON. Закэшированные данные пишутся в ответ
+// без повторной сериализации; ETag позволяет клиенту получить 304 Not Modified.
 func (h *OrderHandler) GetOrderByUID(w http.ResponseWriter, r *http.Request) {
 	uid := chi.URLParam(r, "order_uid")
 	if uid == "" {
@@ -33,19 +45,152 @@ func (h *OrderHandler) GetOrderByUID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	order, err := h.orderService.GetOrderByUID(r.Context(), uid)
+	data, err := h.orderService.GetOrderJSON(r.Context(), uid)
 	if err != nil {
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
 
+	etag := computeETag(data)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+// computeETag возвращает сильный ETag по содержимому ответа
+func computeETag(data []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return `"` + strconv.FormatUint(h.Sum64(), 16) + `"`
+}
+
+// etagMatches проверяет заголовок If-None-Match (список ETag или *)
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOrdersByUIDs возвращает заказы по списку UID: /orders?uid=a&uid=b или /orders?uid=a,b.
+// Ненайденные заказы в ответ не попадают.
+func (h *OrderHandler) GetOrdersByUIDs(w http.ResponseWriter, r *http.Request) {
+	var uids []string
+	for _, value := range r.URL.Query()["uid"] {
+		for _, uid := range strings.Split(value, ",") {
+			if uid = strings.TrimSpace(uid); uid != "" {
+				uids = append(uids, uid)
+			}
+		}
+	}
+	if len(uids) == 0 {
+		http.Error(w, "uid is required", http.StatusBadRequest)
+		return
+	}
+	if len(uids) > maxBatchSize {
+		http.Error(w, "too many uids", http.StatusBadRequest)
+		return
+	}
+
+	orders, err := h.orderService.GetOrdersByUIDs(r.Context(), uids)
+	if err != nil {
+		http.Error(w, "Failed to get orders", http.StatusInternalServerError)
+		return
+	}
+	if orders == nil {
+		orders = []*domain.Order{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(orders); err != nil {
+		http.Error(w, "Failed to encode orders", http.StatusInternalServerError)
+	}
+}
+
+// GetOrdersByCustomer возвращает заказы покупателя (сначала новые)
+func (h *OrderHandler) GetOrdersByCustomer(w http.ResponseWriter, r *http.Request) {
+	customerID := chi.URLParam(r, "customer_id")
+	if customerID == "" {
+		http.Error(w, "customer_id is required", http.StatusBadRequest)
+		return
+	}
+
+	orders, err := h.orderService.GetOrdersByCustomer(r.Context(), customerID)
+	if err != nil {
+		http.Error(w, "Failed to get orders", http.StatusInternalServerError)
+		return
+	}
+	if orders == nil {
+		orders = []*domain.Order{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(orders); err != nil {
+		http.Error(w, "Failed to encode orders", http.StatusInternalServerError)
+	}
+}
+
+// GetOrderByTrackNumber возвращает заказ по трек-номеру
+func (h *OrderHandler) GetOrderByTrackNumber(w http.ResponseWriter, r *http.Request) {
+	trackNumber := chi.URLParam(r, "track_number")
+	if trackNumber == "" {
+		http.Error(w, "track_number is required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.GetOrderByTrackNumber(r.Context(), trackNumber)
+	if err != nil {
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get order", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(order); err != nil {
 		http.Error(w, "Failed to encode order", http.StatusInternalServerError)
 	}
 }
 
-func NewRouter(orderHandler *OrderHandler, healthCheck func(ctx context.Context) error) http.Handler {
+// TenantMiddleware определяет тенанта по API-ключу из заголовка X-API-Key
+// (или Authorization: Bearer) и сохраняет его в контексте запроса.
+// apiKeys - соответствие ключа тенанту (entry).
+func TenantMiddleware(apiKeys map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+
+			tenant, ok := apiKeys[key]
+			if key == "" || !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithTenant(r.Context(), tenant)))
+		})
+	}
+}
+
+// NewRouter создает роутер. Middlewares применяются только к API заказов.
+// admin монтируется в /admin, если задан (см. NewAdminRouter).
+func NewRouter(orderHandler *OrderHandler, admin http.Handler, healthCheck func(ctx context.Context) error, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
@@ -53,13 +198,29 @@ func NewRouter(orderHandler *OrderHandler, healthCheck func(ctx context.Context)
 
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		if err := healthCheck(r.Context()); err != nil {
+			// Деградация (например, недоступен Redis) не выводит реплику из балансировки
+			var degraded interface{ Degraded() bool }
+			if errors.As(err, &degraded) && degraded.Degraded() {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("degraded: " + err.Error()))
+				return
+			}
 			http.Error(w, "health check failed", http.StatusServiceUnavailable)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
 	})
+	if admin != nil {
+		r.Mount("/admin", admin)
+	}
 
-	r.Get("/order/{order_uid}", orderHandler.GetOrderByUID)
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares...)
+		r.Get("/order/{order_uid}", orderHandler.GetOrderByUID)
+		r.Get("/orders", orderHandler.GetOrdersByUIDs)
+		r.Get("/customer/{customer_id}/orders", orderHandler.GetOrdersByCustomer)
+		r.Get("/track/{track_number}", orderHandler.GetOrderByTrackNumber)
+	})
 
 	return r
 }
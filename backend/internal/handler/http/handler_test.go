@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/Ravwvil/order-service/backend/internal/domain"
@@ -21,9 +24,39 @@ type mockOrderService struct {
 	mock.Mock
 }
 
-// GetOrderByUID мокает метод GetOrderByUID
-func (m *mockOrderService) GetOrderByUID(ctx context.Context, uid string) (*domain.Order, error) {
+// GetOrderJSON мокает метод GetOrderJSON
+func (m *mockOrderService) GetOrderJSON(ctx context.Context, uid string) ([]byte, error) {
 	args := m.Called(ctx, uid)
+	var data []byte
+	if args.Get(0) != nil {
+		data = args.Get(0).([]byte)
+	}
+	return data, args.Error(1)
+}
+
+// GetOrdersByUIDs мокает метод GetOrdersByUIDs
+func (m *mockOrderService) GetOrdersByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error) {
+	args := m.Called(ctx, uids)
+	var orders []*domain.Order
+	if args.Get(0) != nil {
+		orders = args.Get(0).([]*domain.Order)
+	}
+	return orders, args.Error(1)
+}
+
+// GetOrdersByCustomer мокает метод GetOrdersByCustomer
+func (m *mockOrderService) GetOrdersByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	args := m.Called(ctx, customerID)
+	var orders []*domain.Order
+	if args.Get(0) != nil {
+		orders = args.Get(0).([]*domain.Order)
+	}
+	return orders, args.Error(1)
+}
+
+// GetOrderByTrackNumber мокает метод GetOrderByTrackNumber
+func (m *mockOrderService) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error) {
+	args := m.Called(ctx, trackNumber)
 	var order *domain.Order
 	if args.Get(0) != nil {
 		order = args.Get(0).(*domain.Order)
@@ -43,10 +76,12 @@ func getTestOrder() *domain.Order {
 func TestOrderHandler_GetOrderByUID(t *testing.T) {
 	testOrder := getTestOrder()
 	uid := testOrder.OrderUID
+	orderJSON, err := json.Marshal(testOrder)
+	require.NoError(t, err)
 
 	t.Run("success", func(t *testing.T) {
 		orderService := new(mockOrderService)
-		orderService.On("GetOrderByUID", mock.Anything, uid).Return(testOrder, nil).Once()
+		orderService.On("GetOrderJSON", mock.Anything, uid).Return(orderJSON, nil).Once()
 		handler := NewOrderHandler(orderService)
 
 		req := httptest.NewRequest(http.MethodGet, "/order/"+uid, nil)
@@ -68,13 +103,49 @@ func TestOrderHandler_GetOrderByUID(t *testing.T) {
 		err = json.Unmarshal(data, &receivedOrder)
 		require.NoError(t, err)
 		assert.Equal(t, testOrder.OrderUID, receivedOrder.OrderUID)
+		assert.Equal(t, orderJSON, data)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		assert.Equal(t, strconv.Itoa(len(orderJSON)), res.Header.Get("Content-Length"))
+		assert.NotEmpty(t, res.Header.Get("ETag"))
 
 		orderService.AssertExpectations(t)
 	})
 
+	t.Run("not modified", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrderJSON", mock.Anything, uid).Return(orderJSON, nil).Twice()
+		handler := NewOrderHandler(orderService)
+
+		request := func(ifNoneMatch string) *http.Response {
+			req := httptest.NewRequest(http.MethodGet, "/order/"+uid, nil)
+			if ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", ifNoneMatch)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("order_uid", uid)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.GetOrderByUID(w, req)
+			return w.Result()
+		}
+
+		first := request("")
+		defer first.Body.Close()
+		etag := first.Header.Get("ETag")
+
+		second := request(`"other", ` + etag)
+		defer second.Body.Close()
+		body, err := io.ReadAll(second.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotModified, second.StatusCode)
+		assert.Equal(t, etag, second.Header.Get("ETag"))
+		assert.Empty(t, body)
+	})
+
 	t.Run("not found", func(t *testing.T) {
 		orderService := new(mockOrderService)
-		orderService.On("GetOrderByUID", mock.Anything, uid).Return(nil, errors.New("not found")).Once()
+		orderService.On("GetOrderJSON", mock.Anything, uid).Return(nil, errors.New("not found")).Once()
 		handler := NewOrderHandler(orderService)
 
 		req := httptest.NewRequest(http.MethodGet, "/order/"+uid, nil)
@@ -107,7 +178,158 @@ func TestOrderHandler_GetOrderByUID(t *testing.T) {
 		res := w.Result()
 		defer res.Body.Close()
 		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
-		orderService.AssertNotCalled(t, "GetOrderByUID")
+		orderService.AssertNotCalled(t, "GetOrderJSON")
+	})
+}
+
+// TestOrderHandler_GetOrdersByUIDs тестирует пакетный обработчик.
+func TestOrderHandler_GetOrdersByUIDs(t *testing.T) {
+	testOrder := getTestOrder()
+
+	t.Run("success", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrdersByUIDs", mock.Anything, []string{"test-uid", "a", "b"}).
+			Return([]*domain.Order{testOrder}, nil).Once()
+		handler := NewOrderHandler(orderService)
+
+		req := httptest.NewRequest(http.MethodGet, "/orders?uid=test-uid&uid=a,b", nil)
+		w := httptest.NewRecorder()
+
+		handler.GetOrdersByUIDs(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var received []domain.Order
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &received))
+		require.Len(t, received, 1)
+		assert.Equal(t, testOrder.OrderUID, received[0].OrderUID)
+		orderService.AssertExpectations(t)
+	})
+
+	t.Run("nothing found", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrdersByUIDs", mock.Anything, []string{"a"}).Return(nil, nil).Once()
+		handler := NewOrderHandler(orderService)
+
+		req := httptest.NewRequest(http.MethodGet, "/orders?uid=a", nil)
+		w := httptest.NewRecorder()
+
+		handler.GetOrdersByUIDs(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, "[]", w.Body.String())
+	})
+
+	t.Run("missing uid", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		handler := NewOrderHandler(orderService)
+
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		w := httptest.NewRecorder()
+
+		handler.GetOrdersByUIDs(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		orderService.AssertNotCalled(t, "GetOrdersByUIDs")
+	})
+
+	t.Run("too many uids", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		handler := NewOrderHandler(orderService)
+
+		uids := make([]string, maxBatchSize+1)
+		for i := range uids {
+			uids[i] = "uid"
+		}
+		req := httptest.NewRequest(http.MethodGet, "/orders?uid="+strings.Join(uids, ","), nil)
+		w := httptest.NewRecorder()
+
+		handler.GetOrdersByUIDs(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		orderService.AssertNotCalled(t, "GetOrdersByUIDs")
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrdersByUIDs", mock.Anything, []string{"a"}).Return(nil, errors.New("db down")).Once()
+		handler := NewOrderHandler(orderService)
+
+		req := httptest.NewRequest(http.MethodGet, "/orders?uid=a", nil)
+		w := httptest.NewRecorder()
+
+		handler.GetOrdersByUIDs(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+// TestOrderHandler_SecondaryLookups тестирует поиск заказов по покупателю и трек-номеру.
+func TestOrderHandler_SecondaryLookups(t *testing.T) {
+	testOrder := getTestOrder()
+	noHealthCheck := func(ctx context.Context) error { return nil }
+
+	t.Run("customer orders", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrdersByCustomer", mock.Anything, "customer").Return([]*domain.Order{testOrder}, nil).Once()
+		router := NewRouter(NewOrderHandler(orderService), nil, noHealthCheck)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/customer/customer/orders", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var received []domain.Order
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &received))
+		require.Len(t, received, 1)
+		assert.Equal(t, testOrder.OrderUID, received[0].OrderUID)
+	})
+
+	t.Run("customer without orders", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrdersByCustomer", mock.Anything, "customer").Return(nil, nil).Once()
+		router := NewRouter(NewOrderHandler(orderService), nil, noHealthCheck)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/customer/customer/orders", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, "[]", w.Body.String())
+	})
+
+	t.Run("track number", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrderByTrackNumber", mock.Anything, "TRACK").Return(testOrder, nil).Once()
+		router := NewRouter(NewOrderHandler(orderService), nil, noHealthCheck)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/track/TRACK", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var received domain.Order
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &received))
+		assert.Equal(t, testOrder.OrderUID, received.OrderUID)
+	})
+
+	t.Run("track number not found", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrderByTrackNumber", mock.Anything, "TRACK").
+			Return(nil, fmt.Errorf("failed to get order: %w", domain.ErrOrderNotFound)).Once()
+		router := NewRouter(NewOrderHandler(orderService), nil, noHealthCheck)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/track/TRACK", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("track number service error", func(t *testing.T) {
+		orderService := new(mockOrderService)
+		orderService.On("GetOrderByTrackNumber", mock.Anything, "TRACK").Return(nil, errors.New("db down")).Once()
+		router := NewRouter(NewOrderHandler(orderService), nil, noHealthCheck)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/track/TRACK", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
 
@@ -117,7 +339,7 @@ func TestNewRouter_HealthCheck(t *testing.T) {
 		healthCheck := func(ctx context.Context) error {
 			return nil
 		}
-		router := NewRouter(nil, healthCheck)
+		router := NewRouter(nil, nil, healthCheck)
 
 		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
 		w := httptest.NewRecorder()
@@ -131,7 +353,7 @@ func TestNewRouter_HealthCheck(t *testing.T) {
 		healthCheck := func(ctx context.Context) error {
 			return errors.New("db is down")
 		}
-		router := NewRouter(nil, healthCheck)
+		router := NewRouter(nil, nil, healthCheck)
 
 		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
 		w := httptest.NewRecorder()
@@ -140,4 +362,94 @@ func TestNewRouter_HealthCheck(t *testing.T) {
 
 		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 	})
+
+	t.Run("degraded", func(t *testing.T) {
+		healthCheck := func(ctx context.Context) error {
+			return degradedError{}
+		}
+		router := NewRouter(nil, nil, healthCheck)
+
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "degraded")
+	})
+}
+
+// degradedError ошибка проверки здоровья, означающая деградацию.
+type degradedError struct{}
+
+func (degradedError) Error() string  { return "redis is unavailable" }
+func (degradedError) Degraded() bool { return true }
+
+// TestTenantMiddleware тестирует определение тенанта по API-ключу.
+func TestTenantMiddleware(t *testing.T) {
+	apiKeys := map[string]string{"wbil-key": "WBIL"}
+
+	var gotTenant string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant, _ = domain.TenantFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := TenantMiddleware(apiKeys)(next)
+
+	t.Run("x-api-key header", func(t *testing.T) {
+		gotTenant = ""
+		req := httptest.NewRequest(http.MethodGet, "/order/test-uid", nil)
+		req.Header.Set("X-API-Key", "wbil-key")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "WBIL", gotTenant)
+	})
+
+	t.Run("bearer token", func(t *testing.T) {
+		gotTenant = ""
+		req := httptest.NewRequest(http.MethodGet, "/order/test-uid", nil)
+		req.Header.Set("Authorization", "Bearer wbil-key")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "WBIL", gotTenant)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/order/test-uid", nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/order/test-uid", nil)
+		req.Header.Set("X-API-Key", "other-key")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("healthz is not protected", func(t *testing.T) {
+		router := NewRouter(NewOrderHandler(new(mockOrderService)), nil, func(ctx context.Context) error { return nil }, TenantMiddleware(apiKeys))
+
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/order/test-uid", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
 }
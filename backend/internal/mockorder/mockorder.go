@@ -0,0 +1,123 @@
+// Package mockorder генерирует тестовые заказы для publisher и бенчмарков.
+package mockorder
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// Generate создает count случайных заказов, похожих на реальные
+func Generate(count int) []domain.Order {
+	if count <= 0 {
+		return []domain.Order{}
+	}
+
+	numWorkers := runtime.NumCPU()
+	if count < numWorkers {
+		numWorkers = count
+	}
+
+	jobs := make(chan int, count)
+	results := make(chan domain.Order, count)
+	cities := []string{"Moscow", "Kazan", "Innopolis", "Penza", "Krasnodar", "St. Petersburg", "Novosibirsk"}
+	names := []string{"Ravil Kazeev", "Dmitriy Kuznetsov", "Vladimir Base", "Alexey Ivanov ", "Anna Petrova"}
+
+	worker := func(jobs <-chan int, results chan<- domain.Order, workerID int) {
+		r := rand.New(rand.NewSource(int64(workerID))) // устанавливаем разный seed для каждого инстанса
+
+		for range jobs {
+			orderUID := generateRandomString(r, 19)
+			trackNumber := generateRandomString(r, 13)
+			now := time.Now()
+
+			goodsTotal := r.Intn(15000) + 500
+			deliveryCost := r.Intn(2000) + 500
+			customFee := 0
+
+			itemsCount := r.Intn(4) + 1
+			var items []domain.Item
+			for j := 0; j < itemsCount; j++ {
+				itemPrice := r.Intn(4000) + 200
+				item := domain.Item{
+					ChrtID:      r.Intn(1000000),
+					TrackNumber: trackNumber,
+					Price:       itemPrice,
+					Rid:         generateRandomString(r, 21),
+					Name:        fmt.Sprintf("Item-%d", j+1),
+					Sale:        r.Intn(60),
+					Size:        "0",
+					TotalPrice:  itemPrice - (itemPrice * r.Intn(30) / 100),
+					NmID:        r.Intn(5000000),
+					Brand:       "Some Brand",
+					Status:      202,
+				}
+				items = append(items, item)
+			}
+
+			order := domain.Order{
+				OrderUID:    orderUID,
+				TrackNumber: trackNumber,
+				Entry:       "WBIL",
+				Delivery: domain.Delivery{
+					Name:    names[r.Intn(len(names))],
+					Phone:   fmt.Sprintf("+79%09d", r.Intn(1000000000)),
+					Zip:     fmt.Sprintf("%06d", r.Intn(1000000)),
+					City:    cities[r.Intn(len(cities))],
+					Address: fmt.Sprintf("Some Street %d", r.Intn(100)+1),
+					Region:  "Some Region",
+					Email:   fmt.Sprintf("user%d@example.com", r.Intn(10000)),
+				},
+				Payment: domain.Payment{
+					Transaction:  orderUID,
+					RequestID:    "",
+					Currency:     "RUB",
+					Provider:     "wbpay",
+					Amount:       goodsTotal + deliveryCost + customFee,
+					PaymentDt:    now.Unix(),
+					Bank:         "sber",
+					DeliveryCost: deliveryCost,
+					GoodsTotal:   goodsTotal,
+					CustomFee:    customFee,
+				},
+				Items:             items,
+				Locale:            "ru",
+				InternalSignature: "",
+				CustomerID:        generateRandomString(r, 10),
+				DeliveryService:   "meest",
+				ShardKey:          fmt.Sprintf("%d", r.Intn(10)),
+				SmID:              r.Intn(100),
+				DateCreated:       now,
+				OofShard:          "1",
+			}
+			results <- order
+		}
+	}
+
+	for w := 0; w < numWorkers; w++ {
+		go worker(jobs, results, w)
+	}
+
+	for j := 0; j < count; j++ {
+		jobs <- j
+	}
+	close(jobs)
+
+	orders := make([]domain.Order, count)
+	for a := 0; a < count; a++ {
+		orders[a] = <-results
+	}
+	return orders
+}
+
+func generateRandomString(r *rand.Rand, length int) string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = charset[r.Intn(len(charset))]
+	}
+	return string(b)
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ShardDirectory справочник UID заказа -> шард
+type ShardDirectory struct {
+	db     *sqlx.DB
+	logger *slog.Logger
+}
+
+func NewShardDirectory(db *sqlx.DB, logger *slog.Logger) *ShardDirectory {
+	return &ShardDirectory{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Lookup возвращает шард заказа, если он есть в справочнике
+func (d *ShardDirectory) Lookup(ctx context.Context, uid string) (string, bool, error) {
+	var shard string
+	err := d.db.GetContext(ctx, &shard, selectShardDirectoryQuery, uid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		d.logger.Error("failed to lookup shard", slog.String("order_uid", uid), slog.Any("error", err))
+		return "", false, fmt.Errorf("failed to lookup shard: %w", err)
+	}
+	return shard, true, nil
+}
+
+// Put сохраняет или обновляет шард заказа
+func (d *ShardDirectory) Put(ctx context.Context, uid, shard string) error {
+	if _, err := d.db.ExecContext(ctx, upsertShardDirectoryQuery, uid, shard); err != nil {
+		d.logger.Error("failed to put shard",
+			slog.String("order_uid", uid),
+			slog.String("shard", shard),
+			slog.Any("error", err))
+		return fmt.Errorf("failed to put shard: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет заказы из справочника
+func (d *ShardDirectory) Delete(ctx context.Context, uids []string) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	if _, err := d.db.ExecContext(ctx, deleteShardDirectoryQuery, pq.Array(uids)); err != nil {
+		d.logger.Error("failed to delete from shard directory", slog.Int("count", len(uids)), slog.Any("error", err))
+		return fmt.Errorf("failed to delete from shard directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardDirectory(t *testing.T) {
+	ctx := context.Background()
+	directory := NewShardDirectory(db, logger)
+
+	_, err := db.Exec("TRUNCATE order_shard_directory")
+	require.NoError(t, err)
+
+	_, found, err := directory.Lookup(ctx, "uid-1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, directory.Put(ctx, "uid-1", "s1"))
+	require.NoError(t, directory.Put(ctx, "uid-1", "s2"))
+
+	shard, found, err := directory.Lookup(ctx, "uid-1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "s2", shard)
+
+	require.NoError(t, directory.Delete(ctx, []string{"uid-1"}))
+	_, found, err = directory.Lookup(ctx, "uid-1")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
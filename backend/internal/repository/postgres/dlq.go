@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+// DLQRepository хранилище сообщений DLQ для разбора через административный API
+type DLQRepository struct {
+	db     *sqlx.DB
+	logger *slog.Logger
+}
+
+func NewDLQRepository(db *sqlx.DB, logger *slog.Logger) *DLQRepository {
+	return &DLQRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// dlqRow строка dlq_messages: заголовки хранятся в JSONB
+type dlqRow struct {
+	domain.DLQEntry
+	HeadersJSON []byte `db:"headers"`
+}
+
+// Save сохраняет сообщение DLQ. Сообщение с тем же ключом и payload обновляет существующую запись:
+// увеличивает attempts и возвращает запись в разбор. Повторная доставка того же или более
+// раннего попадания (см. domain.DLQEntry.NewerThan) запись не меняет: ее мог обновить dlqreplay.
+func (r *DLQRepository) Save(ctx context.Context, entry *domain.DLQEntry) error {
+	headers, err := json.Marshal(entry.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dlq headers: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, upsertDLQMessageQuery,
+		dlqFingerprint(entry.Key, entry.Payload),
+		entry.Key,
+		entry.Payload,
+		string(headers),
+		entry.OriginalTopic,
+		entry.ErrorClass,
+		entry.FailureReason,
+		entry.LastSeen,
+		entry.DLQPartition,
+		entry.DLQOffset,
+	)
+	if err != nil {
+		r.logger.Error("failed to save dlq message",
+			slog.String("key", entry.Key),
+			slog.Int64("dlq_offset", entry.DLQOffset),
+			slog.Any("error", err))
+		return fmt.Errorf("failed to save dlq message: %w", err)
+	}
+	return nil
+}
+
+// List возвращает сообщения DLQ без payload и заголовков, последние попадания первыми
+func (r *DLQRepository) List(ctx context.Context, filter domain.DLQFilter) ([]*domain.DLQEntry, error) {
+	entries := []*domain.DLQEntry{}
+	err := r.db.SelectContext(ctx, &entries, selectDLQMessagesQuery,
+		filter.Status,
+		filter.ErrorClass,
+		filter.Key,
+		filter.Reason,
+		filter.Limit,
+		filter.Offset,
+	)
+	if err != nil {
+		r.logger.Error("failed to list dlq messages", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list dlq messages: %w", err)
+	}
+	return entries, nil
+}
+
+// Get возвращает сообщение DLQ целиком
+func (r *DLQRepository) Get(ctx context.Context, id int64) (*domain.DLQEntry, error) {
+	var row dlqRow
+	if err := r.db.GetContext(ctx, &row, selectDLQMessageQuery, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrDLQEntryNotFound
+		}
+		r.logger.Error("failed to get dlq message", slog.Int64("id", id), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get dlq message: %w", err)
+	}
+
+	entry := row.DLQEntry
+	if err := json.Unmarshal(row.HeadersJSON, &entry.Headers); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dlq headers: %w", err)
+	}
+	return &entry, nil
+}
+
+// SetNote сохраняет заметку оператора
+func (r *DLQRepository) SetNote(ctx context.Context, id int64, note string) error {
+	res, err := r.db.ExecContext(ctx, updateDLQNoteQuery, id, note)
+	if err != nil {
+		r.logger.Error("failed to annotate dlq message", slog.Int64("id", id), slog.Any("error", err))
+		return fmt.Errorf("failed to annotate dlq message: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to annotate dlq message: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrDLQEntryNotFound
+	}
+	return nil
+}
+
+// SetStatus переводит запись из статуса from в to. false, если записи нет или ее статус уже другой.
+func (r *DLQRepository) SetStatus(ctx context.Context, id int64, from, to domain.DLQStatus) (bool, error) {
+	res, err := r.db.ExecContext(ctx, updateDLQStatusQuery, id, from, to)
+	if err != nil {
+		r.logger.Error("failed to update dlq message status",
+			slog.Int64("id", id),
+			slog.String("status", string(to)),
+			slog.Any("error", err))
+		return false, fmt.Errorf("failed to update dlq message status: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update dlq message status: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// Replayed проверяет, разобрано ли уже это попадание сообщения в DLQ: запись переотправлена
+// или отброшена, либо сообщение снова попало в DLQ позже. Записи может еще не быть, если
+// sink не дочитал DLQ или выключен, - тогда попадание не разобрано. Более позднее попадание,
+// чем сохраненное, - новая попытка, которая тоже ждет разбора.
+func (r *DLQRepository) Replayed(ctx context.Context, entry *domain.DLQEntry) (bool, error) {
+	var state domain.DLQEntry
+	err := r.db.GetContext(ctx, &state, selectDLQReplayStateQuery, dlqFingerprint(entry.Key, entry.Payload))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get dlq message replay state",
+			slog.String("key", entry.Key),
+			slog.Int64("dlq_offset", entry.DLQOffset),
+			slog.Any("error", err))
+		return false, fmt.Errorf("failed to get dlq message replay state: %w", err)
+	}
+	if entry.NewerThan(&state) {
+		return false, nil
+	}
+	return state.Status != domain.DLQStatusOpen ||
+		state.DLQPartition != entry.DLQPartition || state.DLQOffset != entry.DLQOffset, nil
+}
+
+// ClaimReplay переводит попадание сообщения в DLQ в статус requeued перед публикацией,
+// создавая запись, если sink ее еще не сохранил, и переносит в нее более позднее попадание,
+// как Save. false, если попадание уже разобрано (см. Replayed): так повторный или
+// параллельный прогон не опубликует его второй раз.
+func (r *DLQRepository) ClaimReplay(ctx context.Context, entry *domain.DLQEntry) (bool, error) {
+	headers, err := json.Marshal(entry.Headers)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal dlq headers: %w", err)
+	}
+
+	var id int64
+	err = r.db.GetContext(ctx, &id, claimDLQMessageQuery,
+		dlqFingerprint(entry.Key, entry.Payload),
+		entry.Key,
+		entry.Payload,
+		string(headers),
+		entry.OriginalTopic,
+		entry.ErrorClass,
+		entry.FailureReason,
+		entry.LastSeen,
+		entry.DLQPartition,
+		entry.DLQOffset,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to claim dlq message for replay",
+			slog.String("key", entry.Key),
+			slog.Int64("dlq_offset", entry.DLQOffset),
+			slog.Any("error", err))
+		return false, fmt.Errorf("failed to claim dlq message for replay: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseReplay возвращает попадание в разбор, если публикация после ClaimReplay не удалась
+func (r *DLQRepository) ReleaseReplay(ctx context.Context, entry *domain.DLQEntry) error {
+	_, err := r.db.ExecContext(ctx, releaseDLQMessageQuery,
+		dlqFingerprint(entry.Key, entry.Payload), entry.DLQPartition, entry.DLQOffset)
+	if err != nil {
+		r.logger.Error("failed to release dlq message",
+			slog.String("key", entry.Key),
+			slog.Int64("dlq_offset", entry.DLQOffset),
+			slog.Any("error", err))
+		return fmt.Errorf("failed to release dlq message: %w", err)
+	}
+	return nil
+}
+
+// dlqFingerprint идентифицирует сообщение по ключу и payload
+func dlqFingerprint(key string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDLQEntry(key, payload string, offset int64, seen time.Time) *domain.DLQEntry {
+	return &domain.DLQEntry{
+		Key:           key,
+		Payload:       []byte(payload),
+		Headers:       map[string]string{"x-failure-reason": "order validation failed"},
+		OriginalTopic: "orders",
+		ErrorClass:    domain.ErrorClassPermanent,
+		FailureReason: "order validation failed",
+		LastSeen:      seen,
+		DLQOffset:     offset,
+	}
+}
+
+func TestDLQRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDLQRepository(db, logger)
+
+	_, err := db.Exec("TRUNCATE dlq_messages")
+	require.NoError(t, err)
+
+	first := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Save(ctx, newTestDLQEntry("uid-1", `{"order_uid":"uid-1"}`, 0, first)))
+	require.NoError(t, repo.Save(ctx, newTestDLQEntry("uid-2", `not json`, 1, first.Add(time.Minute))))
+
+	t.Run("redelivery of the same offset is ignored", func(t *testing.T) {
+		require.NoError(t, repo.Save(ctx, newTestDLQEntry("uid-1", `{"order_uid":"uid-1"}`, 0, first.Add(time.Hour))))
+
+		entries, err := repo.List(ctx, domain.DLQFilter{Key: "uid-1", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, 1, entries[0].Attempts)
+		assert.True(t, entries[0].LastSeen.Equal(first))
+	})
+
+	t.Run("repeated failure increments attempts", func(t *testing.T) {
+		again := newTestDLQEntry("uid-1", `{"order_uid":"uid-1"}`, 5, first.Add(2*time.Hour))
+		again.ErrorClass = domain.ErrorClassRetryable
+		again.FailureReason = "connection refused"
+		require.NoError(t, repo.Save(ctx, again))
+
+		entries, err := repo.List(ctx, domain.DLQFilter{Key: "uid-1", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		entry := entries[0]
+		assert.Equal(t, 2, entry.Attempts)
+		assert.True(t, entry.FirstSeen.Equal(first))
+		assert.True(t, entry.LastSeen.Equal(first.Add(2*time.Hour)))
+		assert.Equal(t, domain.ErrorClassRetryable, entry.ErrorClass)
+		assert.Equal(t, "connection refused", entry.FailureReason)
+		assert.Equal(t, int64(5), entry.DLQOffset)
+		assert.Empty(t, entry.Payload)
+	})
+
+	t.Run("filters and order", func(t *testing.T) {
+		entries, err := repo.List(ctx, domain.DLQFilter{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "uid-1", entries[0].Key)
+		assert.Equal(t, "uid-2", entries[1].Key)
+
+		entries, err = repo.List(ctx, domain.DLQFilter{ErrorClass: domain.ErrorClassPermanent, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "uid-2", entries[0].Key)
+
+		entries, err = repo.List(ctx, domain.DLQFilter{Reason: "refused", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "uid-1", entries[0].Key)
+
+		entries, err = repo.List(ctx, domain.DLQFilter{Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "uid-2", entries[0].Key)
+	})
+
+	t.Run("get, note and status", func(t *testing.T) {
+		entries, err := repo.List(ctx, domain.DLQFilter{Key: "uid-2", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		id := entries[0].ID
+
+		require.NoError(t, repo.SetNote(ctx, id, "broken producer"))
+
+		ok, err := repo.SetStatus(ctx, id, domain.DLQStatusOpen, domain.DLQStatusDiscarded)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = repo.SetStatus(ctx, id, domain.DLQStatusOpen, domain.DLQStatusRequeued)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		entry, err := repo.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("not json"), entry.Payload)
+		assert.Equal(t, "order validation failed", entry.Headers["x-failure-reason"])
+		assert.Equal(t, "broken producer", entry.Note)
+		assert.Equal(t, domain.DLQStatusDiscarded, entry.Status)
+
+		entries, err = repo.List(ctx, domain.DLQFilter{Status: domain.DLQStatusOpen, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "uid-1", entries[0].Key)
+	})
+
+	t.Run("replay claim", func(t *testing.T) {
+		// Записи еще нет: sink не дочитал DLQ
+		entry := newTestDLQEntry("uid-3", `{"order_uid":"uid-3"}`, 7, first)
+		replayed, err := repo.Replayed(ctx, entry)
+		require.NoError(t, err)
+		assert.False(t, replayed)
+
+		claimed, err := repo.ClaimReplay(ctx, entry)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		claimed, err = repo.ClaimReplay(ctx, entry)
+		require.NoError(t, err)
+		assert.False(t, claimed, "the same DLQ offset must not be claimed twice")
+		replayed, err = repo.Replayed(ctx, entry)
+		require.NoError(t, err)
+		assert.True(t, replayed)
+
+		// Публикация не удалась: попадание возвращается в разбор
+		require.NoError(t, repo.ReleaseReplay(ctx, entry))
+		claimed, err = repo.ClaimReplay(ctx, entry)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+
+		// Сообщение снова попало в DLQ: прежнее попадание разобрано, новое ждет переигрывания
+		again := newTestDLQEntry("uid-3", `{"order_uid":"uid-3"}`, 9, first.Add(time.Hour))
+		require.NoError(t, repo.Save(ctx, again))
+		claimed, err = repo.ClaimReplay(ctx, entry)
+		require.NoError(t, err)
+		assert.False(t, claimed)
+		claimed, err = repo.ClaimReplay(ctx, again)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+
+		// Sink выключен: переигранное сообщение снова упало, но записи о новом попадании нет
+		third := newTestDLQEntry("uid-3", `{"order_uid":"uid-3"}`, 12, first.Add(2*time.Hour))
+		replayed, err = repo.Replayed(ctx, third)
+		require.NoError(t, err)
+		assert.False(t, replayed)
+		claimed, err = repo.ClaimReplay(ctx, third)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		claimed, err = repo.ClaimReplay(ctx, again)
+		require.NoError(t, err)
+		assert.False(t, claimed, "an earlier DLQ offset must not be claimed after a newer one")
+
+		// Запоздавший sink не возвращает запись к более раннему попаданию
+		require.NoError(t, repo.Save(ctx, again))
+		replayed, err = repo.Replayed(ctx, third)
+		require.NoError(t, err)
+		assert.True(t, replayed)
+
+		// Отброшенное оператором сообщение не переигрывается
+		replayed, err = repo.Replayed(ctx, newTestDLQEntry("uid-2", `not json`, 1, first))
+		require.NoError(t, err)
+		assert.True(t, replayed)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := repo.Get(ctx, -1)
+		assert.ErrorIs(t, err, domain.ErrDLQEntryNotFound)
+		assert.ErrorIs(t, repo.SetNote(ctx, -1, "note"), domain.ErrDLQEntryNotFound)
+	})
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/Ravwvil/order-service/backend/internal/domain"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // orderRow - результат JOIN запроса для получения заказа с delivery и payment
@@ -55,6 +57,7 @@ type orderRow struct {
 type orderWithItemsRow struct {
 	orderRow
 	// Item fields (nullable)
+	ItemID          sql.NullInt64  `db:"item_id"`
 	ChrtID          sql.NullInt64  `db:"chrt_id"`
 	ItemTrackNumber sql.NullString `db:"item_track_number"`
 	Price           sql.NullInt64  `db:"price"`
@@ -68,6 +71,24 @@ type orderWithItemsRow struct {
 	Status          sql.NullInt64  `db:"status"`
 }
 
+// orderWithItemsJSONRow - заказ с товарами, агрегированными в JSON (json_agg)
+type orderWithItemsJSONRow struct {
+	orderRow
+	Items []byte `db:"items"`
+}
+
+// toDomainOrder преобразует строку в domain.Order вместе с товарами
+func (row *orderWithItemsJSONRow) toDomainOrder() (*domain.Order, error) {
+	order := row.orderRow.toDomainOrder()
+	if err := json.Unmarshal(row.Items, &order.Items); err != nil {
+		return nil, fmt.Errorf("failed to decode items of order %s: %w", row.OrderUID, err)
+	}
+	for i := range order.Items {
+		order.Items[i].OrderUID = row.OrderUID
+	}
+	return order, nil
+}
+
 // toDomainOrder преобразует orderRow в domain.Order
 func (row *orderRow) toDomainOrder() *domain.Order {
 	order := &domain.Order{
@@ -121,8 +142,10 @@ func (row *orderRow) toDomainOrder() *domain.Order {
 }
 
 type OrderRepository struct {
-	db     *sqlx.DB
-	logger *slog.Logger
+	db      *sqlx.DB
+	logger  *slog.Logger
+	rls     bool
+	itemIDs *sqlx.DB // база, из последовательности которой выделяются id товаров
 }
 
 func NewOrderRepository(db *sqlx.DB, logger *slog.Logger) *OrderRepository {
@@ -132,6 +155,49 @@ func NewOrderRepository(db *sqlx.DB, logger *slog.Logger) *OrderRepository {
 	}
 }
 
+// SetRowLevelSecurity включает передачу тенанта в Postgres (app.tenant) для RLS политик
+func (r *OrderRepository) SetRowLevelSecurity(enabled bool) {
+	r.rls = enabled
+}
+
+// SetItemIDSource задает базу, из последовательности order_items которой выделяются id товаров.
+// При шардировании это база справочника: id остаются уникальными во всех шардах
+// и сохраняются при переносе заказа между шардами.
+func (r *OrderRepository) SetItemIDSource(db *sqlx.DB) {
+	r.itemIDs = db
+}
+
+// withTenant выполняет чтение от имени тенанта из контекста.
+// При включенном RLS тенант устанавливается в транзакции только на время запроса.
+func (r *OrderRepository) withTenant(ctx context.Context, fn func(q sqlx.QueryerContext) error) error {
+	tenant, ok := domain.TenantFromContext(ctx)
+	if !r.rls || !ok {
+		return fn(r.db)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		r.logger.Error("failed to begin transaction", slog.Any("error", err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			r.logger.Error("failed to rollback transaction", slog.Any("error", rollbackErr))
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, setTenantQuery, tenant); err != nil {
+		r.logger.Error("failed to set tenant", slog.String("tenant", tenant), slog.Any("error", err))
+		return fmt.Errorf("failed to set tenant: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error {
 	// Проверяем валидность заказа
 	validationResult := order.Validate()
@@ -139,9 +205,20 @@ func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error
 		r.logger.Error("received invalid order data",
 			slog.String("order_uid", order.OrderUID),
 			slog.Any("validation_errors", validationResult.Errors))
-		return fmt.Errorf("validation failed: %w", validationResult.GetFirstError())
+		return fmt.Errorf("validation failed: %w", domain.Permanent(validationResult.GetFirstError()))
 	}
 
+	return r.insert(ctx, order, false)
+}
+
+// Copy сохраняет уже существующий заказ (перенос между шардами) с прежними id товаров.
+// Валидация не выполняется: заказ мог быть обезличен политикой хранения.
+func (r *OrderRepository) Copy(ctx context.Context, order *domain.Order) error {
+	return r.insert(ctx, order, true)
+}
+
+// insert сохраняет заказ в транзакции. keepItemIDs - не выделять товарам новые id.
+func (r *OrderRepository) insert(ctx context.Context, order *domain.Order, keepItemIDs bool) error {
 	// Начинаем транзакцию
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -167,22 +244,25 @@ func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error
 
 	// 1. Создаем основной заказ
 	if err = r.createOrder(ctx, tx, order); err != nil {
-		return fmt.Errorf("failed to create order: %w", err)
+		if errors.Is(err, domain.ErrOrderExists) {
+			return err
+		}
+		return fmt.Errorf("failed to create order: %w", classifyError(err))
 	}
 
 	// 2. Создаем delivery
 	if err = r.createDelivery(ctx, tx, order.OrderUID, &order.Delivery); err != nil {
-		return fmt.Errorf("failed to create delivery: %w", err)
+		return fmt.Errorf("failed to create delivery: %w", classifyError(err))
 	}
 
 	// 3. Создаем payment
 	if err = r.createPayment(ctx, tx, order.OrderUID, &order.Payment); err != nil {
-		return fmt.Errorf("failed to create payment: %w", err)
+		return fmt.Errorf("failed to create payment: %w", classifyError(err))
 	}
 
 	// 4. Создаем items
-	if err = r.createItems(ctx, tx, order.OrderUID, order.Items); err != nil {
-		return fmt.Errorf("failed to create items: %w", err)
+	if err = r.createItems(ctx, tx, order.OrderUID, order.Items, keepItemIDs); err != nil {
+		return fmt.Errorf("failed to create items: %w", classifyError(err))
 	}
 
 	// Коммитим транзакцию
@@ -198,16 +278,39 @@ func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error
 	return nil
 }
 
-// createOrder создает основну заказа в транзакции
+// classifyError помечает постоянными ошибки данных заказа: нарушения ограничений
+// (класс SQLSTATE 23, например повторный id товара) и некорректные значения (класс 22).
+// Повторный order_uid ошибкой не считается, см. domain.ErrOrderExists.
+// Ошибки соединения, дедлоки и прочие остаются временными.
+func classifyError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "22", "23":
+			return domain.Permanent(err)
+		}
+	}
+	return err
+}
+
+// createOrder создает основну заказа в транзакции.
+// Если заказ с таким order_uid уже сохранен, возвращает domain.ErrOrderExists.
 func (r *OrderRepository) createOrder(ctx context.Context, tx *sqlx.Tx, order *domain.Order) error {
 	// Выполняем запрос на вставку заказа
-	_, err := tx.NamedExecContext(ctx, insertOrderQuery, order)
+	res, err := tx.NamedExecContext(ctx, insertOrderQuery, order)
 	if err != nil {
 		r.logger.Error("failed to insert order",
 			slog.String("order_uid", order.OrderUID),
 			slog.Any("error", err))
 		return err
 	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return fmt.Errorf("%w: uid %s", domain.ErrOrderExists, order.OrderUID)
+	}
 	return nil
 }
 
@@ -242,29 +345,59 @@ func (r *OrderRepository) createPayment(ctx context.Context, tx *sqlx.Tx, orderU
 }
 
 // createItems создает записи товаров в транзакции
-func (r *OrderRepository) createItems(ctx context.Context, tx *sqlx.Tx, orderUID string, items []domain.Item) error {
+func (r *OrderRepository) createItems(ctx context.Context, tx *sqlx.Tx, orderUID string, items []domain.Item, keepIDs bool) error {
 	if len(items) == 0 {
 		return nil
 	}
 
+	// Выделяем id заранее: порядок строк RETURNING не гарантирует соответствия порядку VALUES
+	if !keepIDs {
+		var source sqlx.QueryerContext = tx
+		if r.itemIDs != nil {
+			source = r.itemIDs
+		}
+		if err := allocateItemIDs(ctx, source, items); err != nil {
+			r.logger.Error("failed to allocate item ids",
+				slog.String("order_uid", orderUID),
+				slog.Any("error", err))
+			return err
+		}
+	}
+
 	// Подготавливаем запрос для вставки
 	query, args, err := r.buildBulkInsertItemsQuery(orderUID, items)
 	if err != nil {
 		return err
 	}
 
-	// Выполняем запрос
-	_, err = tx.ExecContext(ctx, query, args...)
-	if err != nil {
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
 		r.logger.Error("failed to bulk insert items",
 			slog.String("order_uid", orderUID),
 			slog.Any("error", err))
 		return err
 	}
+	for i := range items {
+		items[i].OrderUID = orderUID
+	}
 
 	return nil
 }
 
+// allocateItemIDs назначает товарам id из последовательности order_items
+func allocateItemIDs(ctx context.Context, q sqlx.QueryerContext, items []domain.Item) error {
+	var ids []int64
+	if err := sqlx.SelectContext(ctx, q, &ids, allocateItemIDsQuery, len(items)); err != nil {
+		return fmt.Errorf("failed to allocate item ids: %w", err)
+	}
+	if len(ids) != len(items) {
+		return fmt.Errorf("allocated %d item ids for %d items", len(ids), len(items))
+	}
+	for i := range items {
+		items[i].ID = ids[i]
+	}
+	return nil
+}
+
 func (r *OrderRepository) buildBulkInsertItemsQuery(orderUID string, items []domain.Item) (string, []interface{}, error) {
 	if len(items) == 0 {
 		return "", nil, errors.New("no items to insert")
@@ -275,23 +408,29 @@ func (r *OrderRepository) buildBulkInsertItemsQuery(orderUID string, items []dom
 	i := 1
 	for _, item := range items {
 		// Создаем строку с аргументами для вставки
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
-			i, i+1, i+2, i+3, i+4, i+5, i+6, i+7, i+8, i+9, i+10, i+11))
-		valueArgs = append(valueArgs, orderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
-		i += 12
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			i, i+1, i+2, i+3, i+4, i+5, i+6, i+7, i+8, i+9, i+10, i+11, i+12))
+		valueArgs = append(valueArgs, item.ID, orderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
+		i += 13
 	}
 
 	query := fmt.Sprintf("%s %s", insertItemQuery, strings.Join(valueStrings, ","))
 	return query, valueArgs, nil
 }
 
+// GetByUID возвращает заказ по UID одним запросом (товары агрегируются через json_agg).
+// Если в контексте задан тенант, заказы других тенантов считаются ненайденными.
 func (r *OrderRepository) GetByUID(ctx context.Context, uid string) (*domain.Order, error) {
-	var row orderRow
-	err := r.db.GetContext(ctx, &row, selectOrderByUIDQuery, uid)
+	tenant, _ := domain.TenantFromContext(ctx)
+
+	var row orderWithItemsJSONRow
+	err := r.withTenant(ctx, func(q sqlx.QueryerContext) error {
+		return sqlx.GetContext(ctx, q, &row, selectOrderByUIDQuery, uid, tenant)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			r.logger.Debug("order not found", slog.String("order_uid", uid))
-			return nil, fmt.Errorf("order with uid %s not found", uid)
+			r.logger.Debug("order not found", slog.String("order_uid", uid), slog.String("tenant", tenant))
+			return nil, fmt.Errorf("%w: uid %s", domain.ErrOrderNotFound, uid)
 		}
 		r.logger.Error("failed to get order",
 			slog.String("order_uid", uid),
@@ -299,40 +438,116 @@ func (r *OrderRepository) GetByUID(ctx context.Context, uid string) (*domain.Ord
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
 
-	// Создаем объект заказа
-	order := row.toDomainOrder()
-
-	// Получаем товары заказа
-	items, err := r.getOrderItems(ctx, uid)
+	order, err := row.toDomainOrder()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get order items: %w", err)
+		r.logger.Error("failed to decode order", slog.String("order_uid", uid), slog.Any("error", err))
+		return nil, err
 	}
-	order.Items = items
 
 	r.logger.Debug("order retrieved successfully",
 		slog.String("order_uid", uid),
-		slog.Int("items_count", len(items)))
+		slog.Int("items_count", len(order.Items)))
 
 	return order, nil
 }
 
-// getOrderItems получает все товары заказа
-func (r *OrderRepository) getOrderItems(ctx context.Context, orderUID string) ([]domain.Item, error) {
-	var items []domain.Item
-	err := r.db.SelectContext(ctx, &items, selectItemsByUIDQuery, orderUID)
+// GetByUIDs возвращает заказы по списку UID одним запросом в порядке uids.
+// Ненайденные заказы (и заказы других тенантов) в результат не попадают.
+func (r *OrderRepository) GetByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	tenant, _ := domain.TenantFromContext(ctx)
+
+	var rows []orderWithItemsJSONRow
+	err := r.withTenant(ctx, func(q sqlx.QueryerContext) error {
+		return sqlx.SelectContext(ctx, q, &rows, selectOrdersByUIDsQuery, pq.Array(uids), tenant)
+	})
 	if err != nil {
-		r.logger.Error("failed to get order items",
-			slog.String("order_uid", orderUID),
-			slog.Any("error", err))
-		return nil, err
+		r.logger.Error("failed to get orders", slog.Int("count", len(uids)), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get orders: %w", err)
 	}
 
-	// Устанавливаем OrderUID для каждого товара
-	for i := range items {
-		items[i].OrderUID = orderUID
+	byUID := make(map[string]*domain.Order, len(rows))
+	for i := range rows {
+		order, err := rows[i].toDomainOrder()
+		if err != nil {
+			r.logger.Error("failed to decode order", slog.String("order_uid", rows[i].OrderUID), slog.Any("error", err))
+			return nil, err
+		}
+		byUID[order.OrderUID] = order
+	}
+
+	orders := make([]*domain.Order, 0, len(byUID))
+	for _, uid := range uids {
+		if order, ok := byUID[uid]; ok {
+			orders = append(orders, order)
+			delete(byUID, uid) // повторный UID во входном списке не дублирует заказ
+		}
+	}
+
+	r.logger.Debug("orders retrieved successfully",
+		slog.Int("requested", len(uids)),
+		slog.Int("found", len(orders)))
+
+	return orders, nil
+}
+
+// GetByCustomer возвращает заказы покупателя (сначала новые)
+func (r *OrderRepository) GetByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	tenant, _ := domain.TenantFromContext(ctx)
+
+	var rows []orderWithItemsJSONRow
+	err := r.withTenant(ctx, func(q sqlx.QueryerContext) error {
+		return sqlx.SelectContext(ctx, q, &rows, selectOrdersByCustomerQuery, customerID, tenant)
+	})
+	if err != nil {
+		r.logger.Error("failed to get customer orders", slog.String("customer_id", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get customer orders: %w", err)
+	}
+
+	orders := make([]*domain.Order, 0, len(rows))
+	for i := range rows {
+		order, err := rows[i].toDomainOrder()
+		if err != nil {
+			r.logger.Error("failed to decode order", slog.String("order_uid", rows[i].OrderUID), slog.Any("error", err))
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	r.logger.Debug("customer orders retrieved successfully",
+		slog.String("customer_id", customerID),
+		slog.Int("count", len(orders)))
+
+	return orders, nil
+}
+
+// GetByTrackNumber возвращает последний заказ с трек-номером
+func (r *OrderRepository) GetByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error) {
+	tenant, _ := domain.TenantFromContext(ctx)
+
+	var row orderWithItemsJSONRow
+	err := r.withTenant(ctx, func(q sqlx.QueryerContext) error {
+		return sqlx.GetContext(ctx, q, &row, selectOrderByTrackNumberQuery, trackNumber, tenant)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.Debug("order not found", slog.String("track_number", trackNumber), slog.String("tenant", tenant))
+			return nil, fmt.Errorf("%w: track number %s", domain.ErrOrderNotFound, trackNumber)
+		}
+		r.logger.Error("failed to get order by track number",
+			slog.String("track_number", trackNumber),
+			slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
 
-	return items, nil
+	order, err := row.toDomainOrder()
+	if err != nil {
+		r.logger.Error("failed to decode order", slog.String("order_uid", row.OrderUID), slog.Any("error", err))
+		return nil, err
+	}
+	return order, nil
 }
 
 func (r *OrderRepository) GetAll(ctx context.Context) ([]*domain.Order, error) {
@@ -354,8 +569,9 @@ func (r *OrderRepository) GetAll(ctx context.Context) ([]*domain.Order, error) {
 			orderedUIDs = append(orderedUIDs, row.OrderUID)
 		}
 
-		if row.ChrtID.Valid {
+		if row.ItemID.Valid {
 			item := domain.Item{
+				ID:          row.ItemID.Int64,
 				OrderUID:    row.OrderUID,
 				ChrtID:      int(row.ChrtID.Int64),
 				TrackNumber: row.ItemTrackNumber.String,
@@ -385,3 +601,56 @@ func (r *OrderRepository) GetAll(ctx context.Context) ([]*domain.Order, error) {
 
 	return orders, nil
 }
+
+// ForEachBatch обходит заказы, созданные не раньше since (нулевое значение - все заказы),
+// пачками по batchSize с keyset-пагинацией по order_uid и вызывает fn для каждой пачки.
+// В памяти одновременно находится только одна пачка.
+// Обход прерывается при ошибке fn или отмене контекста.
+func (r *OrderRepository) ForEachBatch(ctx context.Context, since time.Time, batchSize int, fn func(orders []*domain.Order) error) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("invalid batch size: %d", batchSize)
+	}
+
+	after := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var rows []orderWithItemsJSONRow
+		if err := r.db.SelectContext(ctx, &rows, selectOrdersPageQuery, after, batchSize, since); err != nil {
+			r.logger.Error("failed to get orders page", slog.String("after", after), slog.Any("error", err))
+			return fmt.Errorf("failed to get orders page: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		orders := make([]*domain.Order, len(rows))
+		for i := range rows {
+			order, err := rows[i].toDomainOrder()
+			if err != nil {
+				return err
+			}
+			orders[i] = order
+		}
+		after = rows[len(rows)-1].OrderUID
+
+		if err := fn(orders); err != nil {
+			return err
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+	}
+}
+
+// ListRefs возвращает до limit ссылок на заказы с UID больше afterUID (keyset-пагинация по order_uid)
+func (r *OrderRepository) ListRefs(ctx context.Context, afterUID string, limit int) ([]domain.OrderRef, error) {
+	var refs []domain.OrderRef
+	if err := r.db.SelectContext(ctx, &refs, selectOrderRefsQuery, afterUID, limit); err != nil {
+		r.logger.Error("failed to list order refs", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list order refs: %w", err)
+	}
+	return refs, nil
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+// Запросы прежней реализации GetByUID: заказ и товары отдельными запросами
+const (
+	benchSelectOrderQuery = `
+SELECT
+    o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+    o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created,
+    o.oof_shard, o.created_at, o.updated_at,
+    d.name as delivery_name, d.phone as delivery_phone, d.zip as delivery_zip,
+    d.city as delivery_city, d.address as delivery_address, d.region as delivery_region,
+    d.email as delivery_email,
+    p.transaction, p.request_id, p.currency, p.provider, p.amount,
+    p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
+FROM orders o
+LEFT JOIN deliveries d ON o.order_uid = d.order_uid
+LEFT JOIN payments p ON o.order_uid = p.order_uid
+WHERE o.order_uid = $1`
+
+	benchSelectItemsQuery = `
+SELECT id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+FROM order_items
+WHERE order_uid = $1
+ORDER BY id`
+)
+
+// getByUIDTwoQueries повторяет прежнюю реализацию GetByUID для сравнения
+func getByUIDTwoQueries(ctx context.Context, db *sqlx.DB, uid string) (*domain.Order, error) {
+	var row orderRow
+	if err := db.GetContext(ctx, &row, benchSelectOrderQuery, uid); err != nil {
+		return nil, err
+	}
+	order := row.toDomainOrder()
+	if err := db.SelectContext(ctx, &order.Items, benchSelectItemsQuery, uid); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// BenchmarkGetByUID сравнивает получение заказа одним запросом (json_agg) и двумя запросами.
+// Запуск: go test -run '^$' -bench GetByUID ./internal/repository/postgres/
+func BenchmarkGetByUID(b *testing.B) {
+	ctx := context.Background()
+
+	order := loadOrderFromJSON(b, "../../service/testdata/valid_order.json")
+	for len(order.Items) < 10 {
+		order.Items = append(order.Items, order.Items[0])
+	}
+	clearTables()
+	if err := repo.Create(ctx, order); err != nil {
+		b.Fatalf("failed to create order: %v", err)
+	}
+
+	b.Run("single query", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.GetByUID(ctx, order.OrderUID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("two queries", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := getByUIDTwoQueries(ctx, db, order.OrderUID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -3,9 +3,11 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
@@ -25,12 +27,13 @@ import (
 
 var (
 	db     *sqlx.DB
+	dsn    string
 	repo   *OrderRepository
 	logger *slog.Logger
 )
 
 // Test Helpers
-func loadOrderFromJSON(t *testing.T, path string) *domain.Order {
+func loadOrderFromJSON(t testing.TB, path string) *domain.Order {
 	t.Helper()
 	data, err := os.ReadFile(path)
 	require.NoError(t, err, "failed to read file")
@@ -98,7 +101,7 @@ func TestMain(m *testing.M) {
 		log.Fatalf("failed to get mapped port: %v", err)
 	}
 
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", pgUser, pgPassword, host, port.Port(), pgDatabase)
+	dsn = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", pgUser, pgPassword, host, port.Port(), pgDatabase)
 
 	// Run migrations
 	if err := runMigrations(dsn); err != nil {
@@ -141,6 +144,45 @@ func TestOrderRepository_Create(t *testing.T) {
 		assert.Equal(t, 1, count)
 	})
 
+	t.Run("duplicate rid", func(t *testing.T) {
+		clearTables()
+		dupOrder := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+		dupOrder.Items = append(dupOrder.Items, dupOrder.Items[0])
+
+		err := repo.Create(ctx, dupOrder)
+		require.NoError(t, err)
+
+		// Каждая позиция получает собственный id
+		assert.NotZero(t, dupOrder.Items[0].ID)
+		assert.NotZero(t, dupOrder.Items[1].ID)
+		assert.NotEqual(t, dupOrder.Items[0].ID, dupOrder.Items[1].ID)
+
+		retrievedOrder, err := repo.GetByUID(ctx, dupOrder.OrderUID)
+		require.NoError(t, err)
+		require.Len(t, retrievedOrder.Items, 2)
+		assert.Equal(t, dupOrder.Items[0].ID, retrievedOrder.Items[0].ID)
+		assert.Equal(t, dupOrder.Items[1].ID, retrievedOrder.Items[1].ID)
+	})
+
+	t.Run("item ids match stored rows", func(t *testing.T) {
+		clearTables()
+		order := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+		for i := 1; i <= 5; i++ {
+			item := order.Items[0]
+			item.ChrtID += i
+			item.Rid = fmt.Sprintf("%s-%d", item.Rid, i)
+			order.Items = append(order.Items, item)
+		}
+
+		require.NoError(t, repo.Create(ctx, order))
+
+		for _, item := range order.Items {
+			var chrtID int
+			require.NoError(t, db.Get(&chrtID, "SELECT chrt_id FROM order_items WHERE id = $1", item.ID))
+			assert.Equal(t, item.ChrtID, chrtID)
+		}
+	})
+
 	t.Run("invalid order", func(t *testing.T) {
 		clearTables()
 		invalidOrder := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
@@ -149,6 +191,83 @@ func TestOrderRepository_Create(t *testing.T) {
 		err := repo.Create(ctx, invalidOrder)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "validation failed")
+		assert.Equal(t, domain.ErrorClassPermanent, domain.ClassifyError(err))
+	})
+
+	t.Run("duplicate order is reported as existing", func(t *testing.T) {
+		clearTables()
+		require.NoError(t, repo.Create(ctx, loadOrderFromJSON(t, "../../service/testdata/valid_order.json")))
+
+		err := repo.Create(ctx, loadOrderFromJSON(t, "../../service/testdata/valid_order.json"))
+		assert.ErrorIs(t, err, domain.ErrOrderExists)
+
+		var count int
+		require.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM order_items"))
+		assert.Equal(t, len(loadOrderFromJSON(t, "../../service/testdata/valid_order.json").Items), count)
+
+		// Сохраненный заказ совпадает с повторной доставкой, см. OrderService.ProcessOrderMessage
+		redelivered := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+		stored, err := repo.GetByUID(ctx, redelivered.OrderUID)
+		require.NoError(t, err)
+		assert.True(t, stored.SameContent(redelivered))
+	})
+
+	t.Run("constraint violation is permanent", func(t *testing.T) {
+		clearTables()
+		first := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+		require.NoError(t, repo.Create(ctx, first))
+
+		second := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+		second.OrderUID = "another-uid"
+		second.Items[0].ID = first.Items[0].ID
+		err := repo.Copy(ctx, second)
+		require.Error(t, err)
+		assert.Equal(t, domain.ErrorClassPermanent, domain.ClassifyError(err))
+	})
+
+	t.Run("cancelled context is retryable", func(t *testing.T) {
+		clearTables()
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := repo.Create(cancelled, loadOrderFromJSON(t, "../../service/testdata/valid_order.json"))
+		require.Error(t, err)
+		assert.Equal(t, domain.ErrorClassRetryable, domain.ClassifyError(err))
+	})
+}
+
+func TestOrderRepository_Copy(t *testing.T) {
+	ctx := context.Background()
+	clearTables()
+
+	order := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	require.NoError(t, repo.Create(ctx, order))
+	stored, err := repo.GetByUID(ctx, order.OrderUID)
+	require.NoError(t, err)
+	_, err = repo.DeleteByUIDs(ctx, []string{order.OrderUID})
+	require.NoError(t, err)
+
+	t.Run("keeps item ids", func(t *testing.T) {
+		require.NoError(t, repo.Copy(ctx, stored))
+
+		copied, err := repo.GetByUID(ctx, order.OrderUID)
+		require.NoError(t, err)
+		require.Len(t, copied.Items, len(stored.Items))
+		for i := range stored.Items {
+			assert.Equal(t, stored.Items[i].ID, copied.Items[i].ID)
+		}
+	})
+
+	t.Run("ids from item id source", func(t *testing.T) {
+		clearTables()
+		var next int64
+		require.NoError(t, db.Get(&next, "SELECT setval(pg_get_serial_sequence('order_items', 'id'), 1000)"))
+
+		sourced := NewOrderRepository(db, logger)
+		sourced.SetItemIDSource(db)
+		order := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+		require.NoError(t, sourced.Create(ctx, order))
+		assert.Greater(t, order.Items[0].ID, next)
 	})
 }
 
@@ -183,8 +302,214 @@ func TestOrderRepository_GetByUID(t *testing.T) {
 	t.Run("not found", func(t *testing.T) {
 		_, err := repo.GetByUID(ctx, "non-existent-uid")
 		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+	})
+
+	t.Run("same tenant", func(t *testing.T) {
+		tenantCtx := domain.WithTenant(ctx, order.Entry)
+		retrievedOrder, err := repo.GetByUID(tenantCtx, order.OrderUID)
+		require.NoError(t, err)
+		assert.Equal(t, order.OrderUID, retrievedOrder.OrderUID)
+	})
+
+	t.Run("other tenant", func(t *testing.T) {
+		tenantCtx := domain.WithTenant(ctx, "OTHER")
+		_, err := repo.GetByUID(tenantCtx, order.OrderUID)
+		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "not found")
 	})
+
+	t.Run("row level security", func(t *testing.T) {
+		// Суперпользователь контейнера обходит RLS даже с FORCE, поэтому проверяем отдельной ролью
+		appDB := connectAsAppRole(t)
+		countFor := func(tenant, query string) int {
+			tx, err := appDB.BeginTxx(ctx, nil)
+			require.NoError(t, err)
+			defer func() { _ = tx.Rollback() }()
+			_, err = tx.ExecContext(ctx, setTenantQuery, tenant)
+			require.NoError(t, err)
+			var count int
+			require.NoError(t, tx.GetContext(ctx, &count, query, order.OrderUID))
+			return count
+		}
+
+		// Запросы без условия на entry: строки отсекает только политика
+		ordersQuery := "SELECT COUNT(*) FROM orders WHERE order_uid = $1"
+		itemsQuery := "SELECT COUNT(*) FROM order_items WHERE order_uid = $1"
+		assert.Equal(t, 1, countFor(order.Entry, ordersQuery))
+		assert.Equal(t, len(order.Items), countFor(order.Entry, itemsQuery))
+		assert.Equal(t, 0, countFor("OTHER", ordersQuery))
+		assert.Equal(t, 0, countFor("OTHER", itemsQuery))
+		assert.Equal(t, 1, countFor("", ordersQuery))
+	})
+}
+
+// connectAsAppRole подключается к базе ролью без прав суперпользователя и BYPASSRLS
+func connectAsAppRole(t *testing.T) *sqlx.DB {
+	t.Helper()
+	_, err := db.Exec(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'order_app') THEN
+			CREATE ROLE order_app LOGIN PASSWORD 'order_app' NOSUPERUSER NOBYPASSRLS;
+		END IF;
+	END $$`)
+	require.NoError(t, err)
+	_, err = db.Exec("GRANT SELECT ON ALL TABLES IN SCHEMA public TO order_app")
+	require.NoError(t, err)
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	u.User = url.UserPassword("order_app", "order_app")
+	appDB, err := sqlx.Connect("postgres", u.String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = appDB.Close() })
+	return appDB
+}
+
+func TestOrderRepository_GetByUIDs(t *testing.T) {
+	ctx := context.Background()
+
+	order1 := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	order2 := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	order2.OrderUID = "another-uid-for-batch"
+	order2.Entry = "OTHER"
+	order2.Items = nil
+
+	clearTables()
+	require.NoError(t, repo.Create(ctx, order1))
+	require.NoError(t, repo.Create(ctx, order2))
+
+	t.Run("keeps requested order", func(t *testing.T) {
+		orders, err := repo.GetByUIDs(ctx, []string{order2.OrderUID, "non-existent-uid", order1.OrderUID, order2.OrderUID})
+		require.NoError(t, err)
+		require.Len(t, orders, 2)
+
+		assert.Equal(t, order2.OrderUID, orders[0].OrderUID)
+		assert.Empty(t, orders[0].Items)
+		assert.Equal(t, order1.OrderUID, orders[1].OrderUID)
+		require.Len(t, orders[1].Items, len(order1.Items))
+		assert.Equal(t, order1.Items[0].Rid, orders[1].Items[0].Rid)
+		assert.Equal(t, order1.OrderUID, orders[1].Items[0].OrderUID)
+	})
+
+	t.Run("filters by tenant", func(t *testing.T) {
+		orders, err := repo.GetByUIDs(domain.WithTenant(ctx, order1.Entry), []string{order1.OrderUID, order2.OrderUID})
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, order1.OrderUID, orders[0].OrderUID)
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		orders, err := repo.GetByUIDs(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+	})
+}
+
+func TestOrderRepository_SecondaryLookups(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	older := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	older.CreatedAt, older.UpdatedAt = now.Add(-time.Hour), now.Add(-time.Hour)
+	newer := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	newer.OrderUID = "newer-order-of-customer"
+	newer.CreatedAt, newer.UpdatedAt = now, now
+	newer.Items = nil
+	other := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	other.OrderUID = "order-of-other-tenant"
+	other.Entry = "OTHER"
+	other.CustomerID = "other-customer"
+	other.TrackNumber = "OTHERTRACK"
+	other.Items = nil
+
+	clearTables()
+	require.NoError(t, repo.Create(ctx, older))
+	require.NoError(t, repo.Create(ctx, newer))
+	require.NoError(t, repo.Create(ctx, other))
+
+	t.Run("by customer", func(t *testing.T) {
+		orders, err := repo.GetByCustomer(ctx, older.CustomerID)
+		require.NoError(t, err)
+		require.Len(t, orders, 2)
+		assert.Equal(t, newer.OrderUID, orders[0].OrderUID)
+		assert.Equal(t, older.OrderUID, orders[1].OrderUID)
+		assert.Len(t, orders[1].Items, len(older.Items))
+	})
+
+	t.Run("by customer filters by tenant", func(t *testing.T) {
+		orders, err := repo.GetByCustomer(domain.WithTenant(ctx, "OTHER"), older.CustomerID)
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+	})
+
+	t.Run("by track number returns latest", func(t *testing.T) {
+		order, err := repo.GetByTrackNumber(ctx, older.TrackNumber)
+		require.NoError(t, err)
+		assert.Equal(t, newer.OrderUID, order.OrderUID)
+	})
+
+	t.Run("by track number not found", func(t *testing.T) {
+		_, err := repo.GetByTrackNumber(domain.WithTenant(ctx, older.Entry), other.TrackNumber)
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+	})
+}
+
+func TestOrderRepository_ForEachBatch(t *testing.T) {
+	ctx := context.Background()
+
+	clearTables()
+	now := time.Now()
+	for i, uid := range []string{"uid-c", "uid-a", "uid-b"} {
+		order := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+		order.OrderUID = uid
+		order.DateCreated = now.AddDate(0, 0, -10*i)
+		require.NoError(t, repo.Create(ctx, order))
+	}
+
+	t.Run("pages by order_uid", func(t *testing.T) {
+		var batches [][]string
+		err := repo.ForEachBatch(ctx, time.Time{}, 2, func(orders []*domain.Order) error {
+			var uids []string
+			for _, order := range orders {
+				assert.NotEmpty(t, order.Items)
+				uids = append(uids, order.OrderUID)
+			}
+			batches = append(batches, uids)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"uid-a", "uid-b"}, {"uid-c"}}, batches)
+	})
+
+	t.Run("since", func(t *testing.T) {
+		var uids []string
+		err := repo.ForEachBatch(ctx, now.AddDate(0, 0, -15), 10, func(orders []*domain.Order) error {
+			for _, order := range orders {
+				uids = append(uids, order.OrderUID)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"uid-a", "uid-c"}, uids)
+	})
+
+	t.Run("stops on callback error", func(t *testing.T) {
+		stopErr := errors.New("stop")
+		calls := 0
+		err := repo.ForEachBatch(ctx, time.Time{}, 1, func(orders []*domain.Order) error {
+			calls++
+			return stopErr
+		})
+		assert.ErrorIs(t, err, stopErr)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		err := repo.ForEachBatch(cancelled, time.Time{}, 1, func(orders []*domain.Order) error { return nil })
+		assert.ErrorIs(t, err, context.Canceled)
+	})
 }
 
 func TestOrderRepository_GetAll(t *testing.T) {
@@ -18,12 +18,81 @@ var (
 	//go:embed queries/insert_item.sql
 	insertItemQuery string
 
+	//go:embed queries/allocate_item_ids.sql
+	allocateItemIDsQuery string
+
 	//go:embed queries/select_by_uid.sql
 	selectOrderByUIDQuery string
 
-	//go:embed queries/select_items_by_uid.sql
-	selectItemsByUIDQuery string
+	//go:embed queries/set_tenant.sql
+	setTenantQuery string
+
+	//go:embed queries/select_by_uids.sql
+	selectOrdersByUIDsQuery string
+
+	//go:embed queries/select_by_customer.sql
+	selectOrdersByCustomerQuery string
+
+	//go:embed queries/select_by_track_number.sql
+	selectOrderByTrackNumberQuery string
+
+	//go:embed queries/select_orders_page.sql
+	selectOrdersPageQuery string
 
 	//go:embed queries/select_all_orders_with_items.sql
 	selectAllOrdersWithItemsQuery string
+
+	//go:embed queries/select_expired_order_uids.sql
+	selectExpiredOrderUIDsQuery string
+
+	//go:embed queries/count_expired_orders.sql
+	countExpiredOrdersQuery string
+
+	//go:embed queries/delete_orders_by_uids.sql
+	deleteOrdersByUIDsQuery string
+
+	//go:embed queries/anonymize_orders_by_uids.sql
+	anonymizeOrdersByUIDsQuery string
+
+	//go:embed queries/anonymize_deliveries_by_uids.sql
+	anonymizeDeliveriesByUIDsQuery string
+
+	//go:embed queries/anonymize_payments_by_uids.sql
+	anonymizePaymentsByUIDsQuery string
+
+	//go:embed queries/select_order_refs.sql
+	selectOrderRefsQuery string
+
+	//go:embed queries/select_shard_directory.sql
+	selectShardDirectoryQuery string
+
+	//go:embed queries/upsert_shard_directory.sql
+	upsertShardDirectoryQuery string
+
+	//go:embed queries/delete_shard_directory.sql
+	deleteShardDirectoryQuery string
+
+	//go:embed queries/upsert_dlq_message.sql
+	upsertDLQMessageQuery string
+
+	//go:embed queries/select_dlq_messages.sql
+	selectDLQMessagesQuery string
+
+	//go:embed queries/select_dlq_message.sql
+	selectDLQMessageQuery string
+
+	//go:embed queries/update_dlq_note.sql
+	updateDLQNoteQuery string
+
+	//go:embed queries/update_dlq_status.sql
+	updateDLQStatusQuery string
+
+	//go:embed queries/claim_dlq_message.sql
+	claimDLQMessageQuery string
+
+	//go:embed queries/release_dlq_message.sql
+	releaseDLQMessageQuery string
+
+	//go:embed queries/select_dlq_replay_state.sql
+	selectDLQReplayStateQuery string
 )
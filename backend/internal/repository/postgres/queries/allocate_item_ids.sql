@@ -0,0 +1,2 @@
+SELECT nextval(pg_get_serial_sequence('order_items', 'id'))
+FROM generate_series(1, $1)
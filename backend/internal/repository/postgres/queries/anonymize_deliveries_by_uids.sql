@@ -0,0 +1,9 @@
+UPDATE deliveries SET
+    name = '',
+    phone = '',
+    zip = '',
+    city = '',
+    address = '',
+    region = '',
+    email = ''
+WHERE order_uid = ANY($1)
@@ -0,0 +1,6 @@
+UPDATE orders SET
+    customer_id = '',
+    internal_signature = '',
+    anonymized_at = now()
+WHERE order_uid = ANY($1)
+  AND anonymized_at IS NULL
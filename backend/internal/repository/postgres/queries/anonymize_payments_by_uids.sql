@@ -0,0 +1,4 @@
+UPDATE payments SET
+    transaction = '',
+    request_id = ''
+WHERE order_uid = ANY($1)
@@ -0,0 +1,22 @@
+INSERT INTO dlq_messages (
+    fingerprint, message_key, payload, headers, original_topic, error_class, failure_reason,
+    first_seen, last_seen, dlq_partition, dlq_offset, status
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9, $10, 'requeued')
+ON CONFLICT (fingerprint) DO UPDATE SET
+    headers = EXCLUDED.headers,
+    error_class = EXCLUDED.error_class,
+    failure_reason = EXCLUDED.failure_reason,
+    attempts = dlq_messages.attempts + CASE
+        WHEN (dlq_messages.dlq_partition, dlq_messages.dlq_offset) = (EXCLUDED.dlq_partition, EXCLUDED.dlq_offset)
+        THEN 0 ELSE 1 END,
+    last_seen = EXCLUDED.last_seen,
+    dlq_partition = EXCLUDED.dlq_partition,
+    dlq_offset = EXCLUDED.dlq_offset,
+    status = 'requeued',
+    updated_at = now()
+WHERE (dlq_messages.status = 'open'
+        AND (dlq_messages.dlq_partition, dlq_messages.dlq_offset) = (EXCLUDED.dlq_partition, EXCLUDED.dlq_offset))
+    OR (dlq_messages.dlq_partition = EXCLUDED.dlq_partition AND EXCLUDED.dlq_offset > dlq_messages.dlq_offset)
+    OR (dlq_messages.dlq_partition <> EXCLUDED.dlq_partition AND EXCLUDED.last_seen > dlq_messages.last_seen)
+RETURNING id
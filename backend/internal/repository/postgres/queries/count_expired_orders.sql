@@ -0,0 +1,16 @@
+-- Параметры аналогичны select_expired_order_uids.sql, без размера пачки
+SELECT rule.entry, rule.locale, rule.days, COUNT(*) AS count
+FROM orders o
+JOIN LATERAL (
+    SELECT r.entry, r.locale, r.days
+    FROM unnest($1::text[], $2::text[], $3::int[]) AS r(entry, locale, days)
+    WHERE (r.entry = '' OR r.entry = o.entry)
+      AND (r.locale = '' OR r.locale = o.locale)
+    ORDER BY (r.entry <> '') DESC, (r.locale <> '') DESC
+    LIMIT 1
+) rule ON true
+WHERE rule.days > 0
+  AND o.date_created < $4::timestamptz - make_interval(days => rule.days)
+  AND ($5 OR o.anonymized_at IS NULL)
+GROUP BY rule.entry, rule.locale, rule.days
+ORDER BY rule.entry, rule.locale
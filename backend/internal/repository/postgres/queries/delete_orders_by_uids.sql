@@ -0,0 +1,2 @@
+DELETE FROM orders
+WHERE order_uid = ANY($1)
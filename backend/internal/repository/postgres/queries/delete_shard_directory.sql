@@ -0,0 +1,2 @@
+DELETE FROM order_shard_directory
+WHERE order_uid = ANY($1)
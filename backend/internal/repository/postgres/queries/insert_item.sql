@@ -1,4 +1,4 @@
 INSERT INTO order_items (
-    order_uid, chrt_id, track_number, price, rid, name, 
+    id, order_uid, chrt_id, track_number, price, rid, name, 
     sale, size, total_price, nm_id, brand, status
 ) VALUES
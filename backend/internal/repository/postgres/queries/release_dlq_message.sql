@@ -0,0 +1,3 @@
+UPDATE dlq_messages
+SET status = 'open', updated_at = now()
+WHERE fingerprint = $1 AND dlq_partition = $2 AND dlq_offset = $3 AND status = 'requeued'
@@ -8,8 +8,20 @@ SELECT
     d.email as delivery_email,
     
     p.transaction, p.request_id, p.currency, p.provider, p.amount,
-    p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
+    p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee,
+
+    COALESCE((
+        SELECT json_agg(json_build_object(
+            'id', i.id, 'chrt_id', i.chrt_id, 'track_number', i.track_number,
+            'price', i.price, 'rid', i.rid, 'name', i.name, 'sale', i.sale,
+            'size', i.size, 'total_price', i.total_price, 'nm_id', i.nm_id,
+            'brand', i.brand, 'status', i.status
+        ) ORDER BY i.id)
+        FROM order_items i
+        WHERE i.order_uid = o.order_uid
+    ), '[]'::json) as items
 FROM orders o
 LEFT JOIN deliveries d ON o.order_uid = d.order_uid
 LEFT JOIN payments p ON o.order_uid = p.order_uid
-WHERE o.order_uid = $1
\ No newline at end of file
+WHERE o.order_uid = $1
+  AND ($2 = '' OR o.entry = $2)
\ No newline at end of file
@@ -0,0 +1,27 @@
+SELECT 
+    o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+    o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created,
+    o.oof_shard, o.created_at, o.updated_at,
+    
+    d.name as delivery_name, d.phone as delivery_phone, d.zip as delivery_zip,
+    d.city as delivery_city, d.address as delivery_address, d.region as delivery_region,
+    d.email as delivery_email,
+    
+    p.transaction, p.request_id, p.currency, p.provider, p.amount,
+    p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee,
+
+    COALESCE((
+        SELECT json_agg(json_build_object(
+            'id', i.id, 'chrt_id', i.chrt_id, 'track_number', i.track_number,
+            'price', i.price, 'rid', i.rid, 'name', i.name, 'sale', i.sale,
+            'size', i.size, 'total_price', i.total_price, 'nm_id', i.nm_id,
+            'brand', i.brand, 'status', i.status
+        ) ORDER BY i.id)
+        FROM order_items i
+        WHERE i.order_uid = o.order_uid
+    ), '[]'::json) as items
+FROM orders o
+LEFT JOIN deliveries d ON o.order_uid = d.order_uid
+LEFT JOIN payments p ON o.order_uid = p.order_uid
+WHERE o.order_uid = ANY($1)
+  AND ($2 = '' OR o.entry = $2)
\ No newline at end of file
@@ -0,0 +1,5 @@
+SELECT
+    id, message_key, payload, headers, original_topic, error_class, failure_reason, attempts,
+    first_seen, last_seen, dlq_partition, dlq_offset, status, note, updated_at
+FROM dlq_messages
+WHERE id = $1
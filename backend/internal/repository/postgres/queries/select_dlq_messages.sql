@@ -0,0 +1,10 @@
+SELECT
+    id, message_key, original_topic, error_class, failure_reason, attempts,
+    first_seen, last_seen, dlq_partition, dlq_offset, status, note, updated_at
+FROM dlq_messages
+WHERE ($1 = '' OR status = $1)
+  AND ($2 = '' OR error_class = $2)
+  AND ($3 = '' OR message_key = $3)
+  AND ($4 = '' OR strpos(failure_reason, $4) > 0)
+ORDER BY last_seen DESC, id DESC
+LIMIT $5 OFFSET $6
@@ -0,0 +1,3 @@
+SELECT status, dlq_partition, dlq_offset, last_seen
+FROM dlq_messages
+WHERE fingerprint = $1
@@ -0,0 +1,17 @@
+-- $1, $2, $3 - entry, locale и days правил хранения ('' - любое значение)
+-- $4 - текущее время, $5 - учитывать ли уже обезличенные заказы, $6 - размер пачки
+SELECT o.order_uid, o.entry
+FROM orders o
+JOIN LATERAL (
+    SELECT r.days
+    FROM unnest($1::text[], $2::text[], $3::int[]) AS r(entry, locale, days)
+    WHERE (r.entry = '' OR r.entry = o.entry)
+      AND (r.locale = '' OR r.locale = o.locale)
+    ORDER BY (r.entry <> '') DESC, (r.locale <> '') DESC
+    LIMIT 1
+) rule ON true
+WHERE rule.days > 0
+  AND o.date_created < $4::timestamptz - make_interval(days => rule.days)
+  AND ($5 OR o.anonymized_at IS NULL)
+ORDER BY o.date_created, o.order_uid
+LIMIT $6
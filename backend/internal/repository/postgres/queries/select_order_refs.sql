@@ -0,0 +1,5 @@
+SELECT order_uid, entry, COALESCE(shardkey, '') AS shardkey
+FROM orders
+WHERE order_uid > $1
+ORDER BY order_uid
+LIMIT $2
@@ -0,0 +1,3 @@
+SELECT shard
+FROM order_shard_directory
+WHERE order_uid = $1
@@ -0,0 +1,3 @@
+UPDATE dlq_messages
+SET note = $2, updated_at = now()
+WHERE id = $1
@@ -0,0 +1,3 @@
+UPDATE dlq_messages
+SET status = $3, updated_at = now()
+WHERE id = $1 AND status = $2
@@ -0,0 +1,17 @@
+INSERT INTO dlq_messages (
+    fingerprint, message_key, payload, headers, original_topic, error_class, failure_reason,
+    first_seen, last_seen, dlq_partition, dlq_offset
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9, $10)
+ON CONFLICT (fingerprint) DO UPDATE SET
+    headers = EXCLUDED.headers,
+    error_class = EXCLUDED.error_class,
+    failure_reason = EXCLUDED.failure_reason,
+    attempts = dlq_messages.attempts + 1,
+    last_seen = EXCLUDED.last_seen,
+    dlq_partition = EXCLUDED.dlq_partition,
+    dlq_offset = EXCLUDED.dlq_offset,
+    status = 'open',
+    updated_at = now()
+WHERE (dlq_messages.dlq_partition = EXCLUDED.dlq_partition AND EXCLUDED.dlq_offset > dlq_messages.dlq_offset)
+    OR (dlq_messages.dlq_partition <> EXCLUDED.dlq_partition AND EXCLUDED.last_seen > dlq_messages.last_seen)
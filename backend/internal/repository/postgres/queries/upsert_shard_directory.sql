@@ -0,0 +1,5 @@
+INSERT INTO order_shard_directory (order_uid, shard)
+VALUES ($1, $2)
+ON CONFLICT (order_uid) DO UPDATE SET
+    shard = EXCLUDED.shard,
+    updated_at = now()
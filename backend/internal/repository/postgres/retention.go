@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/lib/pq"
+)
+
+// retentionArgs раскладывает правила политики в параметры запросов
+func retentionArgs(policy domain.RetentionPolicy, now time.Time) []interface{} {
+	entries := make([]string, len(policy.Rules))
+	locales := make([]string, len(policy.Rules))
+	days := make([]int64, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		entries[i] = rule.Entry
+		locales[i] = rule.Locale
+		days[i] = int64(rule.Days)
+	}
+
+	// В режиме удаления обезличенные ранее заказы тоже подлежат очистке
+	includeAnonymized := policy.Mode == domain.RetentionModeDelete
+
+	return []interface{}{pq.Array(entries), pq.Array(locales), pq.Array(days), now, includeAnonymized}
+}
+
+// FindExpired возвращает до limit заказов, срок хранения которых истек
+func (r *OrderRepository) FindExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time, limit int) ([]domain.OrderRef, error) {
+	if len(policy.Rules) == 0 {
+		return nil, nil
+	}
+
+	args := append(retentionArgs(policy, now), limit)
+
+	var refs []domain.OrderRef
+	if err := r.db.SelectContext(ctx, &refs, selectExpiredOrderUIDsQuery, args...); err != nil {
+		r.logger.Error("failed to select expired orders", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to select expired orders: %w", err)
+	}
+
+	return refs, nil
+}
+
+// CountExpired подсчитывает просроченные заказы по каждому из правил
+func (r *OrderRepository) CountExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time) ([]domain.RetentionStat, error) {
+	if len(policy.Rules) == 0 {
+		return nil, nil
+	}
+
+	var rows []struct {
+		Entry  string `db:"entry"`
+		Locale string `db:"locale"`
+		Days   int    `db:"days"`
+		Count  int64  `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, countExpiredOrdersQuery, retentionArgs(policy, now)...); err != nil {
+		r.logger.Error("failed to count expired orders", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to count expired orders: %w", err)
+	}
+
+	stats := make([]domain.RetentionStat, len(rows))
+	for i, row := range rows {
+		stats[i] = domain.RetentionStat{
+			Rule:  domain.RetentionRule{Entry: row.Entry, Locale: row.Locale, Days: row.Days},
+			Count: row.Count,
+		}
+	}
+
+	return stats, nil
+}
+
+// DeleteByUIDs удаляет заказы вместе со связанными записями (ON DELETE CASCADE)
+func (r *OrderRepository) DeleteByUIDs(ctx context.Context, uids []string) (int64, error) {
+	if len(uids) == 0 {
+		return 0, nil
+	}
+
+	res, err := r.db.ExecContext(ctx, deleteOrdersByUIDsQuery, pq.Array(uids))
+	if err != nil {
+		r.logger.Error("failed to delete orders", slog.Int("count", len(uids)), slog.Any("error", err))
+		return 0, fmt.Errorf("failed to delete orders: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// AnonymizeByUIDs удаляет персональные данные покупателя из заказов: идентификатор
+// покупателя и подпись, все поля адреса доставки, а также идентификаторы платежа,
+// по которым покупателя можно найти у платежного провайдера. Сумма, валюта и состав
+// заказа сохраняются для отчетности.
+func (r *OrderRepository) AnonymizeByUIDs(ctx context.Context, uids []string) (int64, error) {
+	if len(uids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", slog.Any("error", err))
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.Error("failed to rollback transaction", slog.Any("error", rollbackErr))
+			}
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, anonymizeOrdersByUIDsQuery, pq.Array(uids))
+	if err != nil {
+		r.logger.Error("failed to anonymize orders", slog.Int("count", len(uids)), slog.Any("error", err))
+		return 0, fmt.Errorf("failed to anonymize orders: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, anonymizeDeliveriesByUIDsQuery, pq.Array(uids)); err != nil {
+		r.logger.Error("failed to anonymize deliveries", slog.Int("count", len(uids)), slog.Any("error", err))
+		return 0, fmt.Errorf("failed to anonymize deliveries: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, anonymizePaymentsByUIDsQuery, pq.Array(uids)); err != nil {
+		r.logger.Error("failed to anonymize payments", slog.Int("count", len(uids)), slog.Any("error", err))
+		return 0, fmt.Errorf("failed to anonymize payments: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", slog.Any("error", err))
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderRepository_Retention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	oldOrder := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	oldOrder.DateCreated = now.AddDate(0, 0, -100)
+
+	freshOrder := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	freshOrder.OrderUID = "fresh-order-uid"
+	freshOrder.DateCreated = now.AddDate(0, 0, -1)
+
+	otherEntryOrder := loadOrderFromJSON(t, "../../service/testdata/valid_order.json")
+	otherEntryOrder.OrderUID = "other-entry-uid"
+	otherEntryOrder.Entry = "KEEP"
+	otherEntryOrder.DateCreated = now.AddDate(0, 0, -100)
+
+	setup := func(t *testing.T) {
+		clearTables()
+		require.NoError(t, repo.Create(ctx, oldOrder))
+		require.NoError(t, repo.Create(ctx, freshOrder))
+		require.NoError(t, repo.Create(ctx, otherEntryOrder))
+	}
+
+	// Общее правило 30 дней, но для entry KEEP - бессрочно
+	rules := []domain.RetentionRule{{Days: 30}, {Entry: "KEEP", Days: 0}}
+	deletePolicy := domain.RetentionPolicy{Mode: domain.RetentionModeDelete, Rules: rules}
+	anonymizePolicy := domain.RetentionPolicy{Mode: domain.RetentionModeAnonymize, Rules: rules}
+
+	t.Run("find expired respects most specific rule", func(t *testing.T) {
+		setup(t)
+
+		refs, err := repo.FindExpired(ctx, deletePolicy, now, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.OrderRef{{OrderUID: oldOrder.OrderUID, Entry: oldOrder.Entry}}, refs)
+
+		stats, err := repo.CountExpired(ctx, deletePolicy, now)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, int64(1), stats[0].Count)
+		assert.Equal(t, rules[0], stats[0].Rule)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		setup(t)
+
+		affected, err := repo.DeleteByUIDs(ctx, []string{oldOrder.OrderUID})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), affected)
+
+		_, err = repo.GetByUID(ctx, oldOrder.OrderUID)
+		assert.Error(t, err)
+
+		var itemsCount int
+		require.NoError(t, db.Get(&itemsCount, "SELECT COUNT(*) FROM order_items WHERE order_uid = $1", oldOrder.OrderUID))
+		assert.Zero(t, itemsCount)
+	})
+
+	t.Run("anonymize", func(t *testing.T) {
+		setup(t)
+
+		affected, err := repo.AnonymizeByUIDs(ctx, []string{oldOrder.OrderUID})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), affected)
+
+		anonymized, err := repo.GetByUID(ctx, oldOrder.OrderUID)
+		require.NoError(t, err)
+		assert.Empty(t, anonymized.CustomerID)
+		assert.Empty(t, anonymized.Delivery.Name)
+		assert.Empty(t, anonymized.Delivery.Phone)
+		assert.Empty(t, anonymized.Delivery.Email)
+		assert.Empty(t, anonymized.Delivery.City)
+		assert.Empty(t, anonymized.Delivery.Region)
+		assert.Empty(t, anonymized.Payment.Transaction)
+		assert.Empty(t, anonymized.Payment.RequestID)
+		assert.Equal(t, oldOrder.Payment.Amount, anonymized.Payment.Amount)
+
+		// Обезличенные заказы не выбираются повторно в режиме anonymize,
+		// но подлежат удалению в режиме delete
+		refs, err := repo.FindExpired(ctx, anonymizePolicy, now, 10)
+		require.NoError(t, err)
+		assert.Empty(t, refs)
+
+		refs, err = repo.FindExpired(ctx, deletePolicy, now, 10)
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+		assert.Equal(t, oldOrder.OrderUID, refs[0].OrderUID)
+	})
+}
@@ -0,0 +1,37 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DB подключение к базе одного шарда
+type DB interface {
+	PingContext(ctx context.Context) error
+	Close() error
+}
+
+// DBGroup объединяет подключения ко всем шардам для проверки состояния и закрытия
+type DBGroup map[string]DB
+
+// PingContext проверяет подключение ко всем шардам
+func (g DBGroup) PingContext(ctx context.Context) error {
+	for name, db := range g {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("shard %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Close закрывает подключения ко всем шардам
+func (g DBGroup) Close() error {
+	var errs []error
+	for name, db := range g {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("shard %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,408 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// Shard хранилище заказов одного шарда (postgres.OrderRepository)
+type Shard interface {
+	Create(ctx context.Context, order *domain.Order) error
+	Copy(ctx context.Context, order *domain.Order) error
+	GetByUID(ctx context.Context, uid string) (*domain.Order, error)
+	GetByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error)
+	GetByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error)
+	GetByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error)
+	GetAll(ctx context.Context) ([]*domain.Order, error)
+	ForEachBatch(ctx context.Context, since time.Time, batchSize int, fn func(orders []*domain.Order) error) error
+	ListRefs(ctx context.Context, afterUID string, limit int) ([]domain.OrderRef, error)
+	FindExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time, limit int) ([]domain.OrderRef, error)
+	CountExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time) ([]domain.RetentionStat, error)
+	DeleteByUIDs(ctx context.Context, uids []string) (int64, error)
+	AnonymizeByUIDs(ctx context.Context, uids []string) (int64, error)
+}
+
+// Directory справочник UID -> шард (postgres.ShardDirectory)
+type Directory interface {
+	Lookup(ctx context.Context, uid string) (string, bool, error)
+	Put(ctx context.Context, uid, shard string) error
+	Delete(ctx context.Context, uids []string) error
+}
+
+// Repository маршрутизирует заказы по шардам на основе Order.ShardKey.
+// Чтение по UID идет через справочник, а при его промахе - опросом всех шардов.
+type Repository struct {
+	shards    map[string]Shard
+	names     []string
+	shardMap  *ShardMap
+	directory Directory
+	logger    *slog.Logger
+}
+
+// New создает репозиторий. directory может быть nil - тогда GetByUID всегда опрашивает все шарды.
+func New(shards map[string]Shard, shardMap *ShardMap, directory Directory, logger *slog.Logger) (*Repository, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("no shards configured")
+	}
+	for _, name := range shardMap.Shards() {
+		if _, ok := shards[name]; !ok {
+			return nil, fmt.Errorf("shard map references unknown shard %q", name)
+		}
+	}
+
+	names := make([]string, 0, len(shards))
+	for name := range shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return &Repository{
+		shards:    shards,
+		names:     names,
+		shardMap:  shardMap,
+		directory: directory,
+		logger:    logger,
+	}, nil
+}
+
+// Create сохраняет заказ в шард, определенный картой шардов, и регистрирует его в справочнике
+func (r *Repository) Create(ctx context.Context, order *domain.Order) error {
+	name, err := r.shardMap.Resolve(order.ShardKey)
+	if err != nil {
+		// Карта шардов статична: повтор не поможет до исправления конфигурации
+		return fmt.Errorf("failed to resolve shard: %w", domain.Permanent(err))
+	}
+
+	if err := r.shards[name].Create(ctx, order); err != nil {
+		return err
+	}
+
+	// Ошибка справочника не критична: чтение найдет заказ опросом всех шардов
+	if r.directory != nil {
+		if err := r.directory.Put(ctx, order.OrderUID, name); err != nil {
+			r.logger.Warn("failed to register order in shard directory",
+				slog.String("order_uid", order.OrderUID),
+				slog.String("shard", name),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	r.logger.Debug("order routed to shard",
+		slog.String("order_uid", order.OrderUID),
+		slog.String("shardkey", order.ShardKey),
+		slog.String("shard", name))
+
+	return nil
+}
+
+// GetByUID ищет заказ по справочнику, при промахе - во всех шардах параллельно
+func (r *Repository) GetByUID(ctx context.Context, uid string) (*domain.Order, error) {
+	if r.directory != nil {
+		name, found, err := r.directory.Lookup(ctx, uid)
+		if err != nil {
+			r.logger.Warn("shard directory lookup failed, falling back to fan-out",
+				slog.String("order_uid", uid),
+				slog.String("error", err.Error()))
+		}
+		if found {
+			if shard, ok := r.shards[name]; ok {
+				order, err := shard.GetByUID(ctx, uid)
+				if err == nil || !errors.Is(err, domain.ErrOrderNotFound) {
+					return order, err
+				}
+				// Справочник устарел (например, во время решардинга) - ищем во всех шардах
+			}
+		}
+	}
+
+	return r.fanOutGetByUID(ctx, uid)
+}
+
+func (r *Repository) fanOutGetByUID(ctx context.Context, uid string) (*domain.Order, error) {
+	type result struct {
+		shard string
+		order *domain.Order
+		err   error
+	}
+
+	fanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, len(r.names))
+	for _, name := range r.names {
+		go func(name string) {
+			order, err := r.shards[name].GetByUID(fanCtx, uid)
+			results <- result{shard: name, order: order, err: err}
+		}(name)
+	}
+
+	var errs []error
+	for range r.names {
+		res := <-results
+		if res.err == nil {
+			if r.directory != nil {
+				if err := r.directory.Put(ctx, uid, res.shard); err != nil {
+					r.logger.Warn("failed to repair shard directory",
+						slog.String("order_uid", uid),
+						slog.String("error", err.Error()))
+				}
+			}
+			return res.order, nil
+		}
+		if !errors.Is(res.err, domain.ErrOrderNotFound) {
+			errs = append(errs, fmt.Errorf("shard %s: %w", res.shard, res.err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, fmt.Errorf("%w: uid %s", domain.ErrOrderNotFound, uid)
+}
+
+// GetByUIDs запрашивает список UID во всех шардах параллельно и возвращает заказы в порядке uids
+func (r *Repository) GetByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	var (
+		mu    sync.Mutex
+		byUID = make(map[string]*domain.Order, len(uids))
+	)
+
+	err := r.scatter(ctx, func(ctx context.Context, name string, shard Shard) error {
+		shardOrders, err := shard.GetByUIDs(ctx, uids)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		for _, order := range shardOrders {
+			byUID[order.OrderUID] = order
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*domain.Order, 0, len(byUID))
+	for _, uid := range uids {
+		if order, ok := byUID[uid]; ok {
+			orders = append(orders, order)
+			delete(byUID, uid)
+		}
+	}
+	return orders, nil
+}
+
+// GetByCustomer собирает заказы покупателя со всех шардов (сначала новые).
+// Покупатель не входит в ключ шардирования, поэтому опрашиваются все шарды.
+func (r *Repository) GetByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	return r.gather(ctx, func(ctx context.Context, shard Shard) ([]*domain.Order, error) {
+		return shard.GetByCustomer(ctx, customerID)
+	})
+}
+
+// GetByTrackNumber ищет заказ с трек-номером во всех шардах и возвращает самый новый
+func (r *Repository) GetByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error) {
+	orders, err := r.gather(ctx, func(ctx context.Context, shard Shard) ([]*domain.Order, error) {
+		order, err := shard.GetByTrackNumber(ctx, trackNumber)
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []*domain.Order{order}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("%w: track number %s", domain.ErrOrderNotFound, trackNumber)
+	}
+	return orders[0], nil
+}
+
+// GetAll собирает заказы со всех шардов (сначала новые)
+func (r *Repository) GetAll(ctx context.Context) ([]*domain.Order, error) {
+	return r.gather(ctx, func(ctx context.Context, shard Shard) ([]*domain.Order, error) {
+		return shard.GetAll(ctx)
+	})
+}
+
+// gather выполняет fn на всех шардах и объединяет заказы (сначала новые)
+func (r *Repository) gather(ctx context.Context, fn func(ctx context.Context, shard Shard) ([]*domain.Order, error)) ([]*domain.Order, error) {
+	var (
+		mu     sync.Mutex
+		orders []*domain.Order
+	)
+
+	err := r.scatter(ctx, func(ctx context.Context, name string, shard Shard) error {
+		shardOrders, err := fn(ctx, shard)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		orders = append(orders, shardOrders...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].OrderUID < orders[j].OrderUID
+	})
+
+	return orders, nil
+}
+
+// ForEachBatch обходит заказы шардов последовательно, пачками внутри каждого шарда
+func (r *Repository) ForEachBatch(ctx context.Context, since time.Time, batchSize int, fn func(orders []*domain.Order) error) error {
+	for _, name := range r.names {
+		if err := r.shards[name].ForEachBatch(ctx, since, batchSize, fn); err != nil {
+			return fmt.Errorf("shard %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// FindExpired собирает просроченные заказы со всех шардов, не более limit
+func (r *Repository) FindExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time, limit int) ([]domain.OrderRef, error) {
+	var (
+		mu   sync.Mutex
+		refs []domain.OrderRef
+	)
+
+	err := r.scatter(ctx, func(ctx context.Context, name string, shard Shard) error {
+		shardRefs, err := shard.FindExpired(ctx, policy, now, limit)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		refs = append(refs, shardRefs...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].OrderUID < refs[j].OrderUID })
+	if len(refs) > limit {
+		refs = refs[:limit]
+	}
+	return refs, nil
+}
+
+// CountExpired суммирует статистику по правилам со всех шардов
+func (r *Repository) CountExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time) ([]domain.RetentionStat, error) {
+	var (
+		mu     sync.Mutex
+		counts = make(map[domain.RetentionRule]int64)
+	)
+
+	err := r.scatter(ctx, func(ctx context.Context, name string, shard Shard) error {
+		stats, err := shard.CountExpired(ctx, policy, now)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		for _, stat := range stats {
+			counts[stat.Rule] += stat.Count
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]domain.RetentionStat, 0, len(counts))
+	for _, rule := range policy.Rules {
+		if count, ok := counts[rule]; ok {
+			stats = append(stats, domain.RetentionStat{Rule: rule, Count: count})
+		}
+	}
+	return stats, nil
+}
+
+// DeleteByUIDs удаляет заказы во всех шардах и из справочника
+func (r *Repository) DeleteByUIDs(ctx context.Context, uids []string) (int64, error) {
+	affected, err := r.sumAffected(ctx, func(ctx context.Context, shard Shard) (int64, error) {
+		return shard.DeleteByUIDs(ctx, uids)
+	})
+	if err != nil {
+		return affected, err
+	}
+
+	if r.directory != nil {
+		if err := r.directory.Delete(ctx, uids); err != nil {
+			r.logger.Warn("failed to delete orders from shard directory", slog.String("error", err.Error()))
+		}
+	}
+	return affected, nil
+}
+
+// AnonymizeByUIDs обезличивает заказы во всех шардах
+func (r *Repository) AnonymizeByUIDs(ctx context.Context, uids []string) (int64, error) {
+	return r.sumAffected(ctx, func(ctx context.Context, shard Shard) (int64, error) {
+		return shard.AnonymizeByUIDs(ctx, uids)
+	})
+}
+
+func (r *Repository) sumAffected(ctx context.Context, fn func(ctx context.Context, shard Shard) (int64, error)) (int64, error) {
+	var (
+		mu    sync.Mutex
+		total int64
+	)
+	err := r.scatter(ctx, func(ctx context.Context, name string, shard Shard) error {
+		affected, err := fn(ctx, shard)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		total += affected
+		mu.Unlock()
+		return nil
+	})
+	return total, err
+}
+
+// scatter выполняет fn параллельно для каждого шарда и объединяет ошибки
+func (r *Repository) scatter(ctx context.Context, fn func(ctx context.Context, name string, shard Shard) error) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, name := range r.names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := fn(ctx, name, r.shards[name]); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("shard %s: %w", name, err))
+				mu.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
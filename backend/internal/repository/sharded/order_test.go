@@ -0,0 +1,405 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memShard хранилище шарда в памяти для тестов.
+type memShard struct {
+	mu     sync.Mutex
+	orders map[string]*domain.Order
+	err    error
+}
+
+func newMemShard() *memShard {
+	return &memShard{orders: make(map[string]*domain.Order)}
+}
+
+func (s *memShard) Create(ctx context.Context, order *domain.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if _, ok := s.orders[order.OrderUID]; ok {
+		return errors.New("duplicate key")
+	}
+	s.orders[order.OrderUID] = order
+	return nil
+}
+
+func (s *memShard) Copy(ctx context.Context, order *domain.Order) error {
+	return s.Create(ctx, order)
+}
+
+func (s *memShard) GetByUID(ctx context.Context, uid string) (*domain.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	order, ok := s.orders[uid]
+	if !ok {
+		return nil, fmt.Errorf("%w: uid %s", domain.ErrOrderNotFound, uid)
+	}
+	return order, nil
+}
+
+func (s *memShard) GetByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var orders []*domain.Order
+	for _, uid := range uids {
+		if order, ok := s.orders[uid]; ok {
+			orders = append(orders, order)
+		}
+	}
+	return orders, s.err
+}
+
+func (s *memShard) GetByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var orders []*domain.Order
+	for _, order := range s.orders {
+		if order.CustomerID == customerID {
+			orders = append(orders, order)
+		}
+	}
+	return orders, s.err
+}
+
+func (s *memShard) GetByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	var latest *domain.Order
+	for _, order := range s.orders {
+		if order.TrackNumber == trackNumber && (latest == nil || order.CreatedAt.After(latest.CreatedAt)) {
+			latest = order
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("%w: track number %s", domain.ErrOrderNotFound, trackNumber)
+	}
+	return latest, nil
+}
+
+func (s *memShard) GetAll(ctx context.Context) ([]*domain.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make([]*domain.Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		orders = append(orders, order)
+	}
+	return orders, s.err
+}
+
+func (s *memShard) ForEachBatch(ctx context.Context, since time.Time, batchSize int, fn func(orders []*domain.Order) error) error {
+	refs, _ := s.ListRefs(ctx, "", len(s.orders))
+	var orders []*domain.Order
+	for _, ref := range refs {
+		if order := s.orders[ref.OrderUID]; !order.DateCreated.Before(since) {
+			orders = append(orders, order)
+		}
+	}
+	for start := 0; start < len(orders); start += batchSize {
+		end := min(start+batchSize, len(orders))
+		batch := orders[start:end]
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memShard) ListRefs(ctx context.Context, afterUID string, limit int) ([]domain.OrderRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var refs []domain.OrderRef
+	for _, order := range s.orders {
+		if order.OrderUID > afterUID {
+			refs = append(refs, domain.OrderRef{OrderUID: order.OrderUID, Entry: order.Entry, ShardKey: order.ShardKey})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].OrderUID < refs[j].OrderUID })
+	if len(refs) > limit {
+		refs = refs[:limit]
+	}
+	return refs, nil
+}
+
+func (s *memShard) FindExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time, limit int) ([]domain.OrderRef, error) {
+	return nil, nil
+}
+
+func (s *memShard) CountExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time) ([]domain.RetentionStat, error) {
+	return []domain.RetentionStat{{Rule: policy.Rules[0], Count: int64(len(s.orders))}}, nil
+}
+
+func (s *memShard) DeleteByUIDs(ctx context.Context, uids []string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var affected int64
+	for _, uid := range uids {
+		if _, ok := s.orders[uid]; ok {
+			delete(s.orders, uid)
+			affected++
+		}
+	}
+	return affected, nil
+}
+
+func (s *memShard) AnonymizeByUIDs(ctx context.Context, uids []string) (int64, error) {
+	return 0, nil
+}
+
+// memDirectory справочник в памяти для тестов.
+type memDirectory struct {
+	mu     sync.Mutex
+	shards map[string]string
+}
+
+func newMemDirectory() *memDirectory {
+	return &memDirectory{shards: make(map[string]string)}
+}
+
+func (d *memDirectory) Lookup(ctx context.Context, uid string) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	shard, ok := d.shards[uid]
+	return shard, ok, nil
+}
+
+func (d *memDirectory) Put(ctx context.Context, uid, shard string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.shards[uid] = shard
+	return nil
+}
+
+func (d *memDirectory) Delete(ctx context.Context, uids []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, uid := range uids {
+		delete(d.shards, uid)
+	}
+	return nil
+}
+
+func newTestRepository(t *testing.T, shardMap string, directory Directory) (*Repository, *memShard, *memShard) {
+	t.Helper()
+	s1, s2 := newMemShard(), newMemShard()
+	m, err := ParseShardMap(shardMap)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo, err := New(map[string]Shard{"s1": s1, "s2": s2}, m, directory, logger)
+	require.NoError(t, err)
+	return repo, s1, s2
+}
+
+func testOrder(uid, shardKey string, createdAt time.Time) *domain.Order {
+	return &domain.Order{OrderUID: uid, ShardKey: shardKey, Entry: "WBIL", CreatedAt: createdAt}
+}
+
+func TestNew_UnknownShard(t *testing.T) {
+	m, err := ParseShardMap("*:s3")
+	require.NoError(t, err)
+
+	_, err = New(map[string]Shard{"s1": newMemShard()}, m, nil, slog.Default())
+	assert.Error(t, err)
+}
+
+func TestRepository_CreateAndGet(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("routes by shardkey and uses directory", func(t *testing.T) {
+		directory := newMemDirectory()
+		repo, s1, s2 := newTestRepository(t, "0-4:s1,5-9:s2", directory)
+
+		require.NoError(t, repo.Create(ctx, testOrder("a", "1", time.Now())))
+		require.NoError(t, repo.Create(ctx, testOrder("b", "7", time.Now())))
+
+		assert.Contains(t, s1.orders, "a")
+		assert.Contains(t, s2.orders, "b")
+		assert.Equal(t, "s2", directory.shards["b"])
+
+		order, err := repo.GetByUID(ctx, "b")
+		require.NoError(t, err)
+		assert.Equal(t, "b", order.OrderUID)
+	})
+
+	t.Run("unknown shardkey is permanent", func(t *testing.T) {
+		repo, _, _ := newTestRepository(t, "0-4:s1,5-9:s2", nil)
+
+		err := repo.Create(ctx, testOrder("c", "x", time.Now()))
+		require.Error(t, err)
+		assert.Equal(t, domain.ErrorClassPermanent, domain.ClassifyError(err))
+	})
+
+	t.Run("fan-out without directory", func(t *testing.T) {
+		repo, _, s2 := newTestRepository(t, "*:s1", nil)
+		s2.orders["x"] = testOrder("x", "1", time.Now())
+
+		order, err := repo.GetByUID(ctx, "x")
+		require.NoError(t, err)
+		assert.Equal(t, "x", order.OrderUID)
+	})
+
+	t.Run("fan-out repairs stale directory", func(t *testing.T) {
+		directory := newMemDirectory()
+		repo, _, s2 := newTestRepository(t, "*:s1", directory)
+		s2.orders["x"] = testOrder("x", "1", time.Now())
+		directory.shards["x"] = "s1"
+
+		order, err := repo.GetByUID(ctx, "x")
+		require.NoError(t, err)
+		assert.Equal(t, "x", order.OrderUID)
+		assert.Equal(t, "s2", directory.shards["x"])
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo, _, _ := newTestRepository(t, "*:s1", newMemDirectory())
+
+		_, err := repo.GetByUID(ctx, "missing")
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+	})
+
+	t.Run("shard failure is reported", func(t *testing.T) {
+		repo, s1, _ := newTestRepository(t, "*:s1", nil)
+		s1.err = errors.New("connection refused")
+
+		_, err := repo.GetByUID(ctx, "missing")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrOrderNotFound)
+	})
+}
+
+func TestRepository_ScatterGather(t *testing.T) {
+	ctx := context.Background()
+	repo, s1, s2 := newTestRepository(t, "*:s1", nil)
+
+	now := time.Now()
+	s1.orders["old"] = testOrder("old", "1", now.Add(-time.Hour))
+	s2.orders["new"] = testOrder("new", "7", now)
+
+	orders, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, "new", orders[0].OrderUID)
+	assert.Equal(t, "old", orders[1].OrderUID)
+
+	batch, err := repo.GetByUIDs(ctx, []string{"old", "missing", "new"})
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "old", batch[0].OrderUID)
+	assert.Equal(t, "new", batch[1].OrderUID)
+
+	var streamed []string
+	err = repo.ForEachBatch(ctx, time.Time{}, 1, func(orders []*domain.Order) error {
+		for _, order := range orders {
+			streamed = append(streamed, order.OrderUID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old", "new"}, streamed)
+
+	policy := domain.RetentionPolicy{Mode: domain.RetentionModeDelete, Rules: []domain.RetentionRule{{Days: 1}}}
+	stats, err := repo.CountExpired(ctx, policy, now)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(2), stats[0].Count)
+
+	affected, err := repo.DeleteByUIDs(ctx, []string{"old", "new"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+}
+
+func TestRepository_SecondaryLookups(t *testing.T) {
+	ctx := context.Background()
+	repo, s1, s2 := newTestRepository(t, "*:s1", nil)
+
+	now := time.Now()
+	old, recent := testOrder("old", "1", now.Add(-time.Hour)), testOrder("new", "7", now)
+	old.CustomerID, recent.CustomerID = "c1", "c1"
+	old.TrackNumber, recent.TrackNumber = "TRACK", "TRACK"
+	s1.orders["old"] = old
+	s2.orders["new"] = recent
+	s2.orders["other"] = testOrder("other", "7", now)
+
+	orders, err := repo.GetByCustomer(ctx, "c1")
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, "new", orders[0].OrderUID)
+	assert.Equal(t, "old", orders[1].OrderUID)
+
+	order, err := repo.GetByTrackNumber(ctx, "TRACK")
+	require.NoError(t, err)
+	assert.Equal(t, "new", order.OrderUID)
+
+	_, err = repo.GetByTrackNumber(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+
+	s1.err = errors.New("shard down")
+	_, err = repo.GetByTrackNumber(ctx, "TRACK")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrOrderNotFound)
+}
+
+func TestRepository_Reshard(t *testing.T) {
+	ctx := context.Background()
+	directory := newMemDirectory()
+	repo, s1, s2 := newTestRepository(t, "*:s1", directory)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, repo.Create(ctx, testOrder(fmt.Sprintf("uid-%d", i), fmt.Sprintf("%d", i), time.Now())))
+	}
+	require.Len(t, s1.orders, 10)
+
+	target, err := ParseShardMap("0-4:s1,5-9:s2")
+	require.NoError(t, err)
+
+	t.Run("dry run", func(t *testing.T) {
+		report, err := repo.Reshard(ctx, target, 3, true)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), report.Scanned)
+		assert.Equal(t, int64(5), report.Moved)
+		assert.Len(t, s1.orders, 10)
+	})
+
+	t.Run("move", func(t *testing.T) {
+		report, err := repo.Reshard(ctx, target, 3, false)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), report.Moved)
+		assert.Zero(t, report.Failed)
+		assert.Len(t, s1.orders, 5)
+		assert.Len(t, s2.orders, 5)
+		assert.Equal(t, "s2", directory.shards["uid-7"])
+
+		order, err := repo.GetByUID(ctx, "uid-7")
+		require.NoError(t, err)
+		assert.Equal(t, "uid-7", order.OrderUID)
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		report, err := repo.Reshard(ctx, target, 3, false)
+		require.NoError(t, err)
+		assert.Zero(t, report.Moved)
+	})
+}
@@ -0,0 +1,128 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// ReshardReport результат переноса заказов под новую карту шардов
+type ReshardReport struct {
+	Scanned int64
+	Moved   int64
+	Failed  int64
+	DryRun  bool
+}
+
+// Reshard переносит заказы, шард которых по карте target отличается от текущего.
+// Перенос идемпотентен: заказ сначала копируется в целевой шард, затем обновляется
+// справочник и только после этого заказ удаляется из исходного шарда.
+// Чтения во время переноса продолжают работать через справочник и опрос всех шардов.
+func (r *Repository) Reshard(ctx context.Context, target *ShardMap, batchSize int, dryRun bool) (*ReshardReport, error) {
+	for _, name := range target.Shards() {
+		if _, ok := r.shards[name]; !ok {
+			return nil, fmt.Errorf("target shard map references unknown shard %q", name)
+		}
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	report := &ReshardReport{DryRun: dryRun}
+
+	for _, source := range r.names {
+		r.logger.Info("resharding: scanning shard", slog.String("shard", source))
+
+		after := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+
+			refs, err := r.shards[source].ListRefs(ctx, after, batchSize)
+			if err != nil {
+				return report, fmt.Errorf("failed to list orders in shard %s: %w", source, err)
+			}
+			if len(refs) == 0 {
+				break
+			}
+			after = refs[len(refs)-1].OrderUID
+
+			for _, ref := range refs {
+				report.Scanned++
+
+				dest, err := target.Resolve(ref.ShardKey)
+				if err != nil {
+					report.Failed++
+					r.logger.Error("resharding: failed to resolve target shard",
+						slog.String("order_uid", ref.OrderUID),
+						slog.String("error", err.Error()))
+					continue
+				}
+				if dest == source {
+					continue
+				}
+
+				if dryRun {
+					report.Moved++
+					continue
+				}
+
+				if err := r.moveOrder(ctx, ref.OrderUID, source, dest); err != nil {
+					report.Failed++
+					r.logger.Error("resharding: failed to move order",
+						slog.String("order_uid", ref.OrderUID),
+						slog.String("from", source),
+						slog.String("to", dest),
+						slog.String("error", err.Error()))
+					continue
+				}
+				report.Moved++
+			}
+
+			r.logger.Info("resharding progress",
+				slog.String("shard", source),
+				slog.Int64("scanned", report.Scanned),
+				slog.Int64("moved", report.Moved),
+				slog.Int64("failed", report.Failed),
+				slog.Bool("dry_run", dryRun))
+		}
+	}
+
+	return report, nil
+}
+
+func (r *Repository) moveOrder(ctx context.Context, uid, source, dest string) error {
+	order, err := r.shards[source].GetByUID(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("failed to read order: %w", err)
+	}
+
+	// Заказ мог быть скопирован при предыдущем прерванном запуске.
+	// Товары копируются с прежними id, уникальными во всех шардах (см. postgres.OrderRepository.SetItemIDSource)
+	_, err = r.shards[dest].GetByUID(ctx, uid)
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrOrderNotFound):
+		if err := r.shards[dest].Copy(ctx, order); err != nil && !errors.Is(err, domain.ErrOrderExists) {
+			return fmt.Errorf("failed to copy order: %w", err)
+		}
+	default:
+		return fmt.Errorf("failed to check target shard: %w", err)
+	}
+
+	if r.directory != nil {
+		if err := r.directory.Put(ctx, uid, dest); err != nil {
+			return fmt.Errorf("failed to update shard directory: %w", err)
+		}
+	}
+
+	if _, err := r.shards[source].DeleteByUIDs(ctx, []string{uid}); err != nil {
+		return fmt.Errorf("failed to delete order from source shard: %w", err)
+	}
+
+	return nil
+}
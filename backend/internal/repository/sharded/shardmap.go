@@ -0,0 +1,126 @@
+package sharded
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type shardRange struct {
+	from, to int
+	shard    string
+}
+
+// ShardMap сопоставляет значение Order.ShardKey имени шарда
+type ShardMap struct {
+	exact    map[string]string
+	ranges   []shardRange
+	fallback string
+}
+
+// ParseShardMap разбирает карту шардов вида "0-4:s1,5-9:s2,*:s1".
+// Ключ - точное значение shardkey, числовой диапазон (включительно) или "*" для остальных.
+func ParseShardMap(s string) (*ShardMap, error) {
+	m := &ShardMap{exact: make(map[string]string)}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		// Имя шарда идет после последнего ":", ключ может содержать "-"
+		idx := strings.LastIndex(part, ":")
+		if idx <= 0 || idx == len(part)-1 {
+			return nil, fmt.Errorf("invalid shard map entry %q: expected key:shard", part)
+		}
+		key, shard := strings.TrimSpace(part[:idx]), strings.TrimSpace(part[idx+1:])
+
+		if key == "*" {
+			if m.fallback != "" {
+				return nil, fmt.Errorf("duplicate default shard in shard map")
+			}
+			m.fallback = shard
+			continue
+		}
+
+		if from, to, ok := parseRange(key); ok {
+			if from > to {
+				return nil, fmt.Errorf("invalid shard map range %q", key)
+			}
+			m.ranges = append(m.ranges, shardRange{from: from, to: to, shard: shard})
+			continue
+		}
+
+		if _, exists := m.exact[key]; exists {
+			return nil, fmt.Errorf("duplicate shard map key %q", key)
+		}
+		m.exact[key] = shard
+	}
+
+	if len(m.exact) == 0 && len(m.ranges) == 0 && m.fallback == "" {
+		return nil, fmt.Errorf("shard map is empty")
+	}
+
+	return m, nil
+}
+
+func parseRange(key string) (int, int, bool) {
+	fromStr, toStr, ok := strings.Cut(key, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	from, err := strconv.Atoi(strings.TrimSpace(fromStr))
+	if err != nil {
+		return 0, 0, false
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(toStr))
+	if err != nil {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+// Resolve возвращает шард для значения shardkey.
+// Точное совпадение важнее диапазона, диапазон важнее шарда по умолчанию.
+func (m *ShardMap) Resolve(shardKey string) (string, error) {
+	if shard, ok := m.exact[shardKey]; ok {
+		return shard, nil
+	}
+
+	if n, err := strconv.Atoi(shardKey); err == nil {
+		for _, r := range m.ranges {
+			if n >= r.from && n <= r.to {
+				return r.shard, nil
+			}
+		}
+	}
+
+	if m.fallback != "" {
+		return m.fallback, nil
+	}
+
+	return "", fmt.Errorf("no shard for shardkey %q", shardKey)
+}
+
+// Shards возвращает отсортированный список шардов, упомянутых в карте
+func (m *ShardMap) Shards() []string {
+	set := make(map[string]struct{})
+	for _, shard := range m.exact {
+		set[shard] = struct{}{}
+	}
+	for _, r := range m.ranges {
+		set[r.shard] = struct{}{}
+	}
+	if m.fallback != "" {
+		set[m.fallback] = struct{}{}
+	}
+
+	shards := make([]string, 0, len(set))
+	for shard := range set {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+	return shards
+}
@@ -0,0 +1,48 @@
+package sharded
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseShardMap тестирует разбор и применение карты шардов.
+func TestParseShardMap(t *testing.T) {
+	t.Run("ranges, exact and default", func(t *testing.T) {
+		m, err := ParseShardMap("0-4:s1, 5-9:s2, 7:s3, *:s1")
+		require.NoError(t, err)
+
+		cases := map[string]string{
+			"0":   "s1",
+			"4":   "s1",
+			"5":   "s2",
+			"7":   "s3",
+			"9":   "s2",
+			"42":  "s1",
+			"abc": "s1",
+		}
+		for key, expected := range cases {
+			shard, err := m.Resolve(key)
+			require.NoError(t, err, key)
+			assert.Equal(t, expected, shard, key)
+		}
+
+		assert.Equal(t, []string{"s1", "s2", "s3"}, m.Shards())
+	})
+
+	t.Run("no default", func(t *testing.T) {
+		m, err := ParseShardMap("0-4:s1")
+		require.NoError(t, err)
+
+		_, err = m.Resolve("5")
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		for _, s := range []string{"", "s1", "0-4:", ":s1", "5-1:s1", "*:s1,*:s2", "a:s1,a:s2"} {
+			_, err := ParseShardMap(s)
+			assert.Error(t, err, s)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// CacheStore операции администрирования общего кэша (redis.Cache)
+type CacheStore interface {
+	Inspect(ctx context.Context, key string) (*domain.CacheEntry, bool, error)
+	Flush(ctx context.Context) (int64, error)
+	Stats(ctx context.Context) (*domain.CacheStats, error)
+}
+
+// InvalidationBus рассылает полную очистку локальных кэшей всем репликам (invalidation.Bus)
+type InvalidationBus interface {
+	PublishFlush(ctx context.Context) error
+}
+
+// CacheRestorer перезагружает кэш из базы (OrderService)
+type CacheRestorer interface {
+	RestoreCache(ctx context.Context) error
+}
+
+var (
+	// ErrNotCached возвращается, если заказа нет в кэше
+	ErrNotCached = errors.New("order is not cached")
+	// ErrRestoreInProgress возвращается, если восстановление кэша уже выполняется
+	ErrRestoreInProgress = errors.New("cache restore is already in progress")
+)
+
+// CacheAdminService операции оператора над кэшем заказов
+type CacheAdminService struct {
+	store    CacheStore
+	cache    CacheEvicter
+	restorer CacheRestorer
+	bus      InvalidationBus
+	logger   *slog.Logger
+	// lifetime контекст приложения, на котором выполняется фоновое восстановление
+	lifetime context.Context
+
+	tenantIsolation bool
+	restoring       atomic.Bool
+}
+
+// NewCacheAdminService создает сервис администрирования. cache - кэш, через который
+// работает OrderService, чтобы удаление заказа затрагивало и локальные копии реплик.
+// ctx - контекст жизни приложения: его отмена прерывает запущенное восстановление.
+func NewCacheAdminService(ctx context.Context, store CacheStore, cache CacheEvicter, restorer CacheRestorer, logger *slog.Logger) *CacheAdminService {
+	return &CacheAdminService{
+		store:    store,
+		cache:    cache,
+		restorer: restorer,
+		logger:   logger,
+		lifetime: ctx,
+	}
+}
+
+// SetTenantIsolation должен совпадать с настройкой OrderService, чтобы ключи кэша совпадали
+func (s *CacheAdminService) SetTenantIsolation(enabled bool) {
+	s.tenantIsolation = enabled
+}
+
+// SetInvalidationBus включает рассылку полной очистки локальных кэшей реплик
+// при очистке кэша
+func (s *CacheAdminService) SetInvalidationBus(bus InvalidationBus) {
+	s.bus = bus
+}
+
+// Entry возвращает запись заказа в кэше и ее TTL
+func (s *CacheAdminService) Entry(ctx context.Context, tenant, uid string) (*domain.CacheEntry, error) {
+	key, err := s.key(tenant, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, found, err := s.store.Inspect(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotCached
+	}
+	return entry, nil
+}
+
+// Evict удаляет заказ из кэша
+func (s *CacheAdminService) Evict(ctx context.Context, tenant, uid string) error {
+	key, err := s.key(tenant, uid)
+	if err != nil {
+		return err
+	}
+
+	s.cache.Delete(ctx, key)
+	s.logger.Info("order evicted from cache by operator", slog.String("key", key))
+	return nil
+}
+
+// Flush удаляет все заказы из кэша и возвращает число удаленных ключей
+func (s *CacheAdminService) Flush(ctx context.Context) (int64, error) {
+	deleted, err := s.store.Flush(ctx)
+	if err != nil {
+		s.logger.Error("failed to flush cache", slog.Int64("deleted", deleted), slog.Any("error", err))
+		return deleted, err
+	}
+
+	s.logger.Info("cache flushed by operator", slog.Int64("deleted", deleted))
+
+	// Иначе реплики продолжат отдавать локальные копии до истечения их TTL
+	return deleted, s.FlushLocal(ctx)
+}
+
+// FlushLocal очищает локальные кэши всех реплик, не затрагивая Redis
+func (s *CacheAdminService) FlushLocal(ctx context.Context) error {
+	if s.bus == nil {
+		return nil
+	}
+	if err := s.bus.PublishFlush(ctx); err != nil {
+		s.logger.Error("failed to publish local cache flush", slog.Any("error", err))
+		return fmt.Errorf("failed to publish local cache flush: %w", err)
+	}
+	return nil
+}
+
+// Restore запускает восстановление кэша из базы в фоне на контексте приложения:
+// оно не прерывается вместе с HTTP-запросом, который его запустил, но
+// останавливается при завершении приложения.
+// Одновременно выполняется не более одного восстановления.
+func (s *CacheAdminService) Restore(ctx context.Context) error {
+	if !s.restoring.CompareAndSwap(false, true) {
+		return ErrRestoreInProgress
+	}
+
+	go func() {
+		defer s.restoring.Store(false)
+
+		start := time.Now()
+		s.logger.Info("cache restore triggered by operator")
+		if err := s.restorer.RestoreCache(s.lifetime); err != nil {
+			s.logger.Error("operator-triggered cache restore failed", slog.Any("error", err))
+			return
+		}
+		s.logger.Info("operator-triggered cache restore finished", slog.Duration("duration", time.Since(start)))
+	}()
+	return nil
+}
+
+// Restoring сообщает, выполняется ли восстановление кэша
+func (s *CacheAdminService) Restoring() bool {
+	return s.restoring.Load()
+}
+
+// Stats возвращает статистику кэша
+func (s *CacheAdminService) Stats(ctx context.Context) (*domain.CacheStats, error) {
+	return s.store.Stats(ctx)
+}
+
+func (s *CacheAdminService) key(tenant, uid string) (string, error) {
+	if s.tenantIsolation && tenant == "" {
+		return "", ErrTenantRequired
+	}
+	return orderCacheKey(s.tenantIsolation, tenant, uid), nil
+}
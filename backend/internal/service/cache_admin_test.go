@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockCacheStore мок для интерфейса CacheStore.
+type MockCacheStore struct {
+	mock.Mock
+}
+
+func (m *MockCacheStore) Inspect(ctx context.Context, key string) (*domain.CacheEntry, bool, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*domain.CacheEntry), args.Bool(1), args.Error(2)
+}
+
+func (m *MockCacheStore) Flush(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCacheStore) Stats(ctx context.Context) (*domain.CacheStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CacheStats), args.Error(1)
+}
+
+// MockCacheRestorer мок для интерфейса CacheRestorer.
+type MockCacheRestorer struct {
+	mock.Mock
+}
+
+func (m *MockCacheRestorer) RestoreCache(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// MockInvalidationBus мок для интерфейса InvalidationBus.
+type MockInvalidationBus struct {
+	mock.Mock
+}
+
+func (m *MockInvalidationBus) PublishFlush(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func newTestCacheAdminService() (*CacheAdminService, *MockCacheStore, *MockCacheEvicter, *MockCacheRestorer) {
+	return newTestCacheAdminServiceWithContext(context.Background())
+}
+
+func newTestCacheAdminServiceWithContext(ctx context.Context) (*CacheAdminService, *MockCacheStore, *MockCacheEvicter, *MockCacheRestorer) {
+	store, cache, restorer := new(MockCacheStore), new(MockCacheEvicter), new(MockCacheRestorer)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return NewCacheAdminService(ctx, store, cache, restorer, logger), store, cache, restorer
+}
+
+// TestCacheAdminService_Entry тестирует метод Entry.
+func TestCacheAdminService_Entry(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		s, store, _, _ := newTestCacheAdminService()
+		entry := &domain.CacheEntry{Key: "order:test-uid", Format: "json"}
+		store.On("Inspect", mock.Anything, "test-uid").Return(entry, true, nil).Once()
+
+		result, err := s.Entry(context.Background(), "", "test-uid")
+		require.NoError(t, err)
+		assert.Equal(t, entry, result)
+	})
+
+	t.Run("not cached", func(t *testing.T) {
+		s, store, _, _ := newTestCacheAdminService()
+		store.On("Inspect", mock.Anything, "test-uid").Return(nil, false, nil).Once()
+
+		_, err := s.Entry(context.Background(), "", "test-uid")
+		assert.ErrorIs(t, err, ErrNotCached)
+	})
+
+	t.Run("tenant isolation", func(t *testing.T) {
+		s, store, _, _ := newTestCacheAdminService()
+		s.SetTenantIsolation(true)
+
+		_, err := s.Entry(context.Background(), "", "test-uid")
+		assert.ErrorIs(t, err, ErrTenantRequired)
+
+		store.On("Inspect", mock.Anything, "WBIL:test-uid").Return(&domain.CacheEntry{}, true, nil).Once()
+		_, err = s.Entry(context.Background(), "WBIL", "test-uid")
+		require.NoError(t, err)
+		store.AssertExpectations(t)
+	})
+}
+
+// TestCacheAdminService_EvictAndFlush тестирует методы Evict и Flush.
+func TestCacheAdminService_EvictAndFlush(t *testing.T) {
+	s, store, cache, _ := newTestCacheAdminService()
+	cache.On("Delete", mock.Anything, []string{"test-uid"}).Once()
+	store.On("Flush", mock.Anything).Return(int64(3), nil).Once()
+
+	require.NoError(t, s.Evict(context.Background(), "", "test-uid"))
+	deleted, err := s.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	cache.AssertExpectations(t)
+	store.AssertExpectations(t)
+}
+
+// TestCacheAdminService_FlushBroadcast тестирует рассылку очистки локальных кэшей реплик.
+func TestCacheAdminService_FlushBroadcast(t *testing.T) {
+	s, store, _, _ := newTestCacheAdminService()
+	bus := new(MockInvalidationBus)
+	s.SetInvalidationBus(bus)
+
+	store.On("Flush", mock.Anything).Return(int64(0), errors.New("redis is unavailable")).Once()
+	_, err := s.Flush(context.Background())
+	assert.Error(t, err)
+	bus.AssertNotCalled(t, "PublishFlush", mock.Anything)
+
+	store.On("Flush", mock.Anything).Return(int64(5), nil).Once()
+	bus.On("PublishFlush", mock.Anything).Return(nil).Twice()
+	deleted, err := s.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), deleted)
+
+	require.NoError(t, s.FlushLocal(context.Background()))
+	bus.AssertExpectations(t)
+}
+
+// TestCacheAdminService_Restore тестирует запуск восстановления кэша.
+func TestCacheAdminService_Restore(t *testing.T) {
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+	s, _, _, restorer := newTestCacheAdminServiceWithContext(appCtx)
+	release := make(chan struct{})
+	restorer.On("RestoreCache", mock.Anything).Run(func(mock.Arguments) { <-release }).Return(errors.New("db down")).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, s.Restore(ctx))
+	cancel()
+
+	// Повторный запуск во время восстановления отклоняется
+	assert.ErrorIs(t, s.Restore(context.Background()), ErrRestoreInProgress)
+	assert.True(t, s.Restoring())
+
+	close(release)
+	assert.Eventually(t, func() bool { return !s.Restoring() }, time.Second, 10*time.Millisecond)
+	restorer.AssertExpectations(t)
+
+	// Контекст восстановления не отменяется вместе с запросом, но отменяется при остановке приложения
+	restoreCtx := restorer.Calls[0].Arguments.Get(0).(context.Context)
+	assert.NoError(t, restoreCtx.Err())
+	stopApp()
+	assert.ErrorIs(t, restoreCtx.Err(), context.Canceled)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// DLQRepository хранилище сообщений DLQ (postgres.DLQRepository)
+type DLQRepository interface {
+	Save(ctx context.Context, entry *domain.DLQEntry) error
+	List(ctx context.Context, filter domain.DLQFilter) ([]*domain.DLQEntry, error)
+	Get(ctx context.Context, id int64) (*domain.DLQEntry, error)
+	SetNote(ctx context.Context, id int64, note string) error
+	SetStatus(ctx context.Context, id int64, from, to domain.DLQStatus) (bool, error)
+}
+
+// DLQRequeuer публикует сообщение DLQ обратно в исходный топик (kafka.Requeuer)
+type DLQRequeuer interface {
+	Requeue(ctx context.Context, entry *domain.DLQEntry) error
+}
+
+// DLQRecorder сохраняет сообщения, прочитанные из DLQ (DLQService)
+type DLQRecorder interface {
+	Record(ctx context.Context, entry *domain.DLQEntry) error
+}
+
+// ErrDLQEntryClosed возвращается при попытке отбросить или переотправить уже разобранное сообщение
+var ErrDLQEntryClosed = errors.New("dlq entry is already discarded or requeued")
+
+const (
+	defaultDLQListLimit = 50
+	maxDLQListLimit     = 500
+)
+
+// DLQService разбор сообщений DLQ оператором без доступа к Kafka
+type DLQService struct {
+	repo     DLQRepository
+	requeuer DLQRequeuer
+	logger   *slog.Logger
+}
+
+func NewDLQService(repo DLQRepository, requeuer DLQRequeuer, logger *slog.Logger) *DLQService {
+	return &DLQService{
+		repo:     repo,
+		requeuer: requeuer,
+		logger:   logger,
+	}
+}
+
+// Record сохраняет сообщение DLQ
+func (s *DLQService) Record(ctx context.Context, entry *domain.DLQEntry) error {
+	return s.repo.Save(ctx, entry)
+}
+
+// List возвращает сообщения DLQ по фильтру
+func (s *DLQService) List(ctx context.Context, filter domain.DLQFilter) ([]*domain.DLQEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultDLQListLimit
+	}
+	filter.Limit = min(filter.Limit, maxDLQListLimit)
+	filter.Offset = max(filter.Offset, 0)
+	return s.repo.List(ctx, filter)
+}
+
+// Get возвращает сообщение DLQ с payload и заголовками
+func (s *DLQService) Get(ctx context.Context, id int64) (*domain.DLQEntry, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Annotate сохраняет заметку оператора к сообщению
+func (s *DLQService) Annotate(ctx context.Context, id int64, note string) error {
+	return s.repo.SetNote(ctx, id, note)
+}
+
+// Discard отбрасывает сообщение: оно остается в базе, но больше не ждет разбора
+func (s *DLQService) Discard(ctx context.Context, id int64) error {
+	if err := s.transition(ctx, id, domain.DLQStatusOpen, domain.DLQStatusDiscarded); err != nil {
+		return err
+	}
+	s.logger.Info("dlq message discarded by operator", slog.Int64("id", id))
+	return nil
+}
+
+// Requeue публикует сообщение обратно в исходный топик. Статус меняется до публикации,
+// чтобы два одновременных запроса не опубликовали сообщение дважды, и возвращается при ошибке.
+func (s *DLQService) Requeue(ctx context.Context, id int64) error {
+	entry, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if entry.Status != domain.DLQStatusOpen {
+		return ErrDLQEntryClosed
+	}
+	if err := s.transition(ctx, id, domain.DLQStatusOpen, domain.DLQStatusRequeued); err != nil {
+		return err
+	}
+
+	if err := s.requeuer.Requeue(ctx, entry); err != nil {
+		s.logger.Error("failed to requeue dlq message", slog.Int64("id", id), slog.Any("error", err))
+		// Запись возвращается в разбор, даже если HTTP-запрос уже отменен
+		if _, revertErr := s.repo.SetStatus(context.WithoutCancel(ctx), id, domain.DLQStatusRequeued, domain.DLQStatusOpen); revertErr != nil {
+			s.logger.Error("failed to reopen dlq message", slog.Int64("id", id), slog.Any("error", revertErr))
+		}
+		return fmt.Errorf("failed to requeue dlq message: %w", err)
+	}
+
+	s.logger.Info("dlq message requeued by operator",
+		slog.Int64("id", id),
+		slog.String("key", entry.Key),
+		slog.String("topic", entry.OriginalTopic))
+	return nil
+}
+
+// transition меняет статус открытого сообщения
+func (s *DLQService) transition(ctx context.Context, id int64, from, to domain.DLQStatus) error {
+	ok, err := s.repo.SetStatus(ctx, id, from, to)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	// Записи нет или ее уже разобрали
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrDLQEntryClosed
+}
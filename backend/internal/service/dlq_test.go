@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDLQRepository мок для интерфейса DLQRepository.
+type MockDLQRepository struct {
+	mock.Mock
+}
+
+func (m *MockDLQRepository) Save(ctx context.Context, entry *domain.DLQEntry) error {
+	return m.Called(ctx, entry).Error(0)
+}
+
+func (m *MockDLQRepository) List(ctx context.Context, filter domain.DLQFilter) ([]*domain.DLQEntry, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.DLQEntry), args.Error(1)
+}
+
+func (m *MockDLQRepository) Get(ctx context.Context, id int64) (*domain.DLQEntry, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DLQEntry), args.Error(1)
+}
+
+func (m *MockDLQRepository) SetNote(ctx context.Context, id int64, note string) error {
+	return m.Called(ctx, id, note).Error(0)
+}
+
+func (m *MockDLQRepository) SetStatus(ctx context.Context, id int64, from, to domain.DLQStatus) (bool, error) {
+	args := m.Called(ctx, id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockDLQRequeuer мок для интерфейса DLQRequeuer.
+type MockDLQRequeuer struct {
+	mock.Mock
+}
+
+func (m *MockDLQRequeuer) Requeue(ctx context.Context, entry *domain.DLQEntry) error {
+	return m.Called(ctx, entry).Error(0)
+}
+
+func newTestDLQService() (*DLQService, *MockDLQRepository, *MockDLQRequeuer) {
+	repo := new(MockDLQRepository)
+	requeuer := new(MockDLQRequeuer)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return NewDLQService(repo, requeuer, logger), repo, requeuer
+}
+
+func TestDLQService_List(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newTestDLQService()
+
+	repo.On("List", ctx, domain.DLQFilter{Status: domain.DLQStatusOpen, Limit: defaultDLQListLimit}).
+		Return([]*domain.DLQEntry{}, nil).Once()
+	repo.On("List", ctx, domain.DLQFilter{Limit: maxDLQListLimit}).
+		Return([]*domain.DLQEntry{}, nil).Once()
+
+	_, err := svc.List(ctx, domain.DLQFilter{Status: domain.DLQStatusOpen, Offset: -1})
+	require.NoError(t, err)
+	_, err = svc.List(ctx, domain.DLQFilter{Limit: 10000})
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestDLQService_Discard(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("open entry", func(t *testing.T) {
+		svc, repo, _ := newTestDLQService()
+		repo.On("SetStatus", ctx, int64(1), domain.DLQStatusOpen, domain.DLQStatusDiscarded).Return(true, nil)
+
+		require.NoError(t, svc.Discard(ctx, 1))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("already closed", func(t *testing.T) {
+		svc, repo, _ := newTestDLQService()
+		repo.On("SetStatus", ctx, int64(1), domain.DLQStatusOpen, domain.DLQStatusDiscarded).Return(false, nil)
+		repo.On("Get", ctx, int64(1)).Return(&domain.DLQEntry{ID: 1, Status: domain.DLQStatusRequeued}, nil)
+
+		assert.ErrorIs(t, svc.Discard(ctx, 1), ErrDLQEntryClosed)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		svc, repo, _ := newTestDLQService()
+		repo.On("SetStatus", ctx, int64(1), domain.DLQStatusOpen, domain.DLQStatusDiscarded).Return(false, nil)
+		repo.On("Get", ctx, int64(1)).Return(nil, domain.ErrDLQEntryNotFound)
+
+		assert.ErrorIs(t, svc.Discard(ctx, 1), domain.ErrDLQEntryNotFound)
+	})
+}
+
+func TestDLQService_Requeue(t *testing.T) {
+	ctx := context.Background()
+	entry := &domain.DLQEntry{ID: 1, Key: "uid-1", OriginalTopic: "orders", Status: domain.DLQStatusOpen}
+
+	t.Run("success", func(t *testing.T) {
+		svc, repo, requeuer := newTestDLQService()
+		repo.On("Get", ctx, int64(1)).Return(entry, nil)
+		repo.On("SetStatus", ctx, int64(1), domain.DLQStatusOpen, domain.DLQStatusRequeued).Return(true, nil)
+		requeuer.On("Requeue", ctx, entry).Return(nil)
+
+		require.NoError(t, svc.Requeue(ctx, 1))
+		repo.AssertExpectations(t)
+		requeuer.AssertExpectations(t)
+	})
+
+	t.Run("publish failure reopens entry", func(t *testing.T) {
+		svc, repo, requeuer := newTestDLQService()
+		repo.On("Get", ctx, int64(1)).Return(entry, nil)
+		repo.On("SetStatus", ctx, int64(1), domain.DLQStatusOpen, domain.DLQStatusRequeued).Return(true, nil)
+		repo.On("SetStatus", mock.Anything, int64(1), domain.DLQStatusRequeued, domain.DLQStatusOpen).Return(true, nil)
+		requeuer.On("Requeue", ctx, entry).Return(errors.New("broker unavailable"))
+
+		assert.Error(t, svc.Requeue(ctx, 1))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("closed entry is not published", func(t *testing.T) {
+		svc, repo, requeuer := newTestDLQService()
+		repo.On("Get", ctx, int64(1)).Return(&domain.DLQEntry{ID: 1, Status: domain.DLQStatusDiscarded}, nil)
+
+		assert.ErrorIs(t, svc.Requeue(ctx, 1), ErrDLQEntryClosed)
+		requeuer.AssertNotCalled(t, "Requeue", mock.Anything, mock.Anything)
+	})
+}
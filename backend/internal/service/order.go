@@ -2,16 +2,25 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"time"
 
 	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"golang.org/x/sync/singleflight"
 )
 
 type OrderRepository interface {
 	Create(ctx context.Context, order *domain.Order) error
 	GetByUID(ctx context.Context, uid string) (*domain.Order, error)
+	GetByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error)
+	GetByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error)
+	GetByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error)
 	GetAll(ctx context.Context) ([]*domain.Order, error)
+	ForEachBatch(ctx context.Context, since time.Time, batchSize int, fn func(orders []*domain.Order) error) error
 }
 
 type OrderCache interface {
@@ -20,17 +29,54 @@ type OrderCache interface {
 	LoadFromDB(ctx context.Context, orders map[string]*domain.Order)
 }
 
+// OrderJSONCache отдает закэшированный заказ в JSON без декодирования
+type OrderJSONCache interface {
+	GetJSON(ctx context.Context, key string) ([]byte, bool)
+}
+
+// NegativeCache запоминает UID заказов, которых нет в базе
+type NegativeCache interface {
+	SetMissing(ctx context.Context, key string, ttl time.Duration)
+	IsMissing(ctx context.Context, key string) bool
+	ClearMissing(ctx context.Context, key string)
+}
+
+// OrderIndex вторичные индексы кэша по покупателю и трек-номеру (redis.Cache).
+// Индексы обновляются самим кэшем при сохранении и удалении заказов; ключи
+// строятся как ключи заказов, но вместо UID содержат customer_id или трек-номер.
+type OrderIndex interface {
+	CustomerUIDs(ctx context.Context, key string) ([]string, bool)
+	SetCustomerUIDs(ctx context.Context, key string, uids []string)
+	TrackUID(ctx context.Context, key string) (string, bool)
+}
+
 // OrderServicer определяет интерфейс для сервиса
 type OrderServicer interface {
 	GetOrderByUID(ctx context.Context, uid string) (*domain.Order, error)
+	GetOrderJSON(ctx context.Context, uid string) ([]byte, error)
+	GetOrdersByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error)
+	GetOrdersByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error)
+	GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error)
 	ProcessOrderMessage(ctx context.Context, order *domain.Order) error
 	RestoreCache(ctx context.Context) error
 }
 
+// ErrTenantRequired возвращается при включенной изоляции тенантов, если тенант не определен
+var ErrTenantRequired = errors.New("tenant is required")
+
 type OrderService struct {
 	repo   OrderRepository
 	cache  OrderCache
 	logger *slog.Logger
+
+	tenantIsolation bool
+	restore         RestoreConfig
+	tracker         AccessTracker
+	cacheAvailable  func() bool
+	negative        NegativeCache
+	negativeTTL     time.Duration
+	index           OrderIndex
+	loads           singleflight.Group
 }
 
 func NewOrderService(repo OrderRepository, cache OrderCache, logger *slog.Logger) *OrderService {
@@ -38,32 +84,296 @@ func NewOrderService(repo OrderRepository, cache OrderCache, logger *slog.Logger
 		repo:   repo,
 		cache:  cache,
 		logger: logger,
+
+		restore: DefaultRestoreConfig,
 	}
 }
 
+// SetTenantIsolation включает изоляцию тенантов: ключи кэша содержат entry заказа,
+// а чтение возможно только при наличии тенанта в контексте
+func (s *OrderService) SetTenantIsolation(enabled bool) {
+	s.tenantIsolation = enabled
+}
+
+// SetNegativeCache включает отрицательное кэширование: ненайденные UID запоминаются на ttl
+// и не запрашиваются из базы повторно. Запись удаляется, как только заказ сохраняется.
+func (s *OrderService) SetNegativeCache(cache NegativeCache, ttl time.Duration) {
+	s.negative = cache
+	s.negativeTTL = ttl
+}
+
+// SetOrderIndex включает поиск заказов по покупателю и трек-номеру через индексы кэша
+func (s *OrderService) SetOrderIndex(index OrderIndex) {
+	s.index = index
+}
+
 func (s *OrderService) GetOrderByUID(ctx context.Context, uid string) (*domain.Order, error) {
 	s.logger.Debug("getting order by UID", slog.String("uid", uid))
 
+	tenant, ok := domain.TenantFromContext(ctx)
+	if s.tenantIsolation && !ok {
+		return nil, ErrTenantRequired
+	}
+	key := orderCacheKey(s.tenantIsolation, tenant, uid)
+
 	// Пытаемся получить из кэша
-	if order, found := s.cache.Get(ctx, uid); found {
+	if order, found := s.cache.Get(ctx, key); found {
 		s.logger.Debug("order found in cache", slog.String("uid", uid))
+		s.recordAccess(ctx, uid)
 		return order, nil
 	}
 
+	return s.fetchOrder(ctx, key, uid)
+}
+
+// GetOrderJSON возвращает заказ в JSON. При попадании в кэш данные отдаются
+// как есть, без декодирования и повторной сериализации.
+func (s *OrderService) GetOrderJSON(ctx context.Context, uid string) ([]byte, error) {
+	tenant, ok := domain.TenantFromContext(ctx)
+	if s.tenantIsolation && !ok {
+		return nil, ErrTenantRequired
+	}
+	key := orderCacheKey(s.tenantIsolation, tenant, uid)
+
+	if jsonCache, ok := s.cache.(OrderJSONCache); ok {
+		if data, found := jsonCache.GetJSON(ctx, key); found {
+			s.logger.Debug("order found in cache", slog.String("uid", uid))
+			s.recordAccess(ctx, uid)
+			return data, nil
+		}
+	} else if order, found := s.cache.Get(ctx, key); found {
+		s.logger.Debug("order found in cache", slog.String("uid", uid))
+		s.recordAccess(ctx, uid)
+		return json.Marshal(order)
+	}
+
+	order, err := s.fetchOrder(ctx, key, uid)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(order)
+}
+
+// fetchOrder получает заказ, отсутствующий в кэше, из базы данных
+func (s *OrderService) fetchOrder(ctx context.Context, key, uid string) (*domain.Order, error) {
+	// Заказ недавно не был найден в базе
+	if s.negative != nil && s.negative.IsMissing(ctx, key) {
+		s.logger.Debug("order found in negative cache", slog.String("uid", uid))
+		return nil, fmt.Errorf("failed to get order: %w: uid %s", domain.ErrOrderNotFound, uid)
+	}
+
 	// Если не найден в кэше, получаем из базы данных
 	s.logger.Debug("order not found in cache, fetching from database", slog.String("uid", uid))
+	order, err := s.loadOrder(ctx, key, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	s.recordAccess(ctx, uid)
+
+	return order, nil
+}
+
+// loadOrder загружает заказ из базы и кэширует его. Конкурентные запросы
+// одного ключа объединяются в одно обращение к базе.
+func (s *OrderService) loadOrder(ctx context.Context, key, uid string) (*domain.Order, error) {
+	result := s.loads.DoChan(key, func() (any, error) {
+		// Загрузка общая для всех ожидающих, поэтому не зависит от отмены контекста первого из них
+		loadCtx := context.WithoutCancel(ctx)
+
+		order, err := s.repo.GetByUID(loadCtx, uid)
+		if errors.Is(err, domain.ErrOrderNotFound) && s.negative != nil {
+			order, err = s.rememberMissing(loadCtx, key, uid)
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrOrderNotFound) {
+				// Запросы несуществующих UID (в т.ч. перебор ботами) - не ошибка сервиса
+				s.logger.Debug("order not found in database", slog.String("uid", uid))
+				return nil, err
+			}
+			s.logger.Error("failed to get order from database",
+				slog.String("uid", uid),
+				slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		// Сохраняем в кэш для последующих запросов
+		s.cache.Set(loadCtx, key, order)
+		s.logger.Debug("order cached successfully", slog.String("uid", uid))
+		return order, nil
+	})
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*domain.Order), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// rememberMissing записывает отрицательную запись и проверяет базу еще раз. Заказ мог
+// быть сохранен между первым чтением и записью: ClearMissing потребителя тогда уже
+// выполнен, и запись осталась бы на весь TTL. Если заказ сохраняется после повторного
+// чтения, потребитель удалит запись сам.
+func (s *OrderService) rememberMissing(ctx context.Context, key, uid string) (*domain.Order, error) {
+	s.negative.SetMissing(ctx, key, s.negativeTTL)
+
 	order, err := s.repo.GetByUID(ctx, uid)
 	if err != nil {
-		s.logger.Error("failed to get order from database",
-			slog.String("uid", uid),
+		return nil, err
+	}
+	s.negative.ClearMissing(ctx, key)
+	s.logger.Debug("order stored while it was being loaded", slog.String("uid", uid))
+	return order, nil
+}
+
+// GetOrdersByUIDs возвращает заказы по списку UID: найденные в кэше берутся из него,
+// остальные загружаются из базы одним запросом и кэшируются. Ненайденные UID пропускаются.
+func (s *OrderService) GetOrdersByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error) {
+	tenant, ok := domain.TenantFromContext(ctx)
+	if s.tenantIsolation && !ok {
+		return nil, ErrTenantRequired
+	}
+
+	found := make(map[string]*domain.Order, len(uids))
+	var missing []string
+	for _, uid := range uids {
+		if _, seen := found[uid]; seen {
+			continue
+		}
+		if order, hit := s.cache.Get(ctx, orderCacheKey(s.tenantIsolation, tenant, uid)); hit {
+			found[uid] = order
+			continue
+		}
+		found[uid] = nil
+		missing = append(missing, uid)
+	}
+
+	if len(missing) > 0 {
+		s.logger.Debug("orders not found in cache, fetching from database", slog.Int("count", len(missing)))
+		orders, err := s.repo.GetByUIDs(ctx, missing)
+		if err != nil {
+			s.logger.Error("failed to get orders from database",
+				slog.Int("count", len(missing)),
+				slog.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to get orders: %w", err)
+		}
+		for _, order := range orders {
+			found[order.OrderUID] = order
+			s.cache.Set(ctx, orderCacheKey(s.tenantIsolation, tenant, order.OrderUID), order)
+		}
+	}
+
+	result := make([]*domain.Order, 0, len(found))
+	for _, uid := range uids {
+		if order := found[uid]; order != nil {
+			result = append(result, order)
+			found[uid] = nil
+		}
+	}
+	return result, nil
+}
+
+// GetOrdersByCustomer возвращает заказы покупателя (сначала новые). Если индекс
+// покупателя в кэше полон, база не запрашивается; иначе заказы загружаются из базы
+// и индекс помечается полным.
+func (s *OrderService) GetOrdersByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	tenant, ok := domain.TenantFromContext(ctx)
+	if s.tenantIsolation && !ok {
+		return nil, ErrTenantRequired
+	}
+	indexKey := orderCacheKey(s.tenantIsolation, tenant, customerID)
+
+	if s.index != nil {
+		if uids, found := s.index.CustomerUIDs(ctx, indexKey); found {
+			s.logger.Debug("customer orders found in cache index",
+				slog.String("customer_id", customerID),
+				slog.Int("count", len(uids)))
+			orders, err := s.GetOrdersByUIDs(ctx, uids)
+			if err != nil {
+				return nil, err
+			}
+			return customerOrders(orders, customerID), nil
+		}
+	}
+
+	s.logger.Debug("customer index not found in cache, fetching from database", slog.String("customer_id", customerID))
+	orders, err := s.repo.GetByCustomer(ctx, customerID)
+	if err != nil {
+		s.logger.Error("failed to get customer orders from database",
+			slog.String("customer_id", customerID),
 			slog.String("error", err.Error()))
-		return nil, fmt.Errorf("failed to get order: %w", err)
+		return nil, fmt.Errorf("failed to get customer orders: %w", err)
 	}
 
-	// Сохраняем в кэш для последующих запросов
-	s.cache.Set(ctx, uid, order)
-	s.logger.Debug("order cached successfully", slog.String("uid", uid))
+	uids := make([]string, len(orders))
+	cached := make(map[string]*domain.Order, len(orders))
+	for i, order := range orders {
+		uids[i] = order.OrderUID
+		cached[orderCacheKey(s.tenantIsolation, tenant, order.OrderUID)] = order
+	}
+	if len(cached) > 0 {
+		s.cache.LoadFromDB(ctx, cached)
+	}
+	if s.index != nil {
+		s.index.SetCustomerUIDs(ctx, indexKey, uids)
+	}
+	return orders, nil
+}
 
+// customerOrders отбрасывает заказы, которые индекс покупателя еще относит к нему,
+// хотя в заказе покупатель уже другой, и упорядочивает их как база (сначала новые)
+func customerOrders(orders []*domain.Order, customerID string) []*domain.Order {
+	result := make([]*domain.Order, 0, len(orders))
+	for _, order := range orders {
+		if order.CustomerID == customerID {
+			result = append(result, order)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].OrderUID < result[j].OrderUID
+	})
+	return result
+}
+
+// GetOrderByTrackNumber возвращает заказ по трек-номеру. UID заказа берется из индекса
+// кэша; если индекса нет или он устарел, заказ ищется в базе.
+func (s *OrderService) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error) {
+	tenant, ok := domain.TenantFromContext(ctx)
+	if s.tenantIsolation && !ok {
+		return nil, ErrTenantRequired
+	}
+
+	if s.index != nil {
+		if uid, found := s.index.TrackUID(ctx, orderCacheKey(s.tenantIsolation, tenant, trackNumber)); found {
+			order, err := s.GetOrderByUID(ctx, uid)
+			if err == nil && order.TrackNumber == trackNumber {
+				return order, nil
+			}
+			if err != nil && !errors.Is(err, domain.ErrOrderNotFound) {
+				return nil, err
+			}
+			s.logger.Debug("track number index is stale", slog.String("track_number", trackNumber), slog.String("uid", uid))
+		}
+	}
+
+	order, err := s.repo.GetByTrackNumber(ctx, trackNumber)
+	if err != nil {
+		if !errors.Is(err, domain.ErrOrderNotFound) {
+			s.logger.Error("failed to get order by track number from database",
+				slog.String("track_number", trackNumber),
+				slog.String("error", err.Error()))
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	s.cache.Set(ctx, orderCacheKey(s.tenantIsolation, tenant, order.OrderUID), order)
+	s.recordAccess(ctx, order.OrderUID)
 	return order, nil
 }
 
@@ -76,43 +386,59 @@ func (s *OrderService) ProcessOrderMessage(ctx context.Context, order *domain.Or
 		s.logger.Error("order validation failed",
 			slog.String("order_uid", order.OrderUID),
 			slog.Any("errors", validationResult.Errors))
-		return fmt.Errorf("order validation failed: %w", validationResult.GetFirstError())
+		return fmt.Errorf("order validation failed: %w", domain.Permanent(validationResult.GetFirstError()))
 	}
 
-	// Сохраняем в базу данных
+	// Сохраняем в базу данных. Повторная доставка уже сохраненного заказа (at-least-once,
+	// переигрывание DLQ) считается успешной: сохраненная версия и кэш не меняются.
 	if err := s.repo.Create(ctx, order); err != nil {
+		if errors.Is(err, domain.ErrOrderExists) {
+			return s.checkRedelivery(ctx, order)
+		}
 		s.logger.Error("failed to save order to database",
 			slog.String("order_uid", order.OrderUID),
 			slog.String("error", err.Error()))
 		return fmt.Errorf("failed to save order: %w", err)
 	}
 
-	// Сохраняем в кэш
-	s.cache.Set(ctx, order.OrderUID, order)
+	// Сохраняем в кэш и снимаем отрицательную запись, если заказ запрашивали до его появления
+	key := orderCacheKey(s.tenantIsolation, order.Entry, order.OrderUID)
+	s.cache.Set(ctx, key, order)
+	if s.negative != nil {
+		s.negative.ClearMissing(ctx, key)
+	}
 	s.logger.Info("order processed successfully", slog.String("order_uid", order.OrderUID))
 
 	return nil
 }
 
-func (s *OrderService) RestoreCache(ctx context.Context) error {
-	s.logger.Info("starting cache restoration from database")
-
-	// Получаем все заказы из базы данных
-	orders, err := s.repo.GetAll(ctx)
+// checkRedelivery сравнивает уже сохраненный заказ с повторно доставленным. Совпадающий
+// заказ пропускается, а другой заказ с тем же order_uid - постоянная ошибка: перезаписать
+// сохраненный нельзя, а повторы результата не изменят.
+func (s *OrderService) checkRedelivery(ctx context.Context, order *domain.Order) error {
+	stored, err := s.repo.GetByUID(ctx, order.OrderUID)
 	if err != nil {
-		s.logger.Error("failed to get all orders from database", slog.String("error", err.Error()))
-		return fmt.Errorf("failed to get orders from database: %w", err)
+		s.logger.Error("failed to get stored order to compare with redelivery",
+			slog.String("order_uid", order.OrderUID),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("failed to get stored order: %w", err)
 	}
-
-	// Преобразуем в map для загрузки в кэш
-	orderMap := make(map[string]*domain.Order, len(orders))
-	for _, order := range orders {
-		orderMap[order.OrderUID] = order
+	if !stored.SameContent(order) {
+		s.logger.Warn("order already stored with different content",
+			slog.String("order_uid", order.OrderUID))
+		return fmt.Errorf("failed to save order: %w",
+			domain.Permanent(fmt.Errorf("%w: uid %s", domain.ErrOrderConflict, order.OrderUID)))
 	}
 
-	// Загружаем в кэш
-	s.cache.LoadFromDB(ctx, orderMap)
-
-	s.logger.Info("cache restoration completed", slog.Int("orders_count", len(orders)))
+	s.logger.Info("order already stored, skipping", slog.String("order_uid", order.OrderUID))
 	return nil
 }
+
+// orderCacheKey возвращает ключ кэша заказа.
+// При изоляции тенантов ключ имеет вид "<entry>:<uid>".
+func orderCacheKey(tenantIsolation bool, tenant, uid string) string {
+	if !tenantIsolation {
+		return uid
+	}
+	return tenant + ":" + uid
+}
@@ -6,11 +6,14 @@ import (
 	"errors"
 	"log/slog"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Ravwvil/order-service/backend/internal/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -38,6 +41,33 @@ func (m *MockOrderRepository) GetByUID(ctx context.Context, uid string) (*domain
 	return args.Get(0).(*domain.Order), args.Error(1)
 }
 
+// GetByUIDs мок для метода GetByUIDs.
+func (m *MockOrderRepository) GetByUIDs(ctx context.Context, uids []string) ([]*domain.Order, error) {
+	args := m.Called(ctx, uids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Order), args.Error(1)
+}
+
+// GetByCustomer мок для метода GetByCustomer.
+func (m *MockOrderRepository) GetByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	args := m.Called(ctx, customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Order), args.Error(1)
+}
+
+// GetByTrackNumber мок для метода GetByTrackNumber.
+func (m *MockOrderRepository) GetByTrackNumber(ctx context.Context, trackNumber string) (*domain.Order, error) {
+	args := m.Called(ctx, trackNumber)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Order), args.Error(1)
+}
+
 // GetAll мок для метода GetAll.
 func (m *MockOrderRepository) GetAll(ctx context.Context) ([]*domain.Order, error) {
 	args := m.Called(ctx)
@@ -47,6 +77,19 @@ func (m *MockOrderRepository) GetAll(ctx context.Context) ([]*domain.Order, erro
 	return args.Get(0).([]*domain.Order), args.Error(1)
 }
 
+// ForEachBatch мок для метода ForEachBatch: вызывает fn для каждой пачки из первого возвращаемого значения.
+func (m *MockOrderRepository) ForEachBatch(ctx context.Context, since time.Time, batchSize int, fn func(orders []*domain.Order) error) error {
+	args := m.Called(ctx, since, batchSize)
+	if batches, ok := args.Get(0).([][]*domain.Order); ok {
+		for _, batch := range batches {
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
 // MockOrderCache мок для интерфейса OrderCache.
 type MockOrderCache struct {
 	mock.Mock
@@ -71,6 +114,65 @@ func (m *MockOrderCache) LoadFromDB(ctx context.Context, orders map[string]*doma
 	m.Called(ctx, orders)
 }
 
+// MockOrderJSONCache мок кэша, отдающего заказы в JSON.
+type MockOrderJSONCache struct {
+	MockOrderCache
+}
+
+// GetJSON мок для метода GetJSON.
+func (m *MockOrderJSONCache) GetJSON(ctx context.Context, key string) ([]byte, bool) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1)
+	}
+	return args.Get(0).([]byte), args.Bool(1)
+}
+
+// MockNegativeCache мок для интерфейса NegativeCache.
+type MockNegativeCache struct {
+	mock.Mock
+}
+
+// SetMissing мок для метода SetMissing.
+func (m *MockNegativeCache) SetMissing(ctx context.Context, key string, ttl time.Duration) {
+	m.Called(ctx, key, ttl)
+}
+
+// IsMissing мок для метода IsMissing.
+func (m *MockNegativeCache) IsMissing(ctx context.Context, key string) bool {
+	return m.Called(ctx, key).Bool(0)
+}
+
+// ClearMissing мок для метода ClearMissing.
+func (m *MockNegativeCache) ClearMissing(ctx context.Context, key string) {
+	m.Called(ctx, key)
+}
+
+// MockOrderIndex мок для интерфейса OrderIndex.
+type MockOrderIndex struct {
+	mock.Mock
+}
+
+// CustomerUIDs мок для метода CustomerUIDs.
+func (m *MockOrderIndex) CustomerUIDs(ctx context.Context, key string) ([]string, bool) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1)
+	}
+	return args.Get(0).([]string), args.Bool(1)
+}
+
+// SetCustomerUIDs мок для метода SetCustomerUIDs.
+func (m *MockOrderIndex) SetCustomerUIDs(ctx context.Context, key string, uids []string) {
+	m.Called(ctx, key, uids)
+}
+
+// TrackUID мок для метода TrackUID.
+func (m *MockOrderIndex) TrackUID(ctx context.Context, key string) (string, bool) {
+	args := m.Called(ctx, key)
+	return args.String(0), args.Bool(1)
+}
+
 // Test Helpers
 // loadOrderFromJSON вспомогательная функция для загрузки заказа из JSON-файла.
 func loadOrderFromJSON(t *testing.T, path string) *domain.Order {
@@ -149,6 +251,461 @@ func TestOrderService_GetOrderByUID(t *testing.T) {
 	})
 }
 
+// TestOrderService_GetOrderJSON тестирует получение заказа в JSON.
+func TestOrderService_GetOrderJSON(t *testing.T) {
+	validOrder := loadOrderFromJSON(t, validOrderPath)
+	uid := validOrder.OrderUID
+	expected, err := json.Marshal(validOrder)
+	require.NoError(t, err)
+
+	t.Run("cache hit returns raw bytes", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderJSONCache)
+		service := NewOrderService(repo, cache, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+		raw := []byte(`{"order_uid":"raw"}`)
+		cache.On("GetJSON", mock.Anything, uid).Return(raw, true).Once()
+
+		data, err := service.GetOrderJSON(context.Background(), uid)
+		require.NoError(t, err)
+		assert.Equal(t, raw, data)
+		cache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "GetByUID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("cache miss loads from database", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderJSONCache)
+		service := NewOrderService(repo, cache, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+		cache.On("GetJSON", mock.Anything, uid).Return(nil, false).Once()
+		repo.On("GetByUID", mock.Anything, uid).Return(validOrder, nil).Once()
+		cache.On("Set", mock.Anything, uid, validOrder).Once()
+
+		data, err := service.GetOrderJSON(context.Background(), uid)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(data))
+		repo.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("cache without JSON support", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		cache.On("Get", mock.Anything, uid).Return(validOrder, true).Once()
+
+		data, err := service.GetOrderJSON(context.Background(), uid)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(data))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderJSONCache)
+		service := NewOrderService(repo, cache, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+		cache.On("GetJSON", mock.Anything, uid).Return(nil, false).Once()
+		repo.On("GetByUID", mock.Anything, uid).Return(nil, domain.ErrOrderNotFound).Once()
+
+		_, err := service.GetOrderJSON(context.Background(), uid)
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+	})
+}
+
+// TestOrderService_GetOrderByUID_Coalescing тестирует объединение конкурентных запросов одного заказа.
+func TestOrderService_GetOrderByUID_Coalescing(t *testing.T) {
+	validOrder := loadOrderFromJSON(t, validOrderPath)
+	uid := validOrder.OrderUID
+
+	t.Run("single database query", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		const callers = 10
+		var started sync.WaitGroup
+		started.Add(callers)
+		release := make(chan struct{})
+
+		cache.On("Get", mock.Anything, uid).Run(func(mock.Arguments) { started.Done() }).Return(nil, false).Times(callers)
+		repo.On("GetByUID", mock.Anything, uid).Run(func(mock.Arguments) { <-release }).Return(validOrder, nil).Once()
+		cache.On("Set", mock.Anything, uid, validOrder).Once()
+
+		var wg sync.WaitGroup
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				order, err := service.GetOrderByUID(context.Background(), uid)
+				assert.NoError(t, err)
+				assert.Equal(t, validOrder, order)
+			}()
+		}
+		started.Wait()
+		// Даем всем вызовам дойти до ожидания общей загрузки
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		repo.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("caller cancellation does not abort shared load", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		release := make(chan struct{})
+		cached := make(chan struct{})
+		cache.On("Get", mock.Anything, uid).Return(nil, false)
+		repo.On("GetByUID", mock.Anything, uid).Run(func(mock.Arguments) { <-release }).Return(validOrder, nil).Once()
+		cache.On("Set", mock.Anything, uid, validOrder).Run(func(mock.Arguments) { close(cached) }).Once()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := service.GetOrderByUID(ctx, uid)
+			errCh <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		assert.ErrorIs(t, <-errCh, context.Canceled)
+
+		close(release)
+		<-cached
+		repo.AssertExpectations(t)
+	})
+}
+
+// TestOrderService_NegativeCache тестирует отрицательное кэширование ненайденных заказов.
+func TestOrderService_NegativeCache(t *testing.T) {
+	validOrder := loadOrderFromJSON(t, validOrderPath)
+	uid := validOrder.OrderUID
+	ttl := 30 * time.Second
+
+	t.Run("not found is remembered", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		negative := new(MockNegativeCache)
+		service := newTestService(repo, cache)
+		service.SetNegativeCache(negative, ttl)
+
+		cache.On("Get", mock.Anything, uid).Return(nil, false)
+		negative.On("IsMissing", mock.Anything, uid).Return(false).Once()
+		// Повторное чтение после записи отрицательной записи
+		repo.On("GetByUID", mock.Anything, uid).Return(nil, domain.ErrOrderNotFound).Twice()
+		negative.On("SetMissing", mock.Anything, uid, ttl).Once()
+
+		_, err := service.GetOrderByUID(context.Background(), uid)
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+
+		negative.On("IsMissing", mock.Anything, uid).Return(true).Once()
+		_, err = service.GetOrderByUID(context.Background(), uid)
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+
+		repo.AssertExpectations(t)
+		negative.AssertExpectations(t)
+		cache.AssertNotCalled(t, "Set")
+	})
+
+	t.Run("database errors are not remembered", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		negative := new(MockNegativeCache)
+		service := newTestService(repo, cache)
+		service.SetNegativeCache(negative, ttl)
+
+		cache.On("Get", mock.Anything, uid).Return(nil, false).Once()
+		negative.On("IsMissing", mock.Anything, uid).Return(false).Once()
+		repo.On("GetByUID", mock.Anything, uid).Return(nil, errors.New("connection refused")).Once()
+
+		_, err := service.GetOrderByUID(context.Background(), uid)
+		assert.Error(t, err)
+		negative.AssertNotCalled(t, "SetMissing")
+	})
+
+	t.Run("stored order clears negative entry", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		negative := new(MockNegativeCache)
+		service := newTestService(repo, cache)
+		service.SetNegativeCache(negative, ttl)
+
+		repo.On("Create", mock.Anything, validOrder).Return(nil).Once()
+		cache.On("Set", mock.Anything, uid, validOrder).Once()
+		negative.On("ClearMissing", mock.Anything, uid).Once()
+
+		assert.NoError(t, service.ProcessOrderMessage(context.Background(), validOrder))
+		negative.AssertExpectations(t)
+	})
+
+	t.Run("order stored during load is not remembered as missing", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		negative := newMemoryNegativeCache()
+		service := newTestService(repo, cache)
+		service.SetNegativeCache(negative, ttl)
+
+		cache.On("Get", mock.Anything, uid).Return(nil, false).Once()
+		cache.On("Set", mock.Anything, uid, validOrder)
+		repo.On("Create", mock.Anything, validOrder).Return(nil).Once()
+		// Заказ сохраняется потребителем между чтением из базы и записью отрицательной
+		// записи: ClearMissing потребителя выполняется раньше SetMissing загрузки
+		repo.On("GetByUID", mock.Anything, uid).Return(nil, domain.ErrOrderNotFound).Once().
+			Run(func(mock.Arguments) {
+				assert.NoError(t, service.ProcessOrderMessage(context.Background(), validOrder))
+			})
+		repo.On("GetByUID", mock.Anything, uid).Return(validOrder, nil).Once()
+
+		order, err := service.GetOrderByUID(context.Background(), uid)
+		require.NoError(t, err)
+		assert.Equal(t, uid, order.OrderUID)
+		assert.False(t, negative.IsMissing(context.Background(), uid))
+		repo.AssertExpectations(t)
+	})
+}
+
+// memoryNegativeCache отрицательный кэш в памяти, сохраняющий порядок операций
+type memoryNegativeCache struct {
+	mu      sync.Mutex
+	missing map[string]bool
+}
+
+func newMemoryNegativeCache() *memoryNegativeCache {
+	return &memoryNegativeCache{missing: make(map[string]bool)}
+}
+
+func (c *memoryNegativeCache) SetMissing(_ context.Context, key string, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.missing[key] = true
+}
+
+func (c *memoryNegativeCache) IsMissing(_ context.Context, key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.missing[key]
+}
+
+func (c *memoryNegativeCache) ClearMissing(_ context.Context, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.missing, key)
+}
+
+// TestOrderService_GetOrdersByUIDs тестирует пакетное получение заказов.
+func TestOrderService_GetOrdersByUIDs(t *testing.T) {
+	cached := &domain.Order{OrderUID: "cached-uid"}
+	stored := &domain.Order{OrderUID: "stored-uid"}
+
+	t.Run("cache hits and single batch query for misses", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		cache.On("Get", mock.Anything, "stored-uid").Return(nil, false).Once()
+		cache.On("Get", mock.Anything, "cached-uid").Return(cached, true).Once()
+		cache.On("Get", mock.Anything, "missing-uid").Return(nil, false).Once()
+		repo.On("GetByUIDs", mock.Anything, []string{"stored-uid", "missing-uid"}).Return([]*domain.Order{stored}, nil).Once()
+		cache.On("Set", mock.Anything, "stored-uid", stored).Once()
+
+		orders, err := service.GetOrdersByUIDs(context.Background(), []string{"stored-uid", "cached-uid", "missing-uid", "cached-uid"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*domain.Order{stored, cached}, orders)
+		cache.AssertExpectations(t)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("all cached", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		cache.On("Get", mock.Anything, "cached-uid").Return(cached, true).Once()
+
+		orders, err := service.GetOrdersByUIDs(context.Background(), []string{"cached-uid"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*domain.Order{cached}, orders)
+		repo.AssertNotCalled(t, "GetByUIDs")
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		dbErr := errors.New("db error")
+		cache.On("Get", mock.Anything, "stored-uid").Return(nil, false).Once()
+		repo.On("GetByUIDs", mock.Anything, []string{"stored-uid"}).Return(nil, dbErr).Once()
+
+		_, err := service.GetOrdersByUIDs(context.Background(), []string{"stored-uid"})
+
+		assert.ErrorIs(t, err, dbErr)
+		cache.AssertNotCalled(t, "Set")
+	})
+}
+
+// TestOrderService_GetOrdersByCustomer тестирует метод GetOrdersByCustomer.
+func TestOrderService_GetOrdersByCustomer(t *testing.T) {
+	now := time.Now()
+	older := &domain.Order{OrderUID: "older-uid", CustomerID: "customer", CreatedAt: now.Add(-time.Hour)}
+	newer := &domain.Order{OrderUID: "newer-uid", CustomerID: "customer", CreatedAt: now}
+	moved := &domain.Order{OrderUID: "moved-uid", CustomerID: "another", CreatedAt: now}
+
+	t.Run("complete index", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		index := new(MockOrderIndex)
+		service := newTestService(repo, cache)
+		service.SetOrderIndex(index)
+
+		index.On("CustomerUIDs", mock.Anything, "customer").Return([]string{"older-uid", "moved-uid", "newer-uid"}, true).Once()
+		cache.On("Get", mock.Anything, "older-uid").Return(older, true).Once()
+		cache.On("Get", mock.Anything, "moved-uid").Return(moved, true).Once()
+		cache.On("Get", mock.Anything, "newer-uid").Return(nil, false).Once()
+		repo.On("GetByUIDs", mock.Anything, []string{"newer-uid"}).Return([]*domain.Order{newer}, nil).Once()
+		cache.On("Set", mock.Anything, "newer-uid", newer).Once()
+
+		orders, err := service.GetOrdersByCustomer(context.Background(), "customer")
+
+		require.NoError(t, err)
+		assert.Equal(t, []*domain.Order{newer, older}, orders)
+		repo.AssertNotCalled(t, "GetByCustomer", mock.Anything, mock.Anything)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("missing index is rebuilt from database", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		index := new(MockOrderIndex)
+		service := newTestService(repo, cache)
+		service.SetOrderIndex(index)
+
+		index.On("CustomerUIDs", mock.Anything, "customer").Return(nil, false).Once()
+		repo.On("GetByCustomer", mock.Anything, "customer").Return([]*domain.Order{newer, older}, nil).Once()
+		cache.On("LoadFromDB", mock.Anything, map[string]*domain.Order{"newer-uid": newer, "older-uid": older}).Once()
+		index.On("SetCustomerUIDs", mock.Anything, "customer", []string{"newer-uid", "older-uid"}).Once()
+
+		orders, err := service.GetOrdersByCustomer(context.Background(), "customer")
+
+		require.NoError(t, err)
+		assert.Equal(t, []*domain.Order{newer, older}, orders)
+		cache.AssertExpectations(t)
+		index.AssertExpectations(t)
+	})
+
+	t.Run("customer without orders", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		index := new(MockOrderIndex)
+		service := newTestService(repo, cache)
+		service.SetOrderIndex(index)
+
+		index.On("CustomerUIDs", mock.Anything, "customer").Return(nil, false).Once()
+		repo.On("GetByCustomer", mock.Anything, "customer").Return([]*domain.Order{}, nil).Once()
+		index.On("SetCustomerUIDs", mock.Anything, "customer", []string{}).Once()
+
+		orders, err := service.GetOrdersByCustomer(context.Background(), "customer")
+
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+		cache.AssertNotCalled(t, "LoadFromDB", mock.Anything, mock.Anything)
+		index.AssertExpectations(t)
+	})
+
+	t.Run("tenant scoped index key", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		index := new(MockOrderIndex)
+		service := newTestService(repo, cache)
+		service.SetOrderIndex(index)
+		service.SetTenantIsolation(true)
+
+		_, err := service.GetOrdersByCustomer(context.Background(), "customer")
+		assert.ErrorIs(t, err, ErrTenantRequired)
+
+		index.On("CustomerUIDs", mock.Anything, "WBIL:customer").Return([]string{}, true).Once()
+		orders, err := service.GetOrdersByCustomer(domain.WithTenant(context.Background(), "WBIL"), "customer")
+
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+		index.AssertExpectations(t)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		dbErr := errors.New("db error")
+		repo.On("GetByCustomer", mock.Anything, "customer").Return(nil, dbErr).Once()
+
+		_, err := service.GetOrdersByCustomer(context.Background(), "customer")
+
+		assert.ErrorIs(t, err, dbErr)
+	})
+}
+
+// TestOrderService_GetOrderByTrackNumber тестирует метод GetOrderByTrackNumber.
+func TestOrderService_GetOrderByTrackNumber(t *testing.T) {
+	order := &domain.Order{OrderUID: "order-uid", TrackNumber: "TRACK"}
+
+	t.Run("found through index", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		index := new(MockOrderIndex)
+		service := newTestService(repo, cache)
+		service.SetOrderIndex(index)
+
+		index.On("TrackUID", mock.Anything, "TRACK").Return("order-uid", true).Once()
+		cache.On("Get", mock.Anything, "order-uid").Return(order, true).Once()
+
+		found, err := service.GetOrderByTrackNumber(context.Background(), "TRACK")
+
+		require.NoError(t, err)
+		assert.Equal(t, order, found)
+		repo.AssertNotCalled(t, "GetByTrackNumber", mock.Anything, mock.Anything)
+	})
+
+	t.Run("stale index falls back to database", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		index := new(MockOrderIndex)
+		service := newTestService(repo, cache)
+		service.SetOrderIndex(index)
+
+		changed := &domain.Order{OrderUID: "old-uid", TrackNumber: "CHANGED"}
+		index.On("TrackUID", mock.Anything, "TRACK").Return("old-uid", true).Once()
+		cache.On("Get", mock.Anything, "old-uid").Return(changed, true).Once()
+		repo.On("GetByTrackNumber", mock.Anything, "TRACK").Return(order, nil).Once()
+		cache.On("Set", mock.Anything, "order-uid", order).Once()
+
+		found, err := service.GetOrderByTrackNumber(context.Background(), "TRACK")
+
+		require.NoError(t, err)
+		assert.Equal(t, order, found)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		repo.On("GetByTrackNumber", mock.Anything, "TRACK").Return(nil, domain.ErrOrderNotFound).Once()
+
+		_, err := service.GetOrderByTrackNumber(context.Background(), "TRACK")
+
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+		cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
 // TestOrderService_ProcessOrderMessage тестирует метод ProcessOrderMessage.
 func TestOrderService_ProcessOrderMessage(t *testing.T) {
 	validOrder := loadOrderFromJSON(t, validOrderPath)
@@ -178,10 +735,62 @@ func TestOrderService_ProcessOrderMessage(t *testing.T) {
 		err := service.ProcessOrderMessage(context.Background(), invalidOrder)
 
 		assert.Error(t, err)
+		assert.Equal(t, domain.ErrorClassPermanent, domain.ClassifyError(err))
 		repo.AssertNotCalled(t, "Create")
 		cache.AssertNotCalled(t, "Set")
 	})
 
+	t.Run("redelivered order is skipped", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		stored := loadOrderFromJSON(t, validOrderPath)
+		stored.CreatedAt = time.Now()
+		repo.On("Create", mock.Anything, validOrder).Return(domain.ErrOrderExists).Once()
+		repo.On("GetByUID", mock.Anything, validOrder.OrderUID).Return(stored, nil).Once()
+
+		err := service.ProcessOrderMessage(context.Background(), validOrder)
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+		cache.AssertNotCalled(t, "Set")
+	})
+
+	t.Run("redelivered order with different content goes to DLQ", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		stored := loadOrderFromJSON(t, validOrderPath)
+		stored.Payment.Amount++
+		repo.On("Create", mock.Anything, validOrder).Return(domain.ErrOrderExists).Once()
+		repo.On("GetByUID", mock.Anything, validOrder.OrderUID).Return(stored, nil).Once()
+
+		err := service.ProcessOrderMessage(context.Background(), validOrder)
+
+		assert.ErrorIs(t, err, domain.ErrOrderConflict)
+		assert.Equal(t, domain.ErrorClassPermanent, domain.ClassifyError(err))
+		repo.AssertExpectations(t)
+		cache.AssertNotCalled(t, "Set")
+	})
+
+	t.Run("redelivered order compare failed is retried", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		repoErr := errors.New("db error")
+
+		repo.On("Create", mock.Anything, validOrder).Return(domain.ErrOrderExists).Once()
+		repo.On("GetByUID", mock.Anything, validOrder.OrderUID).Return(nil, repoErr).Once()
+
+		err := service.ProcessOrderMessage(context.Background(), validOrder)
+
+		assert.ErrorIs(t, err, repoErr)
+		assert.Equal(t, domain.ErrorClassRetryable, domain.ClassifyError(err))
+		repo.AssertExpectations(t)
+	})
+
 	t.Run("repo create failed", func(t *testing.T) {
 		repo := new(MockOrderRepository)
 		cache := new(MockOrderCache)
@@ -194,24 +803,40 @@ func TestOrderService_ProcessOrderMessage(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, repoErr)
+		assert.Equal(t, domain.ErrorClassRetryable, domain.ClassifyError(err))
 		repo.AssertExpectations(t)
 		cache.AssertNotCalled(t, "Set")
 	})
+
+	t.Run("repo constraint violation stays permanent", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		repo.On("Create", mock.Anything, validOrder).Return(domain.Permanent(errors.New("duplicate key"))).Once()
+
+		err := service.ProcessOrderMessage(context.Background(), validOrder)
+
+		assert.Equal(t, domain.ErrorClassPermanent, domain.ClassifyError(err))
+		repo.AssertExpectations(t)
+	})
 }
 
 // TestOrderService_RestoreCache тестирует метод RestoreCache.
 func TestOrderService_RestoreCache(t *testing.T) {
 	validOrder := loadOrderFromJSON(t, validOrderPath)
-	orders := []*domain.Order{validOrder}
-	orderMap := map[string]*domain.Order{validOrder.OrderUID: validOrder}
+	otherOrder := &domain.Order{OrderUID: "other-uid"}
+	batches := [][]*domain.Order{{validOrder}, {otherOrder}}
 
 	t.Run("success", func(t *testing.T) {
 		repo := new(MockOrderRepository)
 		cache := new(MockOrderCache)
 		service := newTestService(repo, cache)
+		service.SetRestoreConfig(RestoreConfig{BatchSize: 1, Concurrency: 2})
 
-		repo.On("GetAll", mock.Anything).Return(orders, nil).Once()
-		cache.On("LoadFromDB", mock.Anything, orderMap).Once()
+		repo.On("ForEachBatch", mock.Anything, time.Time{}, 1).Return(batches, nil).Once()
+		cache.On("LoadFromDB", mock.Anything, map[string]*domain.Order{validOrder.OrderUID: validOrder}).Once()
+		cache.On("LoadFromDB", mock.Anything, map[string]*domain.Order{otherOrder.OrderUID: otherOrder}).Once()
 
 		err := service.RestoreCache(context.Background())
 
@@ -220,13 +845,28 @@ func TestOrderService_RestoreCache(t *testing.T) {
 		cache.AssertExpectations(t)
 	})
 
-	t.Run("get all from repo failed", func(t *testing.T) {
+	t.Run("default config", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetRestoreConfig(RestoreConfig{})
+
+		repo.On("ForEachBatch", mock.Anything, time.Time{}, DefaultRestoreConfig.BatchSize).Return(nil, nil).Once()
+
+		err := service.RestoreCache(context.Background())
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+		cache.AssertNotCalled(t, "LoadFromDB")
+	})
+
+	t.Run("repo failed", func(t *testing.T) {
 		repo := new(MockOrderRepository)
 		cache := new(MockOrderCache)
 		service := newTestService(repo, cache)
 		repoErr := errors.New("db error")
 
-		repo.On("GetAll", mock.Anything).Return(nil, repoErr).Once()
+		repo.On("ForEachBatch", mock.Anything, mock.Anything, mock.Anything).Return(nil, repoErr).Once()
 
 		err := service.RestoreCache(context.Background())
 
@@ -235,4 +875,93 @@ func TestOrderService_RestoreCache(t *testing.T) {
 		repo.AssertExpectations(t)
 		cache.AssertNotCalled(t, "LoadFromDB")
 	})
-} 
\ No newline at end of file
+
+	t.Run("cancelled", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		repo.On("ForEachBatch", mock.Anything, mock.Anything, mock.Anything).Return(batches, nil).Once()
+
+		err := service.RestoreCache(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		cache.AssertNotCalled(t, "LoadFromDB")
+	})
+}
+
+// TestOrderService_TenantIsolation тестирует ключи кэша при изоляции тенантов.
+func TestOrderService_TenantIsolation(t *testing.T) {
+	validOrder := loadOrderFromJSON(t, validOrderPath)
+	uid := validOrder.OrderUID
+	tenantKey := validOrder.Entry + ":" + uid
+
+	t.Run("tenant required", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetTenantIsolation(true)
+
+		_, err := service.GetOrderByUID(context.Background(), uid)
+
+		assert.ErrorIs(t, err, ErrTenantRequired)
+		cache.AssertNotCalled(t, "Get")
+		repo.AssertNotCalled(t, "GetByUID")
+	})
+
+	t.Run("get uses tenant prefixed key", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetTenantIsolation(true)
+		ctx := domain.WithTenant(context.Background(), validOrder.Entry)
+
+		// Загрузка из базы выполняется в контексте без отмены, но с тенантом запроса
+		tenantCtx := mock.MatchedBy(func(c context.Context) bool {
+			tenant, ok := domain.TenantFromContext(c)
+			return ok && tenant == validOrder.Entry
+		})
+		cache.On("Get", ctx, tenantKey).Return(nil, false).Once()
+		repo.On("GetByUID", tenantCtx, uid).Return(validOrder, nil).Once()
+		cache.On("Set", tenantCtx, tenantKey, validOrder).Once()
+
+		order, err := service.GetOrderByUID(ctx, uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, validOrder, order)
+		cache.AssertExpectations(t)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("process uses order entry", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetTenantIsolation(true)
+
+		repo.On("Create", mock.Anything, validOrder).Return(nil).Once()
+		cache.On("Set", mock.Anything, tenantKey, validOrder).Once()
+
+		err := service.ProcessOrderMessage(context.Background(), validOrder)
+
+		assert.NoError(t, err)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("restore uses order entry", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetTenantIsolation(true)
+
+		repo.On("ForEachBatch", mock.Anything, mock.Anything, mock.Anything).Return([][]*domain.Order{{validOrder}}, nil).Once()
+		cache.On("LoadFromDB", mock.Anything, map[string]*domain.Order{tenantKey: validOrder}).Once()
+
+		err := service.RestoreCache(context.Background())
+
+		assert.NoError(t, err)
+		cache.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// RetentionRepository операции хранилища, необходимые для очистки просроченных заказов
+type RetentionRepository interface {
+	FindExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time, limit int) ([]domain.OrderRef, error)
+	CountExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time) ([]domain.RetentionStat, error)
+	DeleteByUIDs(ctx context.Context, uids []string) (int64, error)
+	AnonymizeByUIDs(ctx context.Context, uids []string) (int64, error)
+}
+
+// CacheEvicter удаляет заказы из кэша
+type CacheEvicter interface {
+	Delete(ctx context.Context, keys ...string)
+}
+
+// RetentionConfig настройки задачи очистки
+type RetentionConfig struct {
+	Policy    domain.RetentionPolicy
+	BatchSize int
+	Interval  time.Duration
+	DryRun    bool
+
+	// TenantIsolation должен совпадать с настройкой OrderService, чтобы ключи кэша совпадали
+	TenantIsolation bool
+}
+
+// RetentionReport результат одного прогона очистки
+type RetentionReport struct {
+	Mode      domain.RetentionMode
+	DryRun    bool
+	Stats     []domain.RetentionStat
+	Processed int64
+	Batches   int
+	Duration  time.Duration
+}
+
+// RetentionService периодически удаляет или обезличивает заказы с истекшим сроком хранения
+type RetentionService struct {
+	repo   RetentionRepository
+	cache  CacheEvicter
+	cfg    RetentionConfig
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func NewRetentionService(repo RetentionRepository, cache CacheEvicter, cfg RetentionConfig, logger *slog.Logger) *RetentionService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+
+	return &RetentionService{
+		repo:   repo,
+		cache:  cache,
+		cfg:    cfg,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Run запускает очистку сразу и далее с заданным интервалом до отмены контекста
+func (s *RetentionService) Run(ctx context.Context) {
+	s.logger.Info("starting retention job",
+		slog.String("mode", string(s.cfg.Policy.Mode)),
+		slog.Any("rules", s.rulesForLog()),
+		slog.Int("batch_size", s.cfg.BatchSize),
+		slog.Duration("interval", s.cfg.Interval),
+		slog.Bool("dry_run", s.cfg.DryRun))
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Purge(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("retention purge failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("retention job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge выполняет один прогон очистки пачками.
+// В режиме dry-run только подсчитывает заказы, подлежащие очистке.
+func (s *RetentionService) Purge(ctx context.Context) (*RetentionReport, error) {
+	start := time.Now()
+	now := s.now()
+	report := &RetentionReport{
+		Mode:   s.cfg.Policy.Mode,
+		DryRun: s.cfg.DryRun,
+	}
+
+	if s.cfg.DryRun {
+		stats, err := s.repo.CountExpired(ctx, s.cfg.Policy, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count expired orders: %w", err)
+		}
+		report.Stats = stats
+
+		for _, stat := range stats {
+			report.Processed += stat.Count
+			s.logger.Info("retention dry-run: orders eligible for purge",
+				slog.String("rule", stat.Rule.String()),
+				slog.String("mode", string(report.Mode)),
+				slog.Int64("count", stat.Count))
+		}
+		report.Duration = time.Since(start)
+
+		s.logger.Info("retention dry-run completed",
+			slog.Int64("total", report.Processed),
+			slog.Duration("duration", report.Duration))
+		return report, nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		refs, err := s.repo.FindExpired(ctx, s.cfg.Policy, now, s.cfg.BatchSize)
+		if err != nil {
+			return report, fmt.Errorf("failed to find expired orders: %w", err)
+		}
+		if len(refs) == 0 {
+			break
+		}
+
+		uids := make([]string, len(refs))
+		keys := make([]string, len(refs))
+		for i, ref := range refs {
+			uids[i] = ref.OrderUID
+			keys[i] = orderCacheKey(s.cfg.TenantIsolation, ref.Entry, ref.OrderUID)
+		}
+
+		affected, err := s.purgeBatch(ctx, uids)
+		if err != nil {
+			return report, err
+		}
+
+		// Удаляем из кэша в любом случае, чтобы не отдавать устаревшие персональные данные
+		s.cache.Delete(ctx, keys...)
+
+		report.Batches++
+		report.Processed += affected
+		s.logger.Info("retention batch processed",
+			slog.Int("batch", report.Batches),
+			slog.Int("batch_size", len(uids)),
+			slog.Int64("affected", affected),
+			slog.Int64("processed_total", report.Processed))
+
+		// Защита от зацикливания, если пачка ничего не изменила
+		if len(uids) < s.cfg.BatchSize || affected == 0 {
+			break
+		}
+	}
+
+	report.Duration = time.Since(start)
+	s.logger.Info("retention purge completed",
+		slog.String("mode", string(report.Mode)),
+		slog.Int64("processed", report.Processed),
+		slog.Int("batches", report.Batches),
+		slog.Duration("duration", report.Duration))
+
+	return report, nil
+}
+
+func (s *RetentionService) purgeBatch(ctx context.Context, uids []string) (int64, error) {
+	switch s.cfg.Policy.Mode {
+	case domain.RetentionModeAnonymize:
+		affected, err := s.repo.AnonymizeByUIDs(ctx, uids)
+		if err != nil {
+			return 0, fmt.Errorf("failed to anonymize orders: %w", err)
+		}
+		return affected, nil
+	case domain.RetentionModeDelete:
+		affected, err := s.repo.DeleteByUIDs(ctx, uids)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete orders: %w", err)
+		}
+		return affected, nil
+	default:
+		return 0, fmt.Errorf("unknown retention mode %q", s.cfg.Policy.Mode)
+	}
+}
+
+func (s *RetentionService) rulesForLog() []string {
+	rules := make([]string, len(s.cfg.Policy.Rules))
+	for i, rule := range s.cfg.Policy.Rules {
+		rules[i] = rule.String()
+	}
+	return rules
+}
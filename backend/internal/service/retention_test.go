@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRetentionRepository мок для интерфейса RetentionRepository.
+type MockRetentionRepository struct {
+	mock.Mock
+}
+
+func (m *MockRetentionRepository) FindExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time, limit int) ([]domain.OrderRef, error) {
+	args := m.Called(ctx, policy, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.OrderRef), args.Error(1)
+}
+
+func (m *MockRetentionRepository) CountExpired(ctx context.Context, policy domain.RetentionPolicy, now time.Time) ([]domain.RetentionStat, error) {
+	args := m.Called(ctx, policy, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.RetentionStat), args.Error(1)
+}
+
+func (m *MockRetentionRepository) DeleteByUIDs(ctx context.Context, uids []string) (int64, error) {
+	args := m.Called(ctx, uids)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRetentionRepository) AnonymizeByUIDs(ctx context.Context, uids []string) (int64, error) {
+	args := m.Called(ctx, uids)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockCacheEvicter мок для интерфейса CacheEvicter.
+type MockCacheEvicter struct {
+	mock.Mock
+}
+
+func (m *MockCacheEvicter) Delete(ctx context.Context, keys ...string) {
+	m.Called(ctx, keys)
+}
+
+// refs создает ссылки на заказы с entry WBIL.
+func refs(uids ...string) []domain.OrderRef {
+	result := make([]domain.OrderRef, len(uids))
+	for i, uid := range uids {
+		result[i] = domain.OrderRef{OrderUID: uid, Entry: "WBIL"}
+	}
+	return result
+}
+
+// newTestRetentionService создает RetentionService с фиксированным временем.
+func newTestRetentionService(repo *MockRetentionRepository, cache *MockCacheEvicter, cfg RetentionConfig, now time.Time) *RetentionService {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	s := NewRetentionService(repo, cache, cfg, logger)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+// TestRetentionService_Purge тестирует метод Purge.
+func TestRetentionService_Purge(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := domain.RetentionPolicy{
+		Mode:  domain.RetentionModeDelete,
+		Rules: []domain.RetentionRule{{Days: 30}},
+	}
+
+	t.Run("delete in batches", func(t *testing.T) {
+		repo := new(MockRetentionRepository)
+		cache := new(MockCacheEvicter)
+		s := newTestRetentionService(repo, cache, RetentionConfig{Policy: policy, BatchSize: 2}, now)
+
+		repo.On("FindExpired", mock.Anything, policy, now, 2).Return(refs("a", "b"), nil).Once()
+		repo.On("DeleteByUIDs", mock.Anything, []string{"a", "b"}).Return(int64(2), nil).Once()
+		cache.On("Delete", mock.Anything, []string{"a", "b"}).Once()
+		repo.On("FindExpired", mock.Anything, policy, now, 2).Return(refs("c"), nil).Once()
+		repo.On("DeleteByUIDs", mock.Anything, []string{"c"}).Return(int64(1), nil).Once()
+		cache.On("Delete", mock.Anything, []string{"c"}).Once()
+
+		report, err := s.Purge(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), report.Processed)
+		assert.Equal(t, 2, report.Batches)
+		repo.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("anonymize", func(t *testing.T) {
+		repo := new(MockRetentionRepository)
+		cache := new(MockCacheEvicter)
+		anonPolicy := domain.RetentionPolicy{Mode: domain.RetentionModeAnonymize, Rules: policy.Rules}
+		s := newTestRetentionService(repo, cache, RetentionConfig{Policy: anonPolicy, BatchSize: 10}, now)
+
+		repo.On("FindExpired", mock.Anything, anonPolicy, now, 10).Return(refs("a"), nil).Once()
+		repo.On("AnonymizeByUIDs", mock.Anything, []string{"a"}).Return(int64(1), nil).Once()
+		cache.On("Delete", mock.Anything, []string{"a"}).Once()
+
+		report, err := s.Purge(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), report.Processed)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "DeleteByUIDs")
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		repo := new(MockRetentionRepository)
+		cache := new(MockCacheEvicter)
+		s := newTestRetentionService(repo, cache, RetentionConfig{Policy: policy, DryRun: true}, now)
+
+		stats := []domain.RetentionStat{{Rule: policy.Rules[0], Count: 42}}
+		repo.On("CountExpired", mock.Anything, policy, now).Return(stats, nil).Once()
+
+		report, err := s.Purge(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, int64(42), report.Processed)
+		assert.Equal(t, stats, report.Stats)
+		repo.AssertNotCalled(t, "FindExpired")
+		repo.AssertNotCalled(t, "DeleteByUIDs")
+		cache.AssertNotCalled(t, "Delete")
+	})
+
+	t.Run("delete failed", func(t *testing.T) {
+		repo := new(MockRetentionRepository)
+		cache := new(MockCacheEvicter)
+		s := newTestRetentionService(repo, cache, RetentionConfig{Policy: policy, BatchSize: 10}, now)
+		dbErr := errors.New("db error")
+
+		repo.On("FindExpired", mock.Anything, policy, now, 10).Return(refs("a"), nil).Once()
+		repo.On("DeleteByUIDs", mock.Anything, []string{"a"}).Return(int64(0), dbErr).Once()
+
+		_, err := s.Purge(context.Background())
+
+		assert.ErrorIs(t, err, dbErr)
+		cache.AssertNotCalled(t, "Delete")
+	})
+
+	t.Run("tenant cache keys", func(t *testing.T) {
+		repo := new(MockRetentionRepository)
+		cache := new(MockCacheEvicter)
+		s := newTestRetentionService(repo, cache, RetentionConfig{Policy: policy, BatchSize: 10, TenantIsolation: true}, now)
+
+		repo.On("FindExpired", mock.Anything, policy, now, 10).Return(refs("a"), nil).Once()
+		repo.On("DeleteByUIDs", mock.Anything, []string{"a"}).Return(int64(1), nil).Once()
+		cache.On("Delete", mock.Anything, []string{"WBIL:a"}).Once()
+
+		_, err := s.Purge(context.Background())
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+}
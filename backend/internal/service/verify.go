@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// CacheScanner перебирает и читает заказы общего кэша (redis.Cache)
+type CacheScanner interface {
+	ScanKeys(ctx context.Context, fn func(keys []string) error) error
+	Entries(ctx context.Context, keys []string) (map[string]*domain.Order, error)
+}
+
+// CacheRepairer исправляет записи кэша. Это должен быть кэш, через который
+// работает OrderService, чтобы исправление затрагивало и локальные копии реплик.
+type CacheRepairer interface {
+	Set(ctx context.Context, key string, order *domain.Order)
+	Delete(ctx context.Context, keys ...string)
+}
+
+// VerifyConfig настройки проверки согласованности кэша и базы
+type VerifyConfig struct {
+	Sample   float64 // доля проверяемых заказов от 0 до 1, 1 - все
+	Limit    int     // максимум проверяемых заказов за прогон, 0 - без ограничения
+	Interval time.Duration
+	Repair   bool
+
+	// TenantIsolation должен совпадать с настройкой OrderService, чтобы ключи кэша совпадали
+	TenantIsolation bool
+}
+
+// VerifyReport результат одного прогона проверки
+type VerifyReport struct {
+	Checked  int64         `json:"checked"`
+	Stale    int64         `json:"stale"`    // запись отличается от заказа в базе
+	Orphaned int64         `json:"orphaned"` // заказа нет в базе
+	Corrupt  int64         `json:"corrupt"`  // запись не декодируется
+	Repaired int64         `json:"repaired"`
+	Errors   int64         `json:"errors"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Mismatches возвращает число найденных расхождений
+func (r *VerifyReport) Mismatches() int64 {
+	return r.Stale + r.Orphaned + r.Corrupt
+}
+
+// verifyMetrics счетчики расхождений и статистика последнего прогона, доступны через /admin/debug/vars
+var verifyMetrics = expvar.NewMap("cache_verify")
+
+// errVerifyLimit прерывает перебор ключей по достижении VerifyConfig.Limit
+var errVerifyLimit = errors.New("verify limit reached")
+
+// CacheVerifier сравнивает закэшированные заказы с базой
+type CacheVerifier struct {
+	repo   OrderRepository
+	cache  CacheScanner
+	repair CacheRepairer
+	cfg    VerifyConfig
+	logger *slog.Logger
+	sample func() float64
+}
+
+func NewCacheVerifier(repo OrderRepository, cache CacheScanner, repair CacheRepairer, cfg VerifyConfig, logger *slog.Logger) *CacheVerifier {
+	if cfg.Sample <= 0 || cfg.Sample > 1 {
+		cfg.Sample = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+
+	return &CacheVerifier{
+		repo:   repo,
+		cache:  cache,
+		repair: repair,
+		cfg:    cfg,
+		logger: logger,
+		sample: rand.Float64,
+	}
+}
+
+// Run запускает проверку с заданным интервалом до отмены контекста
+func (v *CacheVerifier) Run(ctx context.Context) {
+	v.logger.Info("starting cache verification job",
+		slog.Float64("sample", v.cfg.Sample),
+		slog.Int("limit", v.cfg.Limit),
+		slog.Duration("interval", v.cfg.Interval),
+		slog.Bool("repair", v.cfg.Repair))
+
+	ticker := time.NewTicker(v.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			v.logger.Info("cache verification job stopped")
+			return
+		case <-ticker.C:
+			if _, err := v.Verify(ctx); err != nil && ctx.Err() == nil {
+				v.logger.Error("cache verification failed", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// Verify выполняет один прогон: перебирает ключи кэша, выбирает из них долю Sample
+// (не более Limit) и сравнивает записи с заказами в базе
+func (v *CacheVerifier) Verify(ctx context.Context) (*VerifyReport, error) {
+	start := time.Now()
+	report := &VerifyReport{}
+
+	err := v.cache.ScanKeys(ctx, func(keys []string) error {
+		sampled := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if v.cfg.Limit > 0 && report.Checked+int64(len(sampled)) >= int64(v.cfg.Limit) {
+				break
+			}
+			if v.cfg.Sample >= 1 || v.sample() < v.cfg.Sample {
+				sampled = append(sampled, key)
+			}
+		}
+		if len(sampled) == 0 {
+			return nil
+		}
+
+		entries, err := v.cache.Entries(ctx, sampled)
+		if err != nil {
+			return err
+		}
+		for key, cached := range entries {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			v.verifyEntry(ctx, key, cached, report)
+		}
+
+		if v.cfg.Limit > 0 && report.Checked >= int64(v.cfg.Limit) {
+			return errVerifyLimit
+		}
+		return nil
+	})
+	report.Duration = time.Since(start)
+	publishVerifyMetrics(report, err)
+
+	if err != nil && !errors.Is(err, errVerifyLimit) {
+		return report, fmt.Errorf("failed to verify cache: %w", err)
+	}
+
+	v.logger.Info("cache verification completed",
+		slog.Int64("checked", report.Checked),
+		slog.Int64("stale", report.Stale),
+		slog.Int64("orphaned", report.Orphaned),
+		slog.Int64("corrupt", report.Corrupt),
+		slog.Int64("repaired", report.Repaired),
+		slog.Int64("errors", report.Errors),
+		slog.Duration("duration", report.Duration))
+	return report, nil
+}
+
+func (v *CacheVerifier) verifyEntry(ctx context.Context, key string, cached *domain.Order, report *VerifyReport) {
+	report.Checked++
+
+	if cached == nil {
+		report.Corrupt++
+		v.logger.Warn("cache entry cannot be decoded", slog.String("key", key))
+		v.evict(ctx, key, report)
+		return
+	}
+
+	tenant, uid := v.splitKey(key)
+	if tenant != "" {
+		ctx = domain.WithTenant(ctx, tenant)
+	}
+
+	order, err := v.repo.GetByUID(ctx, uid)
+	if errors.Is(err, domain.ErrOrderNotFound) {
+		report.Orphaned++
+		v.logger.Warn("cached order is missing in database", slog.String("key", key))
+		v.evict(ctx, key, report)
+		return
+	}
+	if err != nil {
+		report.Errors++
+		v.logger.Error("failed to load order for cache verification", slog.String("key", key), slog.Any("error", err))
+		return
+	}
+
+	if !sameOrder(cached, order) {
+		report.Stale++
+		v.logger.Warn("cached order differs from database", slog.String("key", key))
+		if v.cfg.Repair {
+			v.repair.Set(ctx, key, order)
+			report.Repaired++
+		}
+	}
+}
+
+func (v *CacheVerifier) evict(ctx context.Context, key string, report *VerifyReport) {
+	if v.cfg.Repair {
+		v.repair.Delete(ctx, key)
+		report.Repaired++
+	}
+}
+
+// splitKey разбирает ключ кэша на тенанта и UID (см. orderCacheKey)
+func (v *CacheVerifier) splitKey(key string) (string, string) {
+	if !v.cfg.TenantIsolation {
+		return "", key
+	}
+	tenant, uid, ok := strings.Cut(key, ":")
+	if !ok {
+		return "", key
+	}
+	return tenant, uid
+}
+
+// sameOrder сравнивает заказы без учета часового пояса и точности меток времени
+// (Postgres хранит их с точностью до микросекунд) и полей, которые не сериализуются в кэш
+func sameOrder(a, b *domain.Order) bool {
+	return reflect.DeepEqual(normalizeOrder(a), normalizeOrder(b))
+}
+
+func normalizeOrder(order *domain.Order) domain.Order {
+	o := *order
+	o.DateCreated = normalizeTime(o.DateCreated)
+	o.CreatedAt = normalizeTime(o.CreatedAt)
+	o.UpdatedAt = normalizeTime(o.UpdatedAt)
+	o.Delivery.OrderUID = ""
+	o.Payment.OrderUID = ""
+
+	o.Items = nil
+	if len(order.Items) > 0 {
+		o.Items = make([]domain.Item, len(order.Items))
+		for i, item := range order.Items {
+			item.OrderUID = ""
+			o.Items[i] = item
+		}
+	}
+	return o
+}
+
+func normalizeTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func publishVerifyMetrics(report *VerifyReport, err error) {
+	status := new(expvar.String)
+	status.Set("ok")
+	if err != nil && !errors.Is(err, errVerifyLimit) {
+		status.Set("failed")
+	}
+	verifyMetrics.Set("status", status)
+
+	for name, value := range map[string]int64{
+		"checked":       report.Checked,
+		"stale":         report.Stale,
+		"orphaned":      report.Orphaned,
+		"corrupt":       report.Corrupt,
+		"repaired":      report.Repaired,
+		"errors":        report.Errors,
+		"duration_ms":   report.Duration.Milliseconds(),
+		"last_run_unix": time.Now().Unix(),
+	} {
+		v := new(expvar.Int)
+		v.Set(value)
+		verifyMetrics.Set(name, v)
+	}
+	verifyMetrics.Add("mismatches_total", report.Mismatches())
+	verifyMetrics.Add("repaired_total", report.Repaired)
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockCacheScanner мок для интерфейса CacheScanner: ключи отдаются пачками batches.
+type MockCacheScanner struct {
+	mock.Mock
+	batches [][]string
+}
+
+func (m *MockCacheScanner) ScanKeys(ctx context.Context, fn func(keys []string) error) error {
+	for _, batch := range m.batches {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockCacheScanner) Entries(ctx context.Context, keys []string) (map[string]*domain.Order, error) {
+	args := m.Called(ctx, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]*domain.Order), args.Error(1)
+}
+
+// MockCacheRepairer мок для интерфейса CacheRepairer.
+type MockCacheRepairer struct {
+	mock.Mock
+}
+
+func (m *MockCacheRepairer) Set(ctx context.Context, key string, order *domain.Order) {
+	m.Called(ctx, key, order)
+}
+
+func (m *MockCacheRepairer) Delete(ctx context.Context, keys ...string) {
+	m.Called(ctx, keys)
+}
+
+func newTestCacheVerifier(repo *MockOrderRepository, scanner *MockCacheScanner, repairer *MockCacheRepairer, cfg VerifyConfig) *CacheVerifier {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return NewCacheVerifier(repo, scanner, repairer, cfg, logger)
+}
+
+// TestCacheVerifier_Verify тестирует обнаружение и исправление расхождений.
+func TestCacheVerifier_Verify(t *testing.T) {
+	dbOrder := loadOrderFromJSON(t, validOrderPath)
+	dbOrder.CreatedAt = time.Date(2025, 1, 1, 10, 0, 0, 123456000, time.FixedZone("MSK", 3*3600))
+	dbOrder.Payment.OrderUID = dbOrder.OrderUID
+	for i := range dbOrder.Items {
+		dbOrder.Items[i].OrderUID = dbOrder.OrderUID
+	}
+
+	// Та же запись после кэширования: UTC, наносекунды и без полей json:"-"
+	same := *dbOrder
+	same.CreatedAt = dbOrder.CreatedAt.UTC().Add(789)
+	same.Payment.OrderUID = ""
+	same.Items = append([]domain.Item(nil), dbOrder.Items...)
+	for i := range same.Items {
+		same.Items[i].OrderUID = ""
+	}
+
+	stale := same
+	stale.TrackNumber = "OLD"
+
+	newSetup := func(cfg VerifyConfig) (*CacheVerifier, *MockOrderRepository, *MockCacheScanner, *MockCacheRepairer) {
+		repo, scanner, repairer := new(MockOrderRepository), new(MockCacheScanner), new(MockCacheRepairer)
+		scanner.batches = [][]string{{"same", "stale"}, {"orphan", "corrupt", "failing"}}
+		scanner.On("Entries", mock.Anything, []string{"same", "stale"}).Return(map[string]*domain.Order{"same": &same, "stale": &stale}, nil)
+		scanner.On("Entries", mock.Anything, []string{"orphan", "corrupt", "failing"}).Return(map[string]*domain.Order{"orphan": &same, "corrupt": nil, "failing": &same}, nil)
+		repo.On("GetByUID", mock.Anything, "same").Return(dbOrder, nil)
+		repo.On("GetByUID", mock.Anything, "stale").Return(dbOrder, nil)
+		repo.On("GetByUID", mock.Anything, "orphan").Return(nil, fmt.Errorf("%w: uid orphan", domain.ErrOrderNotFound))
+		repo.On("GetByUID", mock.Anything, "failing").Return(nil, errors.New("db down"))
+		return newTestCacheVerifier(repo, scanner, repairer, cfg), repo, scanner, repairer
+	}
+
+	t.Run("report only", func(t *testing.T) {
+		verifier, _, _, repairer := newSetup(VerifyConfig{})
+
+		report, err := verifier.Verify(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), report.Checked)
+		assert.Equal(t, int64(1), report.Stale)
+		assert.Equal(t, int64(1), report.Orphaned)
+		assert.Equal(t, int64(1), report.Corrupt)
+		assert.Equal(t, int64(1), report.Errors)
+		assert.Equal(t, int64(3), report.Mismatches())
+		assert.Zero(t, report.Repaired)
+		repairer.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything)
+		repairer.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		verifier, _, _, repairer := newSetup(VerifyConfig{Repair: true})
+		repairer.On("Set", mock.Anything, "stale", dbOrder).Once()
+		repairer.On("Delete", mock.Anything, []string{"orphan"}).Once()
+		repairer.On("Delete", mock.Anything, []string{"corrupt"}).Once()
+
+		report, err := verifier.Verify(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), report.Repaired)
+		repairer.AssertExpectations(t)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		verifier, _, scanner, _ := newSetup(VerifyConfig{Limit: 1})
+		scanner.On("Entries", mock.Anything, []string{"same"}).Return(map[string]*domain.Order{"same": &same}, nil)
+
+		report, err := verifier.Verify(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), report.Checked)
+		assert.Zero(t, report.Mismatches())
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		verifier, _, scanner, _ := newSetup(VerifyConfig{Sample: 0.5})
+		draws := []float64{0.9, 0.1, 0.9, 0.9, 0.9}
+		verifier.sample = func() float64 {
+			v := draws[0]
+			draws = draws[1:]
+			return v
+		}
+		scanner.On("Entries", mock.Anything, []string{"stale"}).Return(map[string]*domain.Order{"stale": &stale}, nil)
+
+		report, err := verifier.Verify(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), report.Checked)
+		assert.Equal(t, int64(1), report.Stale)
+	})
+}
+
+// TestCacheVerifier_TenantKeys тестирует проверку ключей с тенантом.
+func TestCacheVerifier_TenantKeys(t *testing.T) {
+	order := loadOrderFromJSON(t, validOrderPath)
+	repo, scanner, repairer := new(MockOrderRepository), new(MockCacheScanner), new(MockCacheRepairer)
+	scanner.batches = [][]string{{"WBIL:" + order.OrderUID}}
+	scanner.On("Entries", mock.Anything, scanner.batches[0]).Return(map[string]*domain.Order{scanner.batches[0][0]: order}, nil)
+	repo.On("GetByUID", mock.MatchedBy(func(ctx context.Context) bool {
+		tenant, ok := domain.TenantFromContext(ctx)
+		return ok && tenant == "WBIL"
+	}), order.OrderUID).Return(order, nil).Once()
+
+	verifier := newTestCacheVerifier(repo, scanner, repairer, VerifyConfig{TenantIsolation: true})
+	report, err := verifier.Verify(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Checked)
+	assert.Zero(t, report.Mismatches())
+	repo.AssertExpectations(t)
+}
+
+// TestCacheVerifier_ScanError тестирует ошибку чтения кэша.
+func TestCacheVerifier_ScanError(t *testing.T) {
+	repo, scanner, repairer := new(MockOrderRepository), new(MockCacheScanner), new(MockCacheRepairer)
+	scanner.batches = [][]string{{"a"}}
+	scanner.On("Entries", mock.Anything, []string{"a"}).Return(nil, errors.New("redis is unavailable"))
+
+	_, err := newTestCacheVerifier(repo, scanner, repairer, VerifyConfig{}).Verify(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+)
+
+// WarmupStrategy определяет, какие заказы загружаются в кэш при старте
+type WarmupStrategy string
+
+const (
+	WarmupNone   WarmupStrategy = "none"   // кэш не прогревается
+	WarmupAll    WarmupStrategy = "all"    // все заказы
+	WarmupRecent WarmupStrategy = "recent" // заказы за последние RecentDays дней
+	WarmupTop    WarmupStrategy = "top"    // TopK наиболее запрашиваемых заказов
+	WarmupList   WarmupStrategy = "list"   // заказы из файла UIDsFile
+)
+
+// ParseWarmupStrategy разбирает стратегию прогрева кэша
+func ParseWarmupStrategy(value string) (WarmupStrategy, error) {
+	switch strategy := WarmupStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case WarmupNone, WarmupAll, WarmupRecent, WarmupTop, WarmupList:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown cache warm-up strategy %q", value)
+	}
+}
+
+// AccessTracker учитывает обращения к заказам для стратегии WarmupTop
+type AccessTracker interface {
+	RecordAccess(ctx context.Context, uid string)
+	TopAccessed(ctx context.Context, k int) ([]string, error)
+}
+
+// RestoreConfig параметры прогрева кэша при старте
+type RestoreConfig struct {
+	Strategy    WarmupStrategy
+	BatchSize   int    // размер пачки заказов, читаемой из базы
+	Concurrency int    // максимальное число пачек, одновременно загружаемых в кэш
+	RecentDays  int    // для WarmupRecent
+	TopK        int    // для WarmupTop
+	UIDsFile    string // для WarmupList: по одному UID в строке, строки с # игнорируются
+}
+
+// DefaultRestoreConfig значения по умолчанию для прогрева кэша
+var DefaultRestoreConfig = RestoreConfig{
+	Strategy:    WarmupAll,
+	BatchSize:   1000,
+	Concurrency: 4,
+	RecentDays:  1,
+	TopK:        10000,
+}
+
+// WarmupReport результат прогрева кэша
+type WarmupReport struct {
+	Strategy  WarmupStrategy
+	Requested int // число запрошенных UID для WarmupTop и WarmupList
+	Loaded    int64
+	Duration  time.Duration
+}
+
+// warmupMetrics статистика последнего прогрева, доступна через /admin/debug/vars
+var warmupMetrics = expvar.NewMap("cache_warmup")
+
+// SetRestoreConfig задает параметры прогрева кэша. Нулевые значения заменяются значениями по умолчанию.
+func (s *OrderService) SetRestoreConfig(cfg RestoreConfig) {
+	if cfg.Strategy == "" {
+		cfg.Strategy = DefaultRestoreConfig.Strategy
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultRestoreConfig.BatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultRestoreConfig.Concurrency
+	}
+	if cfg.RecentDays <= 0 {
+		cfg.RecentDays = DefaultRestoreConfig.RecentDays
+	}
+	if cfg.TopK <= 0 {
+		cfg.TopK = DefaultRestoreConfig.TopK
+	}
+	s.restore = cfg
+}
+
+// SetCacheAvailability задает проверку доступности кэша: пока кэш недоступен,
+// прогрев не выполняется и откладывается до его восстановления
+func (s *OrderService) SetCacheAvailability(available func() bool) {
+	s.cacheAvailable = available
+}
+
+// SetAccessTracker включает учет обращений к заказам в GetOrderByUID
+func (s *OrderService) SetAccessTracker(tracker AccessTracker) {
+	s.tracker = tracker
+}
+
+func (s *OrderService) recordAccess(ctx context.Context, uid string) {
+	if s.tracker != nil {
+		s.tracker.RecordAccess(ctx, uid)
+	}
+}
+
+// RestoreCache прогревает кэш по выбранной стратегии. Заказы читаются и загружаются
+// пачками, одновременно в памяти находится не более Concurrency+1 пачек.
+func (s *OrderService) RestoreCache(ctx context.Context) error {
+	cfg := s.restore
+	report := WarmupReport{Strategy: cfg.Strategy}
+	start := time.Now()
+
+	if cfg.Strategy == WarmupNone {
+		s.logger.Info("cache warm-up disabled")
+		publishWarmupMetrics(report, nil)
+		return nil
+	}
+	if s.cacheAvailable != nil && !s.cacheAvailable() {
+		s.logger.Warn("cache is unavailable, warm-up postponed until it recovers")
+		return nil
+	}
+
+	s.logger.Info("starting cache warm-up",
+		slog.String("strategy", string(cfg.Strategy)),
+		slog.Int("batch_size", cfg.BatchSize),
+		slog.Int("concurrency", cfg.Concurrency))
+
+	var source func(fn func(orders []*domain.Order) error) error
+	switch cfg.Strategy {
+	case WarmupAll:
+		source = func(fn func(orders []*domain.Order) error) error {
+			return s.repo.ForEachBatch(ctx, time.Time{}, cfg.BatchSize, fn)
+		}
+	case WarmupRecent:
+		since := start.AddDate(0, 0, -cfg.RecentDays)
+		source = func(fn func(orders []*domain.Order) error) error {
+			return s.repo.ForEachBatch(ctx, since, cfg.BatchSize, fn)
+		}
+	case WarmupTop, WarmupList:
+		uids, err := s.warmupUIDs(ctx, cfg)
+		if err != nil {
+			publishWarmupMetrics(report, err)
+			return err
+		}
+		report.Requested = len(uids)
+		source = func(fn func(orders []*domain.Order) error) error {
+			return s.forEachUIDsBatch(ctx, uids, cfg.BatchSize, fn)
+		}
+	default:
+		err := fmt.Errorf("unknown cache warm-up strategy %q", cfg.Strategy)
+		publishWarmupMetrics(report, err)
+		return err
+	}
+
+	loaded, err := s.loadBatches(ctx, cfg.Concurrency, source)
+	report.Loaded = loaded
+	report.Duration = time.Since(start)
+	publishWarmupMetrics(report, err)
+
+	if err != nil {
+		s.logger.Error("failed to warm up cache",
+			slog.String("strategy", string(cfg.Strategy)),
+			slog.Int64("orders_count", loaded),
+			slog.String("error", err.Error()))
+		return fmt.Errorf("failed to get orders from database: %w", err)
+	}
+
+	s.logger.Info("cache warm-up completed",
+		slog.String("strategy", string(cfg.Strategy)),
+		slog.Int("requested", report.Requested),
+		slog.Int64("orders_count", loaded),
+		slog.Duration("elapsed", report.Duration))
+	return nil
+}
+
+// warmupUIDs возвращает список UID для стратегий WarmupTop и WarmupList
+func (s *OrderService) warmupUIDs(ctx context.Context, cfg RestoreConfig) ([]string, error) {
+	if cfg.Strategy == WarmupList {
+		uids, err := readUIDsFile(cfg.UIDsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read warm-up UID list: %w", err)
+		}
+		return uids, nil
+	}
+
+	if s.tracker == nil {
+		return nil, fmt.Errorf("access tracking is not configured for %q warm-up", WarmupTop)
+	}
+	uids, err := s.tracker.TopAccessed(ctx, cfg.TopK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get most accessed orders: %w", err)
+	}
+	return uids, nil
+}
+
+// forEachUIDsBatch загружает заказы по списку UID пачками через GetByUIDs
+func (s *OrderService) forEachUIDsBatch(ctx context.Context, uids []string, batchSize int, fn func(orders []*domain.Order) error) error {
+	for start := 0; start < len(uids); start += batchSize {
+		end := min(start+batchSize, len(uids))
+		orders, err := s.repo.GetByUIDs(ctx, uids[start:end])
+		if err != nil {
+			return err
+		}
+		if len(orders) == 0 {
+			continue
+		}
+		if err := fn(orders); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadBatches загружает в кэш пачки из source, не более concurrency пачек одновременно
+func (s *OrderService) loadBatches(ctx context.Context, concurrency int, source func(fn func(orders []*domain.Order) error) error) (int64, error) {
+	start := time.Now()
+	var (
+		wg       sync.WaitGroup
+		restored atomic.Int64
+		batches  atomic.Int64
+		sem      = make(chan struct{}, concurrency)
+	)
+
+	err := source(func(orders []*domain.Order) error {
+		// Преобразуем в map для загрузки в кэш
+		orderMap := make(map[string]*domain.Order, len(orders))
+		for _, order := range orders {
+			orderMap[orderCacheKey(s.tenantIsolation, order.Entry, order.OrderUID)] = order
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			s.cache.LoadFromDB(ctx, orderMap)
+
+			total := restored.Add(int64(len(orderMap)))
+			s.logger.Info("cache warm-up progress",
+				slog.Int64("batch", batches.Add(1)),
+				slog.Int64("orders_count", total),
+				slog.Duration("elapsed", time.Since(start)))
+		}()
+		return nil
+	})
+	wg.Wait()
+
+	return restored.Load(), err
+}
+
+// readUIDsFile читает UID заказов из файла: по одному в строке, пустые строки и строки с # пропускаются
+func readUIDsFile(path string) ([]string, error) {
+	if path == "" {
+		return nil, fmt.Errorf("UID list file is not set")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var uids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		uids = append(uids, line)
+	}
+	return uids, scanner.Err()
+}
+
+func publishWarmupMetrics(report WarmupReport, err error) {
+	strategy := new(expvar.String)
+	strategy.Set(string(report.Strategy))
+	warmupMetrics.Set("strategy", strategy)
+
+	status := new(expvar.String)
+	status.Set("ok")
+	if err != nil {
+		status.Set("failed")
+	}
+	warmupMetrics.Set("status", status)
+
+	for name, value := range map[string]int64{
+		"requested":     int64(report.Requested),
+		"loaded":        report.Loaded,
+		"duration_ms":   report.Duration.Milliseconds(),
+		"last_run_unix": time.Now().Unix(),
+	} {
+		v := new(expvar.Int)
+		v.Set(value)
+		warmupMetrics.Set(name, v)
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ravwvil/order-service/backend/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAccessTracker мок для интерфейса AccessTracker.
+type MockAccessTracker struct {
+	mock.Mock
+}
+
+// RecordAccess мок для метода RecordAccess.
+func (m *MockAccessTracker) RecordAccess(ctx context.Context, uid string) {
+	m.Called(ctx, uid)
+}
+
+// TopAccessed мок для метода TopAccessed.
+func (m *MockAccessTracker) TopAccessed(ctx context.Context, k int) ([]string, error) {
+	args := m.Called(ctx, k)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// TestParseWarmupStrategy тестирует разбор стратегии прогрева.
+func TestParseWarmupStrategy(t *testing.T) {
+	strategy, err := ParseWarmupStrategy(" Recent ")
+	require.NoError(t, err)
+	assert.Equal(t, WarmupRecent, strategy)
+
+	_, err = ParseWarmupStrategy("newest")
+	assert.Error(t, err)
+}
+
+// TestOrderService_Warmup тестирует стратегии прогрева кэша.
+func TestOrderService_Warmup(t *testing.T) {
+	order1 := &domain.Order{OrderUID: "uid-1"}
+	order2 := &domain.Order{OrderUID: "uid-2"}
+
+	t.Run("none", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetRestoreConfig(RestoreConfig{Strategy: WarmupNone})
+
+		require.NoError(t, service.RestoreCache(context.Background()))
+
+		repo.AssertNotCalled(t, "ForEachBatch")
+		cache.AssertNotCalled(t, "LoadFromDB")
+		assert.Equal(t, "\"none\"", warmupMetrics.Get("strategy").String())
+	})
+
+	t.Run("recent", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetRestoreConfig(RestoreConfig{Strategy: WarmupRecent, RecentDays: 7})
+
+		expectedSince := time.Now().AddDate(0, 0, -7)
+		sinceMatcher := mock.MatchedBy(func(since time.Time) bool {
+			return since.Sub(expectedSince).Abs() < time.Minute
+		})
+		repo.On("ForEachBatch", mock.Anything, sinceMatcher, DefaultRestoreConfig.BatchSize).
+			Return([][]*domain.Order{{order1}}, nil).Once()
+		cache.On("LoadFromDB", mock.Anything, map[string]*domain.Order{"uid-1": order1}).Once()
+
+		require.NoError(t, service.RestoreCache(context.Background()))
+
+		repo.AssertExpectations(t)
+		cache.AssertExpectations(t)
+		assert.Equal(t, "1", warmupMetrics.Get("loaded").String())
+	})
+
+	t.Run("top", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		tracker := new(MockAccessTracker)
+		service := newTestService(repo, cache)
+		service.SetAccessTracker(tracker)
+		service.SetRestoreConfig(RestoreConfig{Strategy: WarmupTop, TopK: 3, BatchSize: 2})
+
+		tracker.On("TopAccessed", mock.Anything, 3).Return([]string{"uid-1", "uid-2", "gone"}, nil).Once()
+		repo.On("GetByUIDs", mock.Anything, []string{"uid-1", "uid-2"}).Return([]*domain.Order{order1, order2}, nil).Once()
+		repo.On("GetByUIDs", mock.Anything, []string{"gone"}).Return([]*domain.Order{}, nil).Once()
+		cache.On("LoadFromDB", mock.Anything, map[string]*domain.Order{"uid-1": order1, "uid-2": order2}).Once()
+
+		require.NoError(t, service.RestoreCache(context.Background()))
+
+		tracker.AssertExpectations(t)
+		repo.AssertExpectations(t)
+		cache.AssertExpectations(t)
+		assert.Equal(t, "3", warmupMetrics.Get("requested").String())
+		assert.Equal(t, "2", warmupMetrics.Get("loaded").String())
+	})
+
+	t.Run("top without tracker", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetRestoreConfig(RestoreConfig{Strategy: WarmupTop})
+
+		assert.Error(t, service.RestoreCache(context.Background()))
+		assert.Equal(t, "\"failed\"", warmupMetrics.Get("status").String())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "uids.txt")
+		require.NoError(t, os.WriteFile(path, []byte("# hot orders\nuid-2\n\n  uid-1  \n"), 0o600))
+
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetRestoreConfig(RestoreConfig{Strategy: WarmupList, UIDsFile: path})
+
+		repo.On("GetByUIDs", mock.Anything, []string{"uid-2", "uid-1"}).Return([]*domain.Order{order2, order1}, nil).Once()
+		cache.On("LoadFromDB", mock.Anything, map[string]*domain.Order{"uid-1": order1, "uid-2": order2}).Once()
+
+		require.NoError(t, service.RestoreCache(context.Background()))
+
+		repo.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("list file missing", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetRestoreConfig(RestoreConfig{Strategy: WarmupList, UIDsFile: filepath.Join(t.TempDir(), "missing")})
+
+		assert.Error(t, service.RestoreCache(context.Background()))
+		repo.AssertNotCalled(t, "GetByUIDs")
+	})
+
+	t.Run("cache unavailable", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		service := newTestService(repo, cache)
+		service.SetRestoreConfig(RestoreConfig{Strategy: WarmupAll})
+		service.SetCacheAvailability(func() bool { return false })
+
+		require.NoError(t, service.RestoreCache(context.Background()))
+		repo.AssertNotCalled(t, "ForEachBatch")
+		cache.AssertNotCalled(t, "LoadFromDB")
+	})
+}
+
+// TestOrderService_RecordAccess тестирует учет обращений к заказам.
+func TestOrderService_RecordAccess(t *testing.T) {
+	order := &domain.Order{OrderUID: "uid-1"}
+
+	t.Run("cache hit and miss are recorded", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		tracker := new(MockAccessTracker)
+		service := newTestService(repo, cache)
+		service.SetAccessTracker(tracker)
+
+		cache.On("Get", mock.Anything, "uid-1").Return(order, true).Once()
+		cache.On("Get", mock.Anything, "uid-1").Return(nil, false).Once()
+		repo.On("GetByUID", mock.Anything, "uid-1").Return(order, nil).Once()
+		cache.On("Set", mock.Anything, "uid-1", order).Once()
+		tracker.On("RecordAccess", mock.Anything, "uid-1").Twice()
+
+		_, err := service.GetOrderByUID(context.Background(), "uid-1")
+		require.NoError(t, err)
+		_, err = service.GetOrderByUID(context.Background(), "uid-1")
+		require.NoError(t, err)
+
+		tracker.AssertExpectations(t)
+	})
+
+	t.Run("not found is not recorded", func(t *testing.T) {
+		repo := new(MockOrderRepository)
+		cache := new(MockOrderCache)
+		tracker := new(MockAccessTracker)
+		service := newTestService(repo, cache)
+		service.SetAccessTracker(tracker)
+
+		cache.On("Get", mock.Anything, "missing").Return(nil, false).Once()
+		repo.On("GetByUID", mock.Anything, "missing").Return(nil, errors.New("not found")).Once()
+
+		_, err := service.GetOrderByUID(context.Background(), "missing")
+		assert.Error(t, err)
+		tracker.AssertNotCalled(t, "RecordAccess")
+	})
+}
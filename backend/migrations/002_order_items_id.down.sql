@@ -0,0 +1,13 @@
+-- Перед возвратом составного ключа удаляем дубликаты (order_uid, rid),
+-- оставляя позицию с наименьшим id.
+DELETE FROM order_items a
+USING order_items b
+WHERE a.order_uid = b.order_uid
+  AND a.rid = b.rid
+  AND a.id > b.id;
+
+DROP INDEX IF EXISTS idx_order_items_order_uid_rid;
+
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_pkey;
+ALTER TABLE order_items DROP COLUMN IF EXISTS id;
+ALTER TABLE order_items ADD PRIMARY KEY (order_uid, rid);
@@ -0,0 +1,9 @@
+-- Суррогатный ключ для товаров заказа: rid больше не обязан быть уникальным
+-- в рамках заказа, а у каждой позиции появляется стабильный идентификатор.
+-- BIGSERIAL заполняет значения для уже существующих строк при добавлении колонки.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS id BIGSERIAL;
+
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_pkey;
+ALTER TABLE order_items ADD PRIMARY KEY (id);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order_uid_rid ON order_items(order_uid, rid);
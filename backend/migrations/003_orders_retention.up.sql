@@ -0,0 +1,2 @@
+-- Отметка об обезличивании заказа политикой хранения данных
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ;
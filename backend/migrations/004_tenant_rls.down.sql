@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_orders_entry;
+
+DROP POLICY IF EXISTS order_items_tenant_isolation ON order_items;
+ALTER TABLE order_items NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE order_items DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS payments_tenant_isolation ON payments;
+ALTER TABLE payments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE payments DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS deliveries_tenant_isolation ON deliveries;
+ALTER TABLE deliveries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE deliveries DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS orders_tenant_isolation ON orders;
+ALTER TABLE orders NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE orders DISABLE ROW LEVEL SECURITY;
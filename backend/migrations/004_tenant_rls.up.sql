@@ -0,0 +1,30 @@
+-- Row-level security по тенанту (orders.entry).
+-- Тенант передается через параметр сессии app.tenant. Если он не задан,
+-- политики пропускают все строки: так работают consumer, восстановление кэша
+-- и фоновые задачи. FORCE нужен, чтобы политики применялись и к владельцу таблиц.
+-- Суперпользователи и роли с BYPASSRLS политики игнорируют, поэтому для RLS
+-- приложение должно подключаться к базе отдельной ролью.
+ALTER TABLE orders ENABLE ROW LEVEL SECURITY;
+ALTER TABLE orders FORCE ROW LEVEL SECURITY;
+CREATE POLICY orders_tenant_isolation ON orders
+    USING (
+        COALESCE(current_setting('app.tenant', true), '') = ''
+        OR entry = current_setting('app.tenant', true)
+    );
+
+ALTER TABLE deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE deliveries FORCE ROW LEVEL SECURITY;
+CREATE POLICY deliveries_tenant_isolation ON deliveries
+    USING (EXISTS (SELECT 1 FROM orders o WHERE o.order_uid = deliveries.order_uid));
+
+ALTER TABLE payments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE payments FORCE ROW LEVEL SECURITY;
+CREATE POLICY payments_tenant_isolation ON payments
+    USING (EXISTS (SELECT 1 FROM orders o WHERE o.order_uid = payments.order_uid));
+
+ALTER TABLE order_items ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_items FORCE ROW LEVEL SECURITY;
+CREATE POLICY order_items_tenant_isolation ON order_items
+    USING (EXISTS (SELECT 1 FROM orders o WHERE o.order_uid = order_items.order_uid));
+
+CREATE INDEX IF NOT EXISTS idx_orders_entry ON orders(entry);
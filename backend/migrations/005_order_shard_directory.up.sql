@@ -0,0 +1,7 @@
+-- Справочник UID -> шард для маршрутизации чтений при шардировании.
+-- Используется только в базе, выбранной справочником (SHARD_DIRECTORY).
+CREATE TABLE IF NOT EXISTS order_shard_directory (
+    order_uid TEXT PRIMARY KEY,
+    shard TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
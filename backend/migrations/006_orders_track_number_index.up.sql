@@ -0,0 +1,2 @@
+-- Индекс для поиска заказов по трек-номеру (по customer_id индекс создан в 001_init)
+CREATE INDEX IF NOT EXISTS idx_orders_track_number ON orders(track_number);
@@ -0,0 +1,22 @@
+-- Сообщения DLQ для разбора без доступа к Kafka.
+-- Повторное попадание того же сообщения (тот же ключ и payload) обновляет запись и увеличивает attempts.
+CREATE TABLE IF NOT EXISTS dlq_messages (
+    id BIGSERIAL PRIMARY KEY,
+    fingerprint TEXT NOT NULL UNIQUE,
+    message_key TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    original_topic TEXT NOT NULL,
+    error_class TEXT NOT NULL,
+    failure_reason TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    first_seen TIMESTAMPTZ NOT NULL,
+    last_seen TIMESTAMPTZ NOT NULL,
+    dlq_partition INT NOT NULL,
+    dlq_offset BIGINT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    note TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_dlq_messages_status_last_seen ON dlq_messages (status, last_seen DESC);